@@ -0,0 +1,503 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+	"strings"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type CheckboxAttributeClass struct {
+	AttributeClassInfo
+}
+
+type CheckboxAttribute struct {
+	class *CheckboxAttributeClass
+	value bool
+}
+
+func newCheckboxAttributeClass(_ context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	updateTable := fmt.Sprintf(`checkbox_%v`, id)
+
+	act := &CheckboxAttributeClass{
+		AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "checkbox",
+			key:      id.String(),
+			attrType: AttributeTypeCheckbox,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "value",
+			},
+		},
+	}
+	ac = act
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, act.id, act.name, act.key, act.attrType, act.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+
+	return
+}
+
+func parseCheckboxAttributeClass(_ context.Context, _ tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	ac = &CheckboxAttributeClass{*acProto}
+	return
+}
+
+func (cc *CheckboxAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range cc.metaInfo {
+		m[key] = cc.metaInfo[key]
+	}
+	return m, nil
+}
+
+func (cc *CheckboxAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := cc.name
+	oldkey := cc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range cc.metaInfo {
+		oldMetaInfo[key] = cc.metaInfo[key]
+	}
+	defer func() {
+		if err != nil {
+			cc.name = oldName
+			cc.key = oldkey
+			cc.metaInfo = oldMetaInfo
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			cc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			cc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+	for key := range v {
+		cc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, cc.name, cc.key, cc.metaInfo, cc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (cc *CheckboxAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	attrCheckbox := &CheckboxAttribute{
+		class: cc,
+		value: false,
+	}
+	attr = attrCheckbox
+	obj, err := cc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	cc.DoPreHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.InsertAttribute, attr))
+	defer func() { cc.DoAfterHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), cc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := cc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	if err != nil {
+		return
+	}
+	return
+
+}
+func (cc *CheckboxAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := cc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, cc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrCheckbox := &CheckboxAttribute{
+		class: cc,
+		value: false,
+	}
+	if err = attrCheckbox.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrCheckbox
+	return
+}
+func (cc *CheckboxAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, attr common.Attribute) (err error) {
+	obj, err := cc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	cc.DoPreHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.UpdateAttribute, attr))
+	defer func() { cc.DoAfterHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), cc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := cc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	if _, err = tx.Exac(update, opId, oid); err != nil {
+		return
+	}
+
+	return
+}
+func (cc *CheckboxAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := cc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	cc.DoPreHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.DeleteAttribute, nil))
+	defer func() { cc.DoAfterHook(ctx, cc.db, tx, NewOp(cc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), cc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := cc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (cc *CheckboxAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := cc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have updated_table")
+		return
+	}
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, cc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = cc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, cc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = cc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), cc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, cc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (cc *CheckboxAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrCheckbox := &CheckboxAttribute{
+		class: cc,
+		value: false,
+	}
+	attr = attrCheckbox
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, cc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrCheckbox.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 构建查询
+func (cc *CheckboxAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	jsonPath, ok := cc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have json_value_path")
+		return
+	}
+	// 未勾选和没有该属性都视为false
+	checked := fmt.Sprintf(`COALESCE(data ->> '%s', 0)`, jsonPath)
+	switch op {
+	case "is", "is_not":
+		value, ok := v["value"].(bool)
+		if !ok {
+			err = fmt.Errorf("invaild query value:%s", v)
+			return
+		}
+		cmp := "="
+		if op == "is_not" {
+			cmp = "!="
+		}
+		stmt = fmt.Sprintf(`(%s %s ?)`, checked, cmp)
+		args = []interface{}{value}
+	case "contains_any", "contains_all":
+		var valueList []bool
+		if valueList, err = parseCheckboxValueList(v["value"]); err != nil {
+			return
+		}
+		stmtList := []string{}
+		for _, value := range valueList {
+			stmtList = append(stmtList, fmt.Sprintf(`%s = ?`, checked))
+			args = append(args, value)
+		}
+		connect := " OR "
+		if op == "contains_all" {
+			connect = " AND "
+		}
+		stmt = fmt.Sprintf(`(%s)`, strings.Join(stmtList, connect))
+	case "is_empty":
+		empty, ok := v["value"].(bool)
+		if !ok {
+			empty = true
+		}
+		stmt = fmt.Sprintf(`(%s = ?)`, checked)
+		args = []interface{}{!empty}
+	default:
+		err = fmt.Errorf("unsupport op:%s", op)
+	}
+	return
+}
+
+func parseCheckboxValueList(v interface{}) (valueList []bool, err error) {
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		err = fmt.Errorf("invaild query value list:%v", v)
+		return
+	}
+	for _, item := range list {
+		value, ok := item.(bool)
+		if !ok {
+			err = fmt.Errorf("invaild query value list:%v", v)
+			return
+		}
+		valueList = append(valueList, value)
+	}
+	return
+}
+
+// 支持的筛选操作
+func (cc *CheckboxAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"is":           common.FilterValueBool,
+		"is_not":       common.FilterValueBool,
+		"contains_any": common.FilterValueBoolList,
+		"contains_all": common.FilterValueBoolList,
+		"is_empty":     common.FilterValueOptionalBool,
+	}
+}
+
+// 构建排序，未勾选在前
+func (cc *CheckboxAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	jsonPath, ok := cc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have json_value_path")
+		return
+	}
+	key, err = sortKey(fmt.Sprintf("COALESCE(data ->> '%s', 0)", jsonPath), nil, v)
+	return
+}
+
+// 按是否勾选分组，分组键为0或1
+func (cc *CheckboxAttributeClass) BuildGroup(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	jsonPath, ok := cc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("CheckboxAttribute metainfo dont have json_value_path")
+		return
+	}
+	stmt = fmt.Sprintf(`json_array(COALESCE(data ->> '%s', 0))`, jsonPath)
+	return
+}
+
+func (t *CheckboxAttribute) GetJSON() string {
+	return fmt.Sprintf(`{"value":%t}`, t.value)
+}
+func (t *CheckboxAttribute) String() string {
+	return fmt.Sprintf("%t", t.value)
+}
+func (t *CheckboxAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+func (t *CheckboxAttribute) SetValue(v map[string]interface{}) (err error) {
+	if value, ok := v["value"].(bool); ok {
+		t.value = value
+		return
+	}
+	err = fmt.Errorf("invaild set value:%v", v)
+	return
+}
+func (t *CheckboxAttribute) Parse(v string) error {
+	result := gjson.Get(v, "value")
+	if result.Type != gjson.True && result.Type != gjson.False {
+		return fmt.Errorf("parse error: %v", v)
+	}
+	t.value = result.Bool()
+	return nil
+}
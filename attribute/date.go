@@ -0,0 +1,658 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type DateAttributeClass struct {
+	AttributeClassInfo
+}
+
+type DateAttribute struct {
+	class *DateAttributeClass
+	value time.Time
+}
+
+func newDateAttributeClass(_ context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	jsonUnixPath := fmt.Sprintf(`$."%v"."unix"`, id)
+	updateTable := fmt.Sprintf(`date_%v`, id)
+
+	act := &DateAttributeClass{
+		AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "date",
+			key:      id.String(),
+			attrType: AttributeTypeDate,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "value",
+				"json_unix_path":   jsonUnixPath,
+				"timezone":         "UTC",
+				"include_time":     false,
+			},
+		},
+	}
+	ac = act
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, act.id, act.name, act.key, act.attrType, act.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+
+	return
+}
+
+func parseDateAttributeClass(_ context.Context, _ tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	ac = &DateAttributeClass{*acProto}
+	return
+}
+
+func (dc *DateAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range dc.metaInfo {
+		m[key] = dc.metaInfo[key]
+	}
+	return m, nil
+}
+
+func (dc *DateAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := dc.name
+	oldkey := dc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range dc.metaInfo {
+		oldMetaInfo[key] = dc.metaInfo[key]
+	}
+	defer func() {
+		if err != nil {
+			dc.name = oldName
+			dc.key = oldkey
+			dc.metaInfo = oldMetaInfo
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			dc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			dc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+
+	if timezone, ok := v["timezone"]; ok {
+		value, ok := timezone.(string)
+		if !ok {
+			err = fmt.Errorf("set timezone with error type")
+			return
+		}
+		if _, err = time.LoadLocation(value); err != nil {
+			return
+		}
+		dc.metaInfo["timezone"] = value
+		delete(v, "timezone")
+	}
+	if includeTime, ok := v["include_time"]; ok {
+		value, ok := includeTime.(bool)
+		if !ok {
+			err = fmt.Errorf("set include_time with error type")
+			return
+		}
+		dc.metaInfo["include_time"] = value
+		delete(v, "include_time")
+	}
+	for key := range v {
+		dc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, dc.name, dc.key, dc.metaInfo, dc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (dc *DateAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	attrDate := &DateAttribute{
+		class: dc,
+		value: time.Time{},
+	}
+	attr = attrDate
+	obj, err := dc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	dc.DoPreHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.InsertAttribute, attr))
+	defer func() { dc.DoAfterHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), dc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := dc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	if err != nil {
+		return
+	}
+	return
+
+}
+func (dc *DateAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := dc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, dc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrDate := &DateAttribute{
+		class: dc,
+		value: time.Time{},
+	}
+	if err = attrDate.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrDate
+	return
+}
+func (dc *DateAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, attr common.Attribute) (err error) {
+	obj, err := dc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	dc.DoPreHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.UpdateAttribute, attr))
+	defer func() { dc.DoAfterHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), dc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := dc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	if _, err = tx.Exac(update, opId, oid); err != nil {
+		return
+	}
+
+	return
+}
+func (dc *DateAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := dc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	dc.DoPreHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.DeleteAttribute, nil))
+	defer func() { dc.DoAfterHook(ctx, dc.db, tx, NewOp(dc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), dc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := dc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (dc *DateAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := dc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have updated_table")
+		return
+	}
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, dc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = dc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, dc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = dc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), dc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, dc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (dc *DateAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrDate := &DateAttribute{
+		class: dc,
+		value: time.Time{},
+	}
+	attr = attrDate
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, dc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrDate.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 属性的时区，未设置或无效时使用UTC
+func (dc *DateAttributeClass) location() *time.Location {
+	timezone, ok := dc.metaInfo["timezone"].(string)
+	if !ok {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func (dc *DateAttributeClass) includeTime() bool {
+	includeTime, _ := dc.metaInfo["include_time"].(bool)
+	return includeTime
+}
+
+var dateLayoutList = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+}
+
+// 解析时间字符串，没有时区信息时使用属性的时区
+// 只有日期时dateOnly为true
+func parseDateValue(v interface{}, loc *time.Location) (t time.Time, dateOnly bool, err error) {
+	switch value := v.(type) {
+	case time.Time:
+		t = value.In(loc)
+		return
+	case string:
+		if t, err = time.ParseInLocation("2006-01-02", value, loc); err == nil {
+			dateOnly = true
+			return
+		}
+		for _, layout := range dateLayoutList {
+			if t, err = time.ParseInLocation(layout, value, loc); err == nil {
+				t = t.In(loc)
+				return
+			}
+		}
+	}
+	err = fmt.Errorf("invaild date value:%v", v)
+	return
+}
+
+// 当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// 时间值对应的左闭右开区间，日期对应整天
+func dateValueRange(v interface{}, loc *time.Location) (start int64, end int64, err error) {
+	t, dateOnly, err := parseDateValue(v, loc)
+	if err != nil {
+		return
+	}
+	if dateOnly {
+		start = t.Unix()
+		end = t.AddDate(0, 0, 1).Unix()
+		return
+	}
+	start = t.Unix()
+	end = start + 1
+	return
+}
+
+// 构建查询，相对时间使用ctx中的时钟
+func (dc *DateAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	unixPath, ok := dc.metaInfo["json_unix_path"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have json_unix_path")
+		return
+	}
+	loc := dc.location()
+	unix := fmt.Sprintf("data ->> '%s'", unixPath)
+	rangeStmt := func(start, end int64) string {
+		args = []interface{}{start, end}
+		return fmt.Sprintf(`(%s >= ? AND %s < ?)`, unix, unix)
+	}
+
+	switch op {
+	case "before":
+		var start int64
+		if start, _, err = dateValueRange(v["value"], loc); err != nil {
+			return
+		}
+		stmt = fmt.Sprintf(`(%s < ?)`, unix)
+		args = []interface{}{start}
+	case "after":
+		var end int64
+		if _, end, err = dateValueRange(v["value"], loc); err != nil {
+			return
+		}
+		stmt = fmt.Sprintf(`(%s >= ?)`, unix)
+		args = []interface{}{end}
+	case "on":
+		var t time.Time
+		if t, _, err = parseDateValue(v["value"], loc); err != nil {
+			return
+		}
+		day := startOfDay(t)
+		stmt = rangeStmt(day.Unix(), day.AddDate(0, 0, 1).Unix())
+	case "between":
+		valueList, ok := v["value"].([]interface{})
+		if !ok || len(valueList) != 2 {
+			err = fmt.Errorf("invaild query value:%s", v)
+			return
+		}
+		var start, end int64
+		if start, _, err = dateValueRange(valueList[0], loc); err != nil {
+			return
+		}
+		if _, end, err = dateValueRange(valueList[1], loc); err != nil {
+			return
+		}
+		stmt = rangeStmt(start, end)
+	case "within_past_n_days", "within_next_n_days":
+		// 天数为非负整数
+		n, ok := v["value"].(float64)
+		if !ok || n < 0 || n != math.Trunc(n) {
+			err = fmt.Errorf("invaild query value:%s", v)
+			return
+		}
+		// 按天计算，包含今天
+		today := startOfDay(common.Now(ctx).In(loc))
+		if op == "within_past_n_days" {
+			stmt = rangeStmt(today.AddDate(0, 0, -int(n)).Unix(), today.AddDate(0, 0, 1).Unix())
+		} else {
+			stmt = rangeStmt(today.Unix(), today.AddDate(0, 0, int(n)+1).Unix())
+		}
+	case "is_empty":
+		empty, ok := v["value"].(bool)
+		if !ok {
+			empty = true
+		}
+		if empty {
+			stmt = fmt.Sprintf(`(%s IS NULL)`, unix)
+		} else {
+			stmt = fmt.Sprintf(`(%s IS NOT NULL)`, unix)
+		}
+	default:
+		err = fmt.Errorf("unsupport op:%s", op)
+	}
+	return
+}
+
+// 支持的筛选操作，日期为字符串，2006-01-02格式表示整天
+func (dc *DateAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"before":             common.FilterValueString,
+		"after":              common.FilterValueString,
+		"on":                 common.FilterValueString,
+		"between":            common.FilterValueStringPair,
+		"within_past_n_days": common.FilterValueInteger,
+		"within_next_n_days": common.FilterValueInteger,
+		"is_empty":           common.FilterValueOptionalBool,
+	}
+}
+
+// 构建排序
+func (dc *DateAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	unixPath, ok := dc.metaInfo["json_unix_path"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have json_unix_path")
+		return
+	}
+	key, err = sortKey(fmt.Sprintf("data ->> '%s'", unixPath), nil, v)
+	return
+}
+
+// 按日期分组，bucket为day、week、month、year，默认为day
+// 分组键为属性时区中该区间第一天的日期
+func (dc *DateAttributeClass) BuildGroup(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	unixPath, ok := dc.metaInfo["json_unix_path"].(string)
+	if !ok {
+		err = fmt.Errorf("DateAttribute metainfo dont have json_unix_path")
+		return
+	}
+	unit, ok := v["bucket"].(string)
+	if !ok {
+		unit = "day"
+	}
+	switch unit {
+	case "day", "week", "month", "year":
+	default:
+		err = fmt.Errorf("invaild group bucket:%v", v["bucket"])
+		return
+	}
+	stmt = fmt.Sprintf(`json_array(date_bucket(data ->> '%s', ?, ?))`, unixPath)
+	args = []interface{}{dc.location().String(), unit}
+	return
+}
+
+func (t *DateAttribute) GetJSON() string {
+	if t.value.IsZero() {
+		return `{"value":null,"unix":null}`
+	}
+	return fmt.Sprintf(`{"value":"%s","unix":%d}`, t.String(), t.value.Unix())
+}
+
+// 日期为ISO-8601格式，包含时间时带时区偏移
+func (t *DateAttribute) String() string {
+	if t.value.IsZero() {
+		return ""
+	}
+	value := t.value.In(t.class.location())
+	if t.class.includeTime() {
+		return value.Format(time.RFC3339)
+	}
+	return value.Format("2006-01-02")
+}
+func (t *DateAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+
+// value为时间字符串或time.Time，nil或空字符串表示清空
+func (t *DateAttribute) SetValue(v map[string]interface{}) (err error) {
+	value, ok := v["value"]
+	if !ok {
+		err = fmt.Errorf("invaild set value:%v", v)
+		return
+	}
+	if value == nil || value == "" {
+		t.value = time.Time{}
+		return
+	}
+	loc := t.class.location()
+	parsed, _, err := parseDateValue(value, loc)
+	if err != nil {
+		return
+	}
+	if !t.class.includeTime() {
+		parsed = startOfDay(parsed)
+	}
+	t.value = parsed
+	return
+}
+func (t *DateAttribute) Parse(v string) error {
+	result := gjson.Get(v, "unix")
+	switch result.Type {
+	case gjson.Null:
+		t.value = time.Time{}
+	case gjson.Number:
+		t.value = time.Unix(result.Int(), 0).In(t.class.location())
+	default:
+		return fmt.Errorf("parse error: %v", v)
+	}
+	return nil
+}
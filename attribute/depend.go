@@ -0,0 +1,89 @@
+package attribute
+
+import (
+	"context"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+)
+
+// 检查 acid 依赖 depList 后是否会形成环
+func checkDependCycle(_ context.Context, tx tx.ReadTx, acid common.AttributeClassId, depList []common.AttributeClassId) (err error) {
+	query := `
+	WITH RECURSIVE dep(class_id) AS (
+		SELECT ?
+		UNION
+		SELECT d.dep_class_id FROM attribute_classes_dep d JOIN dep ON d.class_id = dep.class_id
+	)
+	SELECT EXISTS (SELECT 1 FROM dep WHERE class_id = ?)`
+	for _, depAcid := range depList {
+		if depAcid == acid {
+			err = fmt.Errorf("%w: %v depend on itself", common.ErrAttributeClassDependCycle, acid)
+			return
+		}
+		var exists bool
+		if err = tx.QueryRow(query, depAcid, acid).Scan(&exists); err != nil {
+			return
+		}
+		if exists {
+			err = fmt.Errorf("%w: %v -> %v", common.ErrAttributeClassDependCycle, acid, depAcid)
+			return
+		}
+	}
+	return
+}
+
+// 覆盖写入 acid 的依赖
+func saveDepend(_ context.Context, tx tx.WriteTx, acid common.AttributeClassId, depList []common.AttributeClassId) (err error) {
+	deleteDep := `DELETE FROM attribute_classes_dep WHERE class_id = ?`
+	if _, err = tx.Exac(deleteDep, acid); err != nil {
+		return
+	}
+	insertDep := `
+	INSERT INTO attribute_classes_dep
+	(class_id, dep_class_id)
+	VALUES
+	(?, ?)`
+	for _, depAcid := range depList {
+		if _, err = tx.Exac(insertDep, acid, depAcid); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 删除与 acid 相关的全部依赖
+func dropDepend(_ context.Context, tx tx.WriteTx, acid common.AttributeClassId) (err error) {
+	deleteDep := `DELETE FROM attribute_classes_dep WHERE class_id = ? OR dep_class_id = ?`
+	_, err = tx.Exac(deleteDep, acid, acid)
+	return
+}
+
+// 通过 class id 或 key 查找属性类
+func findAttributeClassByRef(ctx context.Context, db common.Database, tx tx.ReadTx, ref string) (ac common.AttributeClass, err error) {
+	var acid common.AttributeClassId
+	if nerr := acid.Scan(ref); nerr == nil {
+		if ac, nerr = db.OpenAttributeClass(ctx, tx, acid); nerr == nil {
+			return
+		}
+	}
+	query := `SELECT class_id FROM attribute_classes WHERE attribute_key = ?`
+	if err = tx.QueryRow(query, ref).Scan(&acid); err != nil {
+		err = fmt.Errorf("attribute class %s not found:%w", ref, common.ErrAttributeClassNotFound)
+		return
+	}
+	ac, err = db.OpenAttributeClass(ctx, tx, acid)
+	return
+}
+
+func marshalDependList(depList []common.AttributeClassId) string {
+	ret := "["
+	for idx, acid := range depList {
+		if idx != 0 {
+			ret += ","
+		}
+		ret += fmt.Sprintf(`"%v"`, acid)
+	}
+	ret += "]"
+	return ret
+}
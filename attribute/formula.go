@@ -0,0 +1,619 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type FormulaAttributeClass struct {
+	AttributeClassInfo
+	expr    formulaNode
+	exprErr error
+}
+
+type FormulaAttribute struct {
+	class *FormulaAttributeClass
+	value interface{}
+}
+
+func newFormulaAttributeClass(ctx context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	updateTable := fmt.Sprintf(`formula_%v`, id)
+
+	fc := &FormulaAttributeClass{
+		AttributeClassInfo: AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "formula",
+			key:      id.String(),
+			attrType: AttributeTypeFormula,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "value",
+				"expression":       "",
+				"dep_attribute":    "[]",
+			},
+		},
+	}
+	ac = fc
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, fc.id, fc.name, fc.key, fc.attrType, fc.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+	err = fc.registerHookFunc(ctx, tx)
+	return
+}
+
+func parseFormulaAttributeClass(ctx context.Context, tx tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	fc := &FormulaAttributeClass{AttributeClassInfo: *acProto}
+	// 依赖的属性类可能已被删除，此时只记录错误，不影响数据库打开
+	expression, _ := fc.metaInfo["expression"].(string)
+	fc.expr, _, fc.exprErr = fc.compile(ctx, tx, expression)
+	if err = fc.registerHookFunc(ctx, tx); err != nil {
+		return
+	}
+	ac = fc
+	return
+}
+
+// 解析公式并解析其中的属性引用
+func (fc *FormulaAttributeClass) compile(ctx context.Context, tx tx.ReadTx, expression string) (node formulaNode, depList []common.AttributeClassId, err error) {
+	depList = []common.AttributeClassId{}
+	if expression == "" {
+		return
+	}
+	node, err = parseFormula(expression)
+	if err != nil {
+		return
+	}
+	depMap := map[common.AttributeClassId]bool{}
+	err = walkFormulaProp(node, func(p *formulaProp) (err error) {
+		ac, err := findAttributeClassByRef(ctx, fc.db, tx, p.ref)
+		if err != nil {
+			return
+		}
+		metaInfo, err := ac.GetMetaInfo(ctx, tx)
+		if err != nil {
+			return
+		}
+		valuePath, ok := metaInfo["gjson_value_path"].(string)
+		if !ok {
+			err = fmt.Errorf("formula: attribute class %v metainfo dont have gjson_value_path", ac.ClassId())
+			return
+		}
+		p.acid = ac.ClassId()
+		p.class = ac
+		p.valuePath = valuePath
+		if !depMap[p.acid] {
+			depMap[p.acid] = true
+			depList = append(depList, p.acid)
+		}
+		return
+	})
+	return
+}
+
+func (fc *FormulaAttributeClass) registerHookFunc(_ context.Context, _ tx.ReadTx) (nerr error) {
+	depAttributeListStr, ok := fc.metaInfo["dep_attribute"].(string)
+	if !ok {
+		nerr = fmt.Errorf("FormulaAttributeClass %v dep attribute error", fc.id)
+		return
+	}
+	depIdList := []common.AttributeClassId{}
+	gjson.Parse(depAttributeListStr).ForEach(func(key, value gjson.Result) bool {
+		var acid common.AttributeClassId
+		if nerr = acid.Scan(value.Str); nerr != nil {
+			return false
+		}
+		depIdList = append(depIdList, acid)
+		return true
+	})
+	if nerr != nil {
+		return
+	}
+
+	afterF := func(ctx context.Context, db common.Database, tx tx.WriteTx, op common.AttributeOp) (err error) {
+		// 依赖的属性变化后重新计算公式
+		for _, acid := range depIdList {
+			if op.ClassId() == acid {
+				err = fc.refresh(ctx, tx, op.Object())
+				return
+			}
+		}
+		return
+	}
+	nerr = common.RegisterAfterAttributeHook(fc.id, afterF)
+	return
+}
+
+// 计算对象上的公式值，表达式无效或计算出错时值为null，与依赖属性为空时一样正常写入
+func (fc *FormulaAttributeClass) evaluate(ctx context.Context, obj common.Object) (attr *FormulaAttribute) {
+	attr = &FormulaAttribute{
+		class: fc,
+		value: nil,
+	}
+	if fc.exprErr != nil || fc.expr == nil {
+		return
+	}
+	env := &formulaEnv{
+		data: obj.Data(),
+		obj:  obj,
+		now:  func() time.Time { return common.Now(ctx) },
+	}
+	value, err := fc.expr.eval(env)
+	if err != nil {
+		return
+	}
+	attr.value = formulaJSONValue(value)
+	return
+}
+
+// 重新计算并写入公式值，只处理已经拥有该属性的对象
+func (fc *FormulaAttributeClass) refresh(ctx context.Context, tx tx.WriteTx, obj common.Object) (err error) {
+	if obj == nil || obj.Data() == nil {
+		return
+	}
+	if !gjson.GetBytes(obj.Data(), fc.id.String()).Exists() {
+		return
+	}
+	return fc.Update(ctx, tx, obj.ObjectId(), nil)
+}
+
+func (fc *FormulaAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range fc.metaInfo {
+		m[key] = fc.metaInfo[key]
+	}
+	return m, nil
+}
+
+// "expression": 公式，见 formula_expr.go
+func (fc *FormulaAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := fc.name
+	oldkey := fc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range fc.metaInfo {
+		oldMetaInfo[key] = fc.metaInfo[key]
+	}
+	oldExpr := fc.expr
+	oldExprErr := fc.exprErr
+	defer func() {
+		if err != nil {
+			fc.name = oldName
+			fc.key = oldkey
+			fc.metaInfo = oldMetaInfo
+			fc.expr = oldExpr
+			fc.exprErr = oldExprErr
+			fc.registerHookFunc(ctx, tx)
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			fc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			fc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+
+	refresh := false
+	if expression, ok := v["expression"]; ok {
+		value, ok := expression.(string)
+		if !ok {
+			err = fmt.Errorf("set expression with error type")
+			return
+		}
+		var node formulaNode
+		var depList []common.AttributeClassId
+		node, depList, err = fc.compile(ctx, tx, value)
+		if err != nil {
+			return
+		}
+		if err = checkDependCycle(ctx, tx, fc.id, depList); err != nil {
+			return
+		}
+		if err = saveDepend(ctx, tx, fc.id, depList); err != nil {
+			return
+		}
+		fc.expr = node
+		fc.exprErr = nil
+		fc.metaInfo["expression"] = value
+		fc.metaInfo["dep_attribute"] = marshalDependList(depList)
+		if err = fc.registerHookFunc(ctx, tx); err != nil {
+			return
+		}
+		refresh = true
+		delete(v, "expression")
+	}
+	delete(v, "dep_attribute")
+
+	for key := range v {
+		fc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, fc.name, fc.key, fc.metaInfo, fc.id); err != nil {
+		return
+	}
+	if refresh {
+		err = fc.refreshAll(ctx, tx)
+	}
+	return
+}
+
+// 公式变化后重新计算全部对象
+func (fc *FormulaAttributeClass) refreshAll(ctx context.Context, tx tx.WriteTx) (err error) {
+	updateTable, ok := fc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have updated_table")
+		return
+	}
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err := tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+	for _, oid := range oidList {
+		if err = fc.Update(ctx, tx, oid, nil); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (fc *FormulaAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	obj, err := fc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	attr = fc.evaluate(ctx, obj)
+
+	//hook
+	fc.DoPreHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.InsertAttribute, attr))
+	defer func() { fc.DoAfterHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), fc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := fc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	return
+}
+
+func (fc *FormulaAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := fc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, fc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrFormula := &FormulaAttribute{
+		class: fc,
+		value: nil,
+	}
+	if err = attrFormula.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrFormula
+	return
+}
+
+// 公式的值由表达式计算得出，传入的attr会被忽略
+func (fc *FormulaAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, _ common.Attribute) (err error) {
+	obj, err := fc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	attr := common.Attribute(fc.evaluate(ctx, obj))
+
+	//hook
+	fc.DoPreHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.UpdateAttribute, attr))
+	defer func() { fc.DoAfterHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), fc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := fc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, opId, oid)
+	return
+}
+
+func (fc *FormulaAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := fc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	fc.DoPreHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.DeleteAttribute, nil))
+	defer func() { fc.DoAfterHook(ctx, fc.db, tx, NewOp(fc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), fc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := fc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (fc *FormulaAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := fc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have updated_table")
+		return
+	}
+	common.DeleteAfterAttributeHook(fc.id)
+
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, fc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = fc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, fc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = fc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), fc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	if err = dropDepend(ctx, tx, fc.id); err != nil {
+		return
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, fc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (fc *FormulaAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrFormula := &FormulaAttribute{
+		class: fc,
+		value: nil,
+	}
+	attr = attrFormula
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, fc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrFormula.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 构建查询
+func (fc *FormulaAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	jsonPath, ok := fc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have json_value_path")
+		return
+	}
+	return buildScalarQuery(jsonPath, v)
+}
+
+// 支持的筛选操作，计算结果的类型不固定，值可以是任意标量
+func (fc *FormulaAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"eq":   common.FilterValueScalar,
+		"neq":  common.FilterValueScalar,
+		"gt":   common.FilterValueScalar,
+		"gte":  common.FilterValueScalar,
+		"lt":   common.FilterValueScalar,
+		"lte":  common.FilterValueScalar,
+		"like": common.FilterValueScalar,
+	}
+}
+
+// 构建排序
+func (fc *FormulaAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	jsonPath, ok := fc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("FormulaAttribute metainfo dont have json_value_path")
+		return
+	}
+	key, err = sortKey(fmt.Sprintf("data ->> '%s'", jsonPath), nil, v)
+	return
+}
+
+func (t *FormulaAttribute) GetJSON() string {
+	data, err := json.Marshal(map[string]interface{}{"value": t.value})
+	if err != nil {
+		return `{"value":null}`
+	}
+	return string(data)
+}
+func (t *FormulaAttribute) String() string {
+	return formulaToString(t.value)
+}
+func (t *FormulaAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+
+// 公式属性只读
+func (t *FormulaAttribute) SetValue(v map[string]interface{}) (err error) {
+	err = fmt.Errorf("formula attribute is read only")
+	return
+}
+func (t *FormulaAttribute) Parse(v string) error {
+	result := gjson.Get(v, "value")
+	if !result.Exists() {
+		return fmt.Errorf("parse error: %v", v)
+	}
+	t.value = result.Value()
+	return nil
+}
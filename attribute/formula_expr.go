@@ -0,0 +1,950 @@
+package attribute
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/tidwall/gjson"
+
+	"paroket/common"
+)
+
+// 公式表达式语法：
+//
+//	字面量     1  2.5  "text"  true  false  null
+//	属性引用   prop("<attribute class id 或 key>")
+//	算术       + - * / %   （+ 任意一侧为字符串时做字符串拼接）
+//	比较       == != < <= > >=
+//	逻辑       and or not  （也可写作 && || !）
+//	函数       if(cond, a, b) concat(...) len(s) upper(s) lower(s)
+//	           abs(x) round(x[, n]) floor(x) ceil(x) min(...) max(...)
+//	           to_number(v) to_string(v) empty(v)
+//	           now() today() date(s) date_add(d, n, unit) date_sub(d, n, unit)
+//	           date_diff(a, b, unit) format_date(d[, layout]) year(d) month(d) day(d)
+//
+// 日期单位：years months weeks days hours minutes seconds（单复数均可）。
+//
+// 公式的结果在对象写入时计算并保存，now() today() 取的是写入时的时间，
+// 之后不会随时间自动更新，只在引用的属性写入或公式被修改时重新计算。
+// 计算出错时（如 to_number("abc")）结果为 null，写入本身不会失败。
+
+type formulaTokenKind int
+
+const (
+	formulaTokEOF formulaTokenKind = iota
+	formulaTokNumber
+	formulaTokString
+	formulaTokIdent
+	formulaTokOp
+	formulaTokLParen
+	formulaTokRParen
+	formulaTokComma
+)
+
+type formulaToken struct {
+	kind formulaTokenKind
+	text string
+	num  float64
+	pos  int
+}
+
+func lexFormula(src string) (tokens []formulaToken, err error) {
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			var num float64
+			num, err = strconv.ParseFloat(text, 64)
+			if err != nil {
+				err = fmt.Errorf("formula: invaild number %q at %d", text, start)
+				return
+			}
+			tokens = append(tokens, formulaToken{kind: formulaTokNumber, text: text, num: num, pos: start})
+		case r == '"' || r == '\'':
+			start := i
+			quote := r
+			i++
+			buf := &strings.Builder{}
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					buf.WriteRune(runes[i+1])
+					i += 2
+					continue
+				}
+				if runes[i] == quote {
+					closed = true
+					i++
+					break
+				}
+				buf.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				err = fmt.Errorf("formula: unterminated string at %d", start)
+				return
+			}
+			tokens = append(tokens, formulaToken{kind: formulaTokString, text: buf.String(), pos: start})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			text := string(runes[start:i])
+			switch text {
+			case "and", "or", "not":
+				tokens = append(tokens, formulaToken{kind: formulaTokOp, text: text, pos: start})
+			default:
+				tokens = append(tokens, formulaToken{kind: formulaTokIdent, text: text, pos: start})
+			}
+		case r == '(':
+			tokens = append(tokens, formulaToken{kind: formulaTokLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, formulaToken{kind: formulaTokRParen, text: ")", pos: i})
+			i++
+		case r == ',':
+			tokens = append(tokens, formulaToken{kind: formulaTokComma, text: ",", pos: i})
+			i++
+		default:
+			start := i
+			var op string
+			if i+1 < len(runes) {
+				switch string(runes[i : i+2]) {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					op = string(runes[i : i+2])
+				}
+			}
+			if op == "" {
+				switch r {
+				case '+', '-', '*', '/', '%', '<', '>', '!':
+					op = string(r)
+				case '=':
+					op = "=="
+				default:
+					err = fmt.Errorf("formula: unexpected character %q at %d", r, i)
+					return
+				}
+				i++
+			} else {
+				i += 2
+			}
+			switch op {
+			case "&&":
+				op = "and"
+			case "||":
+				op = "or"
+			case "!":
+				op = "not"
+			}
+			tokens = append(tokens, formulaToken{kind: formulaTokOp, text: op, pos: start})
+		}
+	}
+	tokens = append(tokens, formulaToken{kind: formulaTokEOF, pos: len(runes)})
+	return
+}
+
+// 公式语法树
+type formulaNode interface {
+	eval(env *formulaEnv) (interface{}, error)
+}
+
+type formulaLiteral struct {
+	value interface{}
+}
+
+type formulaProp struct {
+	ref       string
+	acid      common.AttributeClassId
+	valuePath string
+	class     common.AttributeClass
+}
+
+type formulaUnary struct {
+	op string
+	x  formulaNode
+}
+
+type formulaBinary struct {
+	op   string
+	l, r formulaNode
+}
+
+type formulaCall struct {
+	name string
+	args []formulaNode
+	pos  int
+}
+
+type formulaParser struct {
+	tokens []formulaToken
+	pos    int
+}
+
+func parseFormula(src string) (node formulaNode, err error) {
+	tokens, err := lexFormula(src)
+	if err != nil {
+		return
+	}
+	p := &formulaParser{tokens: tokens}
+	node, err = p.parseOr()
+	if err != nil {
+		return
+	}
+	if tok := p.peek(); tok.kind != formulaTokEOF {
+		err = fmt.Errorf("formula: unexpected %q at %d", tok.text, tok.pos)
+	}
+	return
+}
+
+func (p *formulaParser) peek() formulaToken {
+	return p.tokens[p.pos]
+}
+
+func (p *formulaParser) next() formulaToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != formulaTokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *formulaParser) isOp(ops ...string) bool {
+	tok := p.peek()
+	if tok.kind != formulaTokOp {
+		return false
+	}
+	for _, op := range ops {
+		if tok.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *formulaParser) parseOr() (node formulaNode, err error) {
+	node, err = p.parseAnd()
+	for err == nil && p.isOp("or") {
+		p.next()
+		var r formulaNode
+		r, err = p.parseAnd()
+		node = &formulaBinary{op: "or", l: node, r: r}
+	}
+	return
+}
+
+func (p *formulaParser) parseAnd() (node formulaNode, err error) {
+	node, err = p.parseNot()
+	for err == nil && p.isOp("and") {
+		p.next()
+		var r formulaNode
+		r, err = p.parseNot()
+		node = &formulaBinary{op: "and", l: node, r: r}
+	}
+	return
+}
+
+func (p *formulaParser) parseNot() (node formulaNode, err error) {
+	if p.isOp("not") {
+		p.next()
+		var x formulaNode
+		x, err = p.parseNot()
+		node = &formulaUnary{op: "not", x: x}
+		return
+	}
+	return p.parseCompare()
+}
+
+func (p *formulaParser) parseCompare() (node formulaNode, err error) {
+	node, err = p.parseAdd()
+	if err == nil && p.isOp("==", "!=", "<", "<=", ">", ">=") {
+		op := p.next().text
+		var r formulaNode
+		r, err = p.parseAdd()
+		node = &formulaBinary{op: op, l: node, r: r}
+	}
+	return
+}
+
+func (p *formulaParser) parseAdd() (node formulaNode, err error) {
+	node, err = p.parseMul()
+	for err == nil && p.isOp("+", "-") {
+		op := p.next().text
+		var r formulaNode
+		r, err = p.parseMul()
+		node = &formulaBinary{op: op, l: node, r: r}
+	}
+	return
+}
+
+func (p *formulaParser) parseMul() (node formulaNode, err error) {
+	node, err = p.parseUnary()
+	for err == nil && p.isOp("*", "/", "%") {
+		op := p.next().text
+		var r formulaNode
+		r, err = p.parseUnary()
+		node = &formulaBinary{op: op, l: node, r: r}
+	}
+	return
+}
+
+func (p *formulaParser) parseUnary() (node formulaNode, err error) {
+	if p.isOp("-") {
+		p.next()
+		var x formulaNode
+		x, err = p.parseUnary()
+		node = &formulaUnary{op: "-", x: x}
+		return
+	}
+	return p.parsePrimary()
+}
+
+func (p *formulaParser) parsePrimary() (node formulaNode, err error) {
+	tok := p.next()
+	switch tok.kind {
+	case formulaTokNumber:
+		node = &formulaLiteral{value: tok.num}
+	case formulaTokString:
+		node = &formulaLiteral{value: tok.text}
+	case formulaTokLParen:
+		node, err = p.parseOr()
+		if err != nil {
+			return
+		}
+		if t := p.next(); t.kind != formulaTokRParen {
+			err = fmt.Errorf("formula: expect ')' at %d", t.pos)
+		}
+	case formulaTokIdent:
+		switch tok.text {
+		case "true":
+			node = &formulaLiteral{value: true}
+			return
+		case "false":
+			node = &formulaLiteral{value: false}
+			return
+		case "null":
+			node = &formulaLiteral{value: nil}
+			return
+		}
+		if t := p.next(); t.kind != formulaTokLParen {
+			err = fmt.Errorf("formula: unknown identifier %q at %d", tok.text, tok.pos)
+			return
+		}
+		args := []formulaNode{}
+		if p.peek().kind != formulaTokRParen {
+			for {
+				var arg formulaNode
+				arg, err = p.parseOr()
+				if err != nil {
+					return
+				}
+				args = append(args, arg)
+				if p.peek().kind != formulaTokComma {
+					break
+				}
+				p.next()
+			}
+		}
+		if t := p.next(); t.kind != formulaTokRParen {
+			err = fmt.Errorf("formula: expect ')' at %d", t.pos)
+			return
+		}
+		if tok.text == "prop" {
+			if len(args) != 1 {
+				err = fmt.Errorf("formula: prop need one argument at %d", tok.pos)
+				return
+			}
+			var ref string
+			lit, ok := args[0].(*formulaLiteral)
+			if ok {
+				ref, ok = lit.value.(string)
+			}
+			if !ok {
+				err = fmt.Errorf("formula: prop argument must be a string at %d", tok.pos)
+				return
+			}
+			node = &formulaProp{ref: ref}
+			return
+		}
+		if _, ok := formulaFuncMap[tok.text]; !ok && tok.text != "if" {
+			err = fmt.Errorf("formula: unknown function %q at %d", tok.text, tok.pos)
+			return
+		}
+		node = &formulaCall{name: tok.text, args: args, pos: tok.pos}
+	default:
+		if tok.kind == formulaTokEOF {
+			err = fmt.Errorf("formula: unexpected end of expression")
+			return
+		}
+		err = fmt.Errorf("formula: unexpected %q at %d", tok.text, tok.pos)
+	}
+	return
+}
+
+// 遍历语法树中的全部属性引用
+func walkFormulaProp(node formulaNode, f func(p *formulaProp) error) (err error) {
+	switch n := node.(type) {
+	case *formulaProp:
+		err = f(n)
+	case *formulaUnary:
+		err = walkFormulaProp(n.x, f)
+	case *formulaBinary:
+		if err = walkFormulaProp(n.l, f); err != nil {
+			return
+		}
+		err = walkFormulaProp(n.r, f)
+	case *formulaCall:
+		for _, arg := range n.args {
+			if err = walkFormulaProp(arg, f); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+type formulaEnv struct {
+	data []byte
+	obj  common.Object
+	now  func() time.Time
+}
+
+func (n *formulaLiteral) eval(_ *formulaEnv) (interface{}, error) {
+	return n.value, nil
+}
+
+func (n *formulaProp) eval(env *formulaEnv) (v interface{}, err error) {
+	result := gjson.GetBytes(env.data, fmt.Sprintf("%v.%s", n.acid, n.valuePath))
+	switch result.Type {
+	case gjson.Null:
+		return nil, nil
+	case gjson.Number:
+		return result.Num, nil
+	case gjson.String:
+		return result.Str, nil
+	case gjson.True:
+		return true, nil
+	case gjson.False:
+		return false, nil
+	default:
+		// 复合值使用属性的字符串表示
+		if n.class == nil {
+			return result.Raw, nil
+		}
+		var attr common.Attribute
+		attr, err = n.class.FromObject(env.obj)
+		if err != nil {
+			return
+		}
+		return attr.String(), nil
+	}
+}
+
+func (n *formulaUnary) eval(env *formulaEnv) (v interface{}, err error) {
+	x, err := n.x.eval(env)
+	if err != nil {
+		return
+	}
+	switch n.op {
+	case "not":
+		return !formulaTruthy(x), nil
+	case "-":
+		var f float64
+		f, err = formulaToNumber(x)
+		return -f, err
+	}
+	return nil, fmt.Errorf("formula: unknown unary op %s", n.op)
+}
+
+func (n *formulaBinary) eval(env *formulaEnv) (v interface{}, err error) {
+	l, err := n.l.eval(env)
+	if err != nil {
+		return
+	}
+	// 短路求值
+	switch n.op {
+	case "and":
+		if !formulaTruthy(l) {
+			return false, nil
+		}
+		var r interface{}
+		r, err = n.r.eval(env)
+		return formulaTruthy(r), err
+	case "or":
+		if formulaTruthy(l) {
+			return true, nil
+		}
+		var r interface{}
+		r, err = n.r.eval(env)
+		return formulaTruthy(r), err
+	}
+	r, err := n.r.eval(env)
+	if err != nil {
+		return
+	}
+	switch n.op {
+	case "+":
+		_, ls := l.(string)
+		_, rs := r.(string)
+		if ls || rs {
+			return formulaToString(l) + formulaToString(r), nil
+		}
+		return formulaArith(n.op, l, r)
+	case "-", "*", "/", "%":
+		return formulaArith(n.op, l, r)
+	case "==", "!=", "<", "<=", ">", ">=":
+		return formulaCompare(n.op, l, r)
+	}
+	return nil, fmt.Errorf("formula: unknown op %s", n.op)
+}
+
+func formulaArith(op string, l, r interface{}) (v interface{}, err error) {
+	a, err := formulaToNumber(l)
+	if err != nil {
+		return
+	}
+	b, err := formulaToNumber(r)
+	if err != nil {
+		return
+	}
+	switch op {
+	case "+":
+		return a + b, nil
+	case "-":
+		return a - b, nil
+	case "*":
+		return a * b, nil
+	case "/":
+		if b == 0 {
+			return nil, fmt.Errorf("formula: division by zero")
+		}
+		return a / b, nil
+	case "%":
+		if b == 0 {
+			return nil, fmt.Errorf("formula: division by zero")
+		}
+		return math.Mod(a, b), nil
+	}
+	return nil, fmt.Errorf("formula: unknown op %s", op)
+}
+
+func formulaCompare(op string, l, r interface{}) (v interface{}, err error) {
+	var c int
+	switch lv := l.(type) {
+	case nil:
+		switch op {
+		case "==":
+			return r == nil, nil
+		case "!=":
+			return r != nil, nil
+		}
+		return false, nil
+	case float64:
+		var rv float64
+		if rv, err = formulaToNumber(r); err != nil {
+			return compareFallback(op, l, r)
+		}
+		c = compareFloat(lv, rv)
+	case bool:
+		rv, ok := r.(bool)
+		if !ok {
+			return compareFallback(op, l, r)
+		}
+		if lv == rv {
+			c = 0
+		} else if !lv {
+			c = -1
+		} else {
+			c = 1
+		}
+	case time.Time:
+		var rv time.Time
+		if rv, err = formulaToTime(r); err != nil {
+			return compareFallback(op, l, r)
+		}
+		c = lv.Compare(rv)
+	case string:
+		switch rv := r.(type) {
+		case time.Time:
+			var lt time.Time
+			if lt, err = formulaToTime(lv); err != nil {
+				return compareFallback(op, l, r)
+			}
+			c = lt.Compare(rv)
+		case string:
+			c = strings.Compare(lv, rv)
+		default:
+			return compareFallback(op, l, r)
+		}
+	default:
+		return nil, fmt.Errorf("formula: can not compare %T", l)
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("formula: unknown op %s", op)
+}
+
+// 类型不同的值只能判断是否相等
+func compareFallback(op string, l, r interface{}) (v interface{}, err error) {
+	switch op {
+	case "==":
+		return false, nil
+	case "!=":
+		return true, nil
+	}
+	return nil, fmt.Errorf("formula: can not compare %T with %T", l, r)
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (n *formulaCall) eval(env *formulaEnv) (v interface{}, err error) {
+	// if 只对命中的分支求值
+	if n.name == "if" {
+		if len(n.args) != 3 {
+			return nil, fmt.Errorf("formula: if need 3 arguments at %d", n.pos)
+		}
+		var cond interface{}
+		cond, err = n.args[0].eval(env)
+		if err != nil {
+			return
+		}
+		if formulaTruthy(cond) {
+			return n.args[1].eval(env)
+		}
+		return n.args[2].eval(env)
+	}
+	fn := formulaFuncMap[n.name]
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		var a interface{}
+		a, err = arg.eval(env)
+		if err != nil {
+			return
+		}
+		args = append(args, a)
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("formula: %s get wrong number of arguments at %d", n.name, n.pos)
+	}
+	return fn.impl(env, args)
+}
+
+type formulaFunc struct {
+	minArgs int
+	maxArgs int // -1 表示不限
+	impl    func(env *formulaEnv, args []interface{}) (interface{}, error)
+}
+
+var formulaFuncMap map[string]formulaFunc
+
+func init() {
+	formulaFuncMap = map[string]formulaFunc{
+		"concat": {0, -1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			buf := &strings.Builder{}
+			for _, a := range args {
+				buf.WriteString(formulaToString(a))
+			}
+			return buf.String(), nil
+		}},
+		"len": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return float64(len([]rune(formulaToString(args[0])))), nil
+		}},
+		"upper": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return strings.ToUpper(formulaToString(args[0])), nil
+		}},
+		"lower": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return strings.ToLower(formulaToString(args[0])), nil
+		}},
+		"empty": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return args[0] == nil || formulaToString(args[0]) == "", nil
+		}},
+		"to_string": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return formulaToString(args[0]), nil
+		}},
+		"to_number": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return formulaToNumber(args[0])
+		}},
+		"abs":   {1, 1, formulaMathFunc(math.Abs)},
+		"floor": {1, 1, formulaMathFunc(math.Floor)},
+		"ceil":  {1, 1, formulaMathFunc(math.Ceil)},
+		"round": {1, 2, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			x, err := formulaToNumber(args[0])
+			if err != nil {
+				return nil, err
+			}
+			digits := 0.0
+			if len(args) == 2 {
+				if digits, err = formulaToNumber(args[1]); err != nil {
+					return nil, err
+				}
+			}
+			p := math.Pow(10, digits)
+			return math.Round(x*p) / p, nil
+		}},
+		"min": {1, -1, formulaReduceFunc(math.Min)},
+		"max": {1, -1, formulaReduceFunc(math.Max)},
+		"now": {0, 0, func(env *formulaEnv, _ []interface{}) (interface{}, error) {
+			return env.now(), nil
+		}},
+		"today": {0, 0, func(env *formulaEnv, _ []interface{}) (interface{}, error) {
+			t := env.now()
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+		}},
+		"date": {1, 1, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return formulaToTime(args[0])
+		}},
+		"date_add": {3, 3, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return formulaDateAdd(args, 1)
+		}},
+		"date_sub": {3, 3, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			return formulaDateAdd(args, -1)
+		}},
+		"date_diff": {3, 3, formulaDateDiff},
+		"format_date": {1, 2, func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+			t, err := formulaToTime(args[0])
+			if err != nil {
+				return nil, err
+			}
+			layout := "2006-01-02"
+			if len(args) == 2 {
+				layout = formulaToString(args[1])
+			}
+			return t.Format(layout), nil
+		}},
+		"year":  {1, 1, formulaDatePartFunc(func(t time.Time) int { return t.Year() })},
+		"month": {1, 1, formulaDatePartFunc(func(t time.Time) int { return int(t.Month()) })},
+		"day":   {1, 1, formulaDatePartFunc(func(t time.Time) int { return t.Day() })},
+	}
+}
+
+func formulaMathFunc(f func(float64) float64) func(*formulaEnv, []interface{}) (interface{}, error) {
+	return func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+		x, err := formulaToNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return f(x), nil
+	}
+}
+
+func formulaReduceFunc(f func(float64, float64) float64) func(*formulaEnv, []interface{}) (interface{}, error) {
+	return func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+		ret, err := formulaToNumber(args[0])
+		if err != nil {
+			return nil, err
+		}
+		for _, arg := range args[1:] {
+			x, err := formulaToNumber(arg)
+			if err != nil {
+				return nil, err
+			}
+			ret = f(ret, x)
+		}
+		return ret, nil
+	}
+}
+
+func formulaDatePartFunc(f func(time.Time) int) func(*formulaEnv, []interface{}) (interface{}, error) {
+	return func(_ *formulaEnv, args []interface{}) (interface{}, error) {
+		t, err := formulaToTime(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return float64(f(t)), nil
+	}
+}
+
+func formulaDateAdd(args []interface{}, sign int) (v interface{}, err error) {
+	t, err := formulaToTime(args[0])
+	if err != nil {
+		return
+	}
+	n, err := formulaToNumber(args[1])
+	if err != nil {
+		return
+	}
+	n *= float64(sign)
+	switch strings.TrimSuffix(formulaToString(args[2]), "s") {
+	case "year":
+		return t.AddDate(int(n), 0, 0), nil
+	case "month":
+		return t.AddDate(0, int(n), 0), nil
+	case "week":
+		return t.AddDate(0, 0, int(n)*7), nil
+	case "day":
+		return t.AddDate(0, 0, int(n)), nil
+	case "hour":
+		return t.Add(time.Duration(n * float64(time.Hour))), nil
+	case "minute":
+		return t.Add(time.Duration(n * float64(time.Minute))), nil
+	case "second":
+		return t.Add(time.Duration(n * float64(time.Second))), nil
+	}
+	return nil, fmt.Errorf("formula: unknown date unit %v", args[2])
+}
+
+func formulaDateDiff(_ *formulaEnv, args []interface{}) (v interface{}, err error) {
+	a, err := formulaToTime(args[0])
+	if err != nil {
+		return
+	}
+	b, err := formulaToTime(args[1])
+	if err != nil {
+		return
+	}
+	d := a.Sub(b)
+	switch strings.TrimSuffix(formulaToString(args[2]), "s") {
+	case "year":
+		return math.Trunc(float64(monthDiff(a, b)) / 12), nil
+	case "month":
+		return float64(monthDiff(a, b)), nil
+	case "week":
+		return math.Trunc(d.Hours() / 24 / 7), nil
+	case "day":
+		return math.Trunc(d.Hours() / 24), nil
+	case "hour":
+		return math.Trunc(d.Hours()), nil
+	case "minute":
+		return math.Trunc(d.Minutes()), nil
+	case "second":
+		return math.Trunc(d.Seconds()), nil
+	}
+	return nil, fmt.Errorf("formula: unknown date unit %v", args[2])
+}
+
+// 按日历计算 a - b 相差的整月数
+func monthDiff(a, b time.Time) int {
+	months := (a.Year()-b.Year())*12 + int(a.Month()) - int(b.Month())
+	if months > 0 && a.Day() < b.Day() {
+		months--
+	}
+	if months < 0 && a.Day() > b.Day() {
+		months++
+	}
+	return months
+}
+
+func formulaTruthy(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return value
+	case float64:
+		return value != 0
+	case string:
+		return value != ""
+	}
+	return true
+}
+
+func formulaToNumber(v interface{}) (float64, error) {
+	switch value := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return value, nil
+	case bool:
+		if value {
+			return 1, nil
+		}
+		return 0, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 0, fmt.Errorf("formula: can not convert %q to number", value)
+		}
+		return f, nil
+	case time.Time:
+		return float64(value.Unix()), nil
+	}
+	return 0, fmt.Errorf("formula: can not convert %T to number", v)
+}
+
+func formulaToString(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+var formulaTimeLayouts = []string{
+	time.RFC3339Nano,
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+func formulaToTime(v interface{}) (t time.Time, err error) {
+	switch value := v.(type) {
+	case time.Time:
+		return value, nil
+	case float64:
+		return time.Unix(int64(value), 0).UTC(), nil
+	case string:
+		for _, layout := range formulaTimeLayouts {
+			if t, err = time.Parse(layout, strings.TrimSpace(value)); err == nil {
+				return
+			}
+		}
+		return t, fmt.Errorf("formula: can not convert %q to date", value)
+	}
+	return t, fmt.Errorf("formula: can not convert %T to date", v)
+}
+
+// 公式结果转换为可存入对象的json值
+func formulaJSONValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case float64:
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,619 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+	"slices"
+	"strings"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type MultiSelectAttributeClass struct {
+	AttributeClassInfo
+}
+
+type MultiSelectAttribute struct {
+	class *MultiSelectAttributeClass
+	value []string
+}
+
+func newMultiSelectAttributeClass(_ context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	updateTable := fmt.Sprintf(`multi_select_%v`, id)
+
+	act := &MultiSelectAttributeClass{
+		AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "multi_select",
+			key:      id.String(),
+			attrType: AttributeTypeMultiSelect,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "idx",
+				"options":          "[]",
+			},
+		},
+	}
+	ac = act
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, act.id, act.name, act.key, act.attrType, act.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+
+	return
+}
+
+func parseMultiSelectAttributeClass(_ context.Context, _ tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	ac = &MultiSelectAttributeClass{*acProto}
+	return
+}
+
+func (mc *MultiSelectAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range mc.metaInfo {
+		m[key] = mc.metaInfo[key]
+	}
+	return m, nil
+}
+
+func (mc *MultiSelectAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := mc.name
+	oldkey := mc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range mc.metaInfo {
+		oldMetaInfo[key] = mc.metaInfo[key]
+	}
+	defer func() {
+		if err != nil {
+			mc.name = oldName
+			mc.key = oldkey
+			mc.metaInfo = oldMetaInfo
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			mc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			mc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+
+	refresh := false
+	if options, ok := v["options"]; ok {
+		var optionsStr string
+		if optionsStr, err = normalizeSelectOptions(options); err != nil {
+			return
+		}
+		mc.metaInfo["options"] = optionsStr
+		refresh = true
+		delete(v, "options")
+	}
+	for key := range v {
+		mc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, mc.name, mc.key, mc.metaInfo, mc.id); err != nil {
+		return
+	}
+	if refresh {
+		err = mc.refreshOptions(ctx, tx)
+	}
+	return
+}
+
+func (mc *MultiSelectAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	attrMulti := &MultiSelectAttribute{
+		class: mc,
+		value: []string{},
+	}
+	attr = attrMulti
+	obj, err := mc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	mc.DoPreHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.InsertAttribute, attr))
+	defer func() { mc.DoAfterHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), mc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := mc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	if err != nil {
+		return
+	}
+	return
+
+}
+func (mc *MultiSelectAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := mc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, mc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrMulti := &MultiSelectAttribute{
+		class: mc,
+		value: []string{},
+	}
+	if err = attrMulti.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrMulti
+	return
+}
+func (mc *MultiSelectAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, attr common.Attribute) (err error) {
+	obj, err := mc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	mc.DoPreHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.UpdateAttribute, attr))
+	defer func() { mc.DoAfterHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), mc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := mc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	if _, err = tx.Exac(update, opId, oid); err != nil {
+		return
+	}
+
+	return
+}
+func (mc *MultiSelectAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := mc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	mc.DoPreHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.DeleteAttribute, nil))
+	defer func() { mc.DoAfterHook(ctx, mc.db, tx, NewOp(mc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), mc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := mc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (mc *MultiSelectAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := mc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have updated_table")
+		return
+	}
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, mc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = mc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, mc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = mc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), mc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, mc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (mc *MultiSelectAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrMulti := &MultiSelectAttribute{
+		class: mc,
+		value: []string{},
+	}
+	attr = attrMulti
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, mc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrMulti.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 选项变化后移除已删除的选项，并更新索引中的选项名称
+func (mc *MultiSelectAttributeClass) refreshOptions(ctx context.Context, tx tx.WriteTx) (err error) {
+	updateTable, ok := mc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have updated_table")
+		return
+	}
+	options, err := parseSelectOptions(mc.metaInfo)
+	if err != nil {
+		return
+	}
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err := tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+	for _, oid := range oidList {
+		var attr common.Attribute
+		if attr, err = mc.FindId(ctx, tx, oid); err != nil {
+			return
+		}
+		attrMulti := attr.(*MultiSelectAttribute)
+		idList := []string{}
+		for _, id := range attrMulti.value {
+			if _, ok := findSelectOption(options, id); ok {
+				idList = append(idList, id)
+			}
+		}
+		attrMulti.value = idList
+		if err = mc.Update(ctx, tx, oid, attrMulti); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 构建查询，查询值可以是选项id或选项名称
+func (mc *MultiSelectAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	jsonPath, ok := mc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	options, err := parseSelectOptions(mc.metaInfo)
+	if err != nil {
+		return
+	}
+	if op == "is_empty" {
+		empty, ok := v["value"].(bool)
+		if !ok {
+			empty = true
+		}
+		cmp := "="
+		if !empty {
+			cmp = ">"
+		}
+		stmt = fmt.Sprintf(`(COALESCE(json_array_length(data, '%s'), 0) %s 0)`, jsonPath, cmp)
+		return
+	}
+
+	idList, err := resolveSelectOptionList(options, v["value"])
+	if err != nil {
+		return
+	}
+	if (op == "is" || op == "is_not") && len(idList) != 1 {
+		err = fmt.Errorf("invaild select option list:%v", v["value"])
+		return
+	}
+	containStmt := func(idList []string) string {
+		args = append(args, selectIdListArgs(idList)...)
+		return fmt.Sprintf(
+			`EXISTS (SELECT 1 FROM json_each(data, '%s') WHERE json_each.value IN (%s))`,
+			jsonPath,
+			placeholders(len(idList)),
+		)
+	}
+	switch op {
+	case "is":
+		stmt = fmt.Sprintf(`(%s)`, containStmt(idList))
+	case "is_not":
+		stmt = fmt.Sprintf(`(NOT %s)`, containStmt(idList))
+	case "contains_any":
+		stmt = fmt.Sprintf(`(%s)`, containStmt(idList))
+	case "contains_all":
+		// 空列表总是满足
+		if len(idList) == 0 {
+			stmt = "(1)"
+			return
+		}
+		stmtList := []string{}
+		for _, id := range idList {
+			stmtList = append(stmtList, containStmt([]string{id}))
+		}
+		stmt = fmt.Sprintf(`(%s)`, strings.Join(stmtList, " AND "))
+	default:
+		err = fmt.Errorf("unsupport op:%s", op)
+	}
+	return
+}
+
+// 支持的筛选操作，选项可以使用选项id或名称
+func (mc *MultiSelectAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"is":           common.FilterValueString,
+		"is_not":       common.FilterValueString,
+		"contains_any": common.FilterValueStrings,
+		"contains_all": common.FilterValueStrings,
+		"is_empty":     common.FilterValueOptionalBool,
+	}
+}
+
+// 构建排序，按已选选项中最靠前的选项顺序排序
+func (mc *MultiSelectAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	jsonPath, ok := mc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	options, err := parseSelectOptions(mc.metaInfo)
+	if err != nil {
+		return
+	}
+	caseExpr, args := selectOrderExpr("json_each.value", options)
+	orderExpr := fmt.Sprintf(
+		`(SELECT MIN(%s) FROM json_each(data, '%s'))`,
+		caseExpr,
+		jsonPath,
+	)
+	key, err = sortKey(orderExpr, args, v)
+	return
+}
+
+// 按选项分组，对象属于每个选中的选项的分组
+func (mc *MultiSelectAttributeClass) BuildGroup(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	jsonPath, ok := mc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("MultiSelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	stmt = fmt.Sprintf(`COALESCE(data -> '%s', '[]')`, jsonPath)
+	return
+}
+
+// 按选项顺序返回已选的选项
+func (t *MultiSelectAttribute) optionList() (optionList []SelectOption) {
+	optionList = []SelectOption{}
+	options, err := parseSelectOptions(t.class.metaInfo)
+	if err != nil {
+		return
+	}
+	for _, option := range options {
+		if slices.Contains(t.value, option.Id) {
+			optionList = append(optionList, option)
+		}
+	}
+	return
+}
+
+func (t *MultiSelectAttribute) GetJSON() string {
+	idList := []string{}
+	nameList := []string{}
+	for _, option := range t.optionList() {
+		idList = append(idList, option.Id)
+		nameList = append(nameList, option.Name)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"value": idList,
+		"idx":   strings.Join(nameList, " "),
+	})
+	if err != nil {
+		return `{"value":[],"idx":""}`
+	}
+	return string(data)
+}
+func (t *MultiSelectAttribute) String() string {
+	nameList := []string{}
+	for _, option := range t.optionList() {
+		nameList = append(nameList, option.Name)
+	}
+	return strings.Join(nameList, ", ")
+}
+func (t *MultiSelectAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+
+// value为选项id或选项名称的列表
+func (t *MultiSelectAttribute) SetValue(v map[string]interface{}) (err error) {
+	value, ok := v["value"]
+	if !ok {
+		err = fmt.Errorf("invaild set value:%v", v)
+		return
+	}
+	options, err := parseSelectOptions(t.class.metaInfo)
+	if err != nil {
+		return
+	}
+	idList, err := resolveSelectOptionList(options, value)
+	if err != nil {
+		return
+	}
+	t.value = idList
+	return
+}
+func (t *MultiSelectAttribute) Parse(v string) error {
+	result := gjson.Get(v, "value")
+	if !result.IsArray() {
+		return fmt.Errorf("parse error: %v", v)
+	}
+	idList := []string{}
+	for _, id := range result.Array() {
+		idList = append(idList, id.String())
+	}
+	t.value = idList
+	return nil
+}
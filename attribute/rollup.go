@@ -0,0 +1,876 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+	"strings"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// 汇总方式
+const (
+	RollupCount        = "count"
+	RollupSum          = "sum"
+	RollupAvg          = "avg"
+	RollupMin          = "min"
+	RollupMax          = "max"
+	RollupStd          = "std"
+	RollupConcatUnique = "concat_unique"
+)
+
+type RollupAttributeClass struct {
+	AttributeClassInfo
+}
+
+type RollupAttribute struct {
+	class *RollupAttributeClass
+	value interface{}
+}
+
+func newRollupAttributeClass(ctx context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	updateTable := fmt.Sprintf(`rollup_%v`, id)
+
+	rc := &RollupAttributeClass{
+		AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "rollup",
+			key:      id.String(),
+			attrType: AttributeTypeRollup,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "value",
+				"link_attribute":   "",
+				"target_attribute": "",
+				"aggregation":      RollupCount,
+			},
+		},
+	}
+	ac = rc
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, rc.id, rc.name, rc.key, rc.attrType, rc.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+	err = rc.registerHookFunc(ctx, tx)
+	return
+}
+
+func parseRollupAttributeClass(ctx context.Context, tx tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	rc := &RollupAttributeClass{*acProto}
+	if err = rc.registerHookFunc(ctx, tx); err != nil {
+		return
+	}
+	ac = rc
+	return
+}
+
+// 读取metainfo中的属性类id，未配置时返回false
+func (rc *RollupAttributeClass) metaAcid(key string) (acid common.AttributeClassId, ok bool, err error) {
+	idStr, isStr := rc.metaInfo[key].(string)
+	if !isStr {
+		err = fmt.Errorf("RollupAttributeClass %v %s error", rc.id, key)
+		return
+	}
+	if idStr == "" {
+		return
+	}
+	if err = acid.Scan(idStr); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+func (rc *RollupAttributeClass) registerHookFunc(_ context.Context, _ tx.ReadTx) (nerr error) {
+	linkAcid, hasLink, nerr := rc.metaAcid("link_attribute")
+	if nerr != nil {
+		return
+	}
+	targetAcid, hasTarget, nerr := rc.metaAcid("target_attribute")
+	if nerr != nil {
+		return
+	}
+	if !hasLink {
+		common.DeleteAfterAttributeHook(rc.id)
+		return
+	}
+
+	afterF := func(ctx context.Context, db common.Database, tx tx.WriteTx, op common.AttributeOp) (err error) {
+		// link变化时，link_obj_table已经更新，直接重新汇总本对象
+		if op.ClassId() == linkAcid {
+			err = rc.refresh(ctx, tx, op.Object())
+			return
+		}
+		// 被关联对象的目标属性变化时，重新汇总关联了该对象的全部对象
+		if hasTarget && op.ClassId() == targetAcid && op.Object() != nil {
+			var oidList []common.ObjectId
+			oidList, err = rc.findLinkedBy(ctx, tx, op.Object().ObjectId())
+			if err != nil {
+				return
+			}
+			for _, oid := range oidList {
+				var obj common.Object
+				obj, err = db.OpenObject(ctx, tx, oid)
+				if err != nil {
+					return
+				}
+				if err = rc.refresh(ctx, tx, obj); err != nil {
+					return
+				}
+			}
+		}
+		return
+	}
+	nerr = common.RegisterAfterAttributeHook(rc.id, afterF)
+	return
+}
+
+// 删除对象后重新汇总关联了该对象的全部对象
+// 对象已经删除，汇总时不再包含它，link_obj_table中的记录仍可用于查找关联了它的对象
+func refreshRollupAfterDeleteObject(ctx context.Context, db common.Database, tx tx.WriteTx, obj common.Object) (err error) {
+	query := `SELECT class_id FROM attribute_classes WHERE attribute_type = ?`
+	rows, err := tx.Query(query, AttributeTypeRollup)
+	if err != nil {
+		return
+	}
+	acidList := []common.AttributeClassId{}
+	for rows.Next() {
+		var acid common.AttributeClassId
+		if err = rows.Scan(&acid); err != nil {
+			rows.Close()
+			return
+		}
+		acidList = append(acidList, acid)
+	}
+	rows.Close()
+	for _, acid := range acidList {
+		var ac common.AttributeClass
+		if ac, err = db.OpenAttributeClass(ctx, tx, acid); err != nil {
+			return
+		}
+		rc, ok := ac.(*RollupAttributeClass)
+		if !ok {
+			continue
+		}
+		if _, hasLink, _ := rc.metaAcid("link_attribute"); !hasLink {
+			continue
+		}
+		var oidList []common.ObjectId
+		if oidList, err = rc.findLinkedBy(ctx, tx, obj.ObjectId()); err != nil {
+			return
+		}
+		for _, oid := range oidList {
+			if oid == obj.ObjectId() {
+				continue
+			}
+			var linkedObj common.Object
+			if linkedObj, err = db.OpenObject(ctx, tx, oid); err != nil {
+				if err == sql.ErrNoRows {
+					err = nil
+					continue
+				}
+				return
+			}
+			if err = rc.refresh(ctx, tx, linkedObj); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) linkObjTable(ctx context.Context, tx tx.ReadTx) (linkObjTable string, err error) {
+	linkAcid, ok, err := rc.metaAcid("link_attribute")
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("RollupAttributeClass %v link attribute unset", rc.id)
+		return
+	}
+	linkAc, err := rc.db.OpenAttributeClass(ctx, tx, linkAcid)
+	if err != nil {
+		return
+	}
+	metaInfo, err := linkAc.GetMetaInfo(ctx, tx)
+	if err != nil {
+		return
+	}
+	linkObjTable, ok = metaInfo["link_obj_table"].(string)
+	if !ok {
+		err = fmt.Errorf("linkAttributeClass metainfo dont have link_obj_table")
+	}
+	return
+}
+
+// 查找通过link关联了oid的对象
+func (rc *RollupAttributeClass) findLinkedBy(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (oidList []common.ObjectId, err error) {
+	linkObjTable, err := rc.linkObjTable(ctx, tx)
+	if err != nil {
+		return
+	}
+	query := fmt.Sprintf(`
+	SELECT DISTINCT object_id FROM %s WHERE ref_object_id = ?`, linkObjTable)
+	rows, err := tx.Query(query, oid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var linkedOid common.ObjectId
+		if err = rows.Scan(&linkedOid); err != nil {
+			return
+		}
+		oidList = append(oidList, linkedOid)
+	}
+	return
+}
+
+// 在sqlite中计算汇总值
+func (rc *RollupAttributeClass) compute(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (value interface{}, err error) {
+	if _, ok, _ := rc.metaAcid("link_attribute"); !ok {
+		return
+	}
+	linkObjTable, err := rc.linkObjTable(ctx, tx)
+	if err != nil {
+		return
+	}
+	aggregation, _ := rc.metaInfo["aggregation"].(string)
+	if aggregation == RollupCount {
+		// 只统计仍然存在的关联对象
+		query := fmt.Sprintf(`
+	SELECT COUNT(*) FROM %s l
+	JOIN objects o ON o.object_id = l.ref_object_id
+	WHERE l.object_id = ?`, linkObjTable)
+		var count int64
+		if err = tx.QueryRow(query, oid).Scan(&count); err != nil {
+			return
+		}
+		value = float64(count)
+		return
+	}
+
+	targetAcid, ok, err := rc.metaAcid("target_attribute")
+	if err != nil {
+		return
+	}
+	if !ok {
+		err = fmt.Errorf("RollupAttributeClass %v target attribute unset", rc.id)
+		return
+	}
+	targetAc, err := rc.db.OpenAttributeClass(ctx, tx, targetAcid)
+	if err != nil {
+		return
+	}
+	metaInfo, err := targetAc.GetMetaInfo(ctx, tx)
+	if err != nil {
+		return
+	}
+	jsonPath, ok := metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("rollup target attribute metainfo dont have json_value_path")
+		return
+	}
+
+	valueStmt := fmt.Sprintf(`
+	SELECT o.data ->> '%s' AS v FROM %s l
+	JOIN objects o ON o.object_id = l.ref_object_id
+	WHERE l.object_id = ?`, jsonPath, linkObjTable)
+	var query string
+	switch aggregation {
+	case RollupSum:
+		// 没有关联对象时求和为0
+		query = fmt.Sprintf(`SELECT total(v) FROM (%s)`, valueStmt)
+	case RollupAvg, RollupMin, RollupMax, RollupStd:
+		query = fmt.Sprintf(`SELECT %s(v) FROM (%s)`, aggregation, valueStmt)
+	case RollupConcatUnique:
+		value, err = rc.concatUnique(tx, valueStmt, oid)
+		return
+	default:
+		err = fmt.Errorf("unsupport rollup aggregation:%s", aggregation)
+		return
+	}
+	var retValue interface{}
+	if err = tx.QueryRow(query, oid).Scan(&retValue); err != nil {
+		return
+	}
+	value = rollupValue(retValue)
+	return
+}
+
+// 去重后按出现顺序拼接，格式化方式与公式保持一致
+func (rc *RollupAttributeClass) concatUnique(tx tx.ReadTx, valueStmt string, oid common.ObjectId) (value interface{}, err error) {
+	query := fmt.Sprintf(`
+	SELECT DISTINCT v FROM (%s) WHERE v IS NOT NULL`, valueStmt)
+	rows, err := tx.Query(query, oid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	strList := []string{}
+	for rows.Next() {
+		var v interface{}
+		if err = rows.Scan(&v); err != nil {
+			return
+		}
+		strList = append(strList, formulaToString(rollupValue(v)))
+	}
+	value = strings.Join(strList, ", ")
+	return
+}
+
+func rollupValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case int64:
+		return float64(value)
+	case []byte:
+		return string(value)
+	}
+	return v
+}
+
+// 重新计算并写入汇总值，只处理已经拥有该属性的对象
+func (rc *RollupAttributeClass) refresh(ctx context.Context, tx tx.WriteTx, obj common.Object) (err error) {
+	if obj == nil || obj.Data() == nil {
+		return
+	}
+	if !gjson.GetBytes(obj.Data(), rc.id.String()).Exists() {
+		return
+	}
+	return rc.Update(ctx, tx, obj.ObjectId(), nil)
+}
+
+// 配置变化后重新计算全部对象
+func (rc *RollupAttributeClass) refreshAll(ctx context.Context, tx tx.WriteTx) (err error) {
+	updateTable, ok := rc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have updated_table")
+		return
+	}
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err := tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+	for _, oid := range oidList {
+		if err = rc.Update(ctx, tx, oid, nil); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range rc.metaInfo {
+		m[key] = rc.metaInfo[key]
+	}
+	return m, nil
+}
+
+func acidFromSetValue(key string, v interface{}) (acid string, err error) {
+	switch value := v.(type) {
+	case string:
+		acid = value
+	case common.AttributeClassId:
+		acid = value.String()
+	case common.AttributeClass:
+		acid = value.ClassId().String()
+	default:
+		err = fmt.Errorf("set %s with error type", key)
+	}
+	return
+}
+
+// "link_attribute":   link属性类,
+// "target_attribute": 被关联对象上需要汇总的属性类,
+// "aggregation":      count sum avg min max std concat_unique
+func (rc *RollupAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := rc.name
+	oldkey := rc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range rc.metaInfo {
+		oldMetaInfo[key] = rc.metaInfo[key]
+	}
+	defer func() {
+		if err != nil {
+			rc.name = oldName
+			rc.key = oldkey
+			rc.metaInfo = oldMetaInfo
+			rc.registerHookFunc(ctx, tx)
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			rc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			rc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+
+	refresh := false
+	if link, ok := v["link_attribute"]; ok {
+		var acid string
+		if acid, err = acidFromSetValue("link_attribute", link); err != nil {
+			return
+		}
+		rc.metaInfo["link_attribute"] = acid
+		refresh = true
+		delete(v, "link_attribute")
+	}
+	if target, ok := v["target_attribute"]; ok {
+		var acid string
+		if acid, err = acidFromSetValue("target_attribute", target); err != nil {
+			return
+		}
+		rc.metaInfo["target_attribute"] = acid
+		refresh = true
+		delete(v, "target_attribute")
+	}
+	if aggregation, ok := v["aggregation"]; ok {
+		value, ok := aggregation.(string)
+		if !ok {
+			err = fmt.Errorf("set aggregation with error type")
+			return
+		}
+		switch value {
+		case RollupCount, RollupSum, RollupAvg, RollupMin, RollupMax, RollupStd, RollupConcatUnique:
+		default:
+			err = fmt.Errorf("unsupport rollup aggregation:%s", value)
+			return
+		}
+		rc.metaInfo["aggregation"] = value
+		refresh = true
+		delete(v, "aggregation")
+	}
+
+	if refresh {
+		depList := []common.AttributeClassId{}
+		linkAcid, hasLink, nerr := rc.metaAcid("link_attribute")
+		if nerr != nil {
+			err = nerr
+			return
+		}
+		if hasLink {
+			var linkAc common.AttributeClass
+			linkAc, err = rc.db.OpenAttributeClass(ctx, tx, linkAcid)
+			if err != nil {
+				return
+			}
+			if _, ok := linkAc.(*LinkAttributeClass); !ok {
+				err = fmt.Errorf("rollup link_attribute %v is not a link", linkAcid)
+				return
+			}
+			depList = append(depList, linkAcid)
+		}
+		targetAcid, hasTarget, nerr := rc.metaAcid("target_attribute")
+		if nerr != nil {
+			err = nerr
+			return
+		}
+		if hasTarget {
+			if _, err = rc.db.OpenAttributeClass(ctx, tx, targetAcid); err != nil {
+				return
+			}
+			depList = append(depList, targetAcid)
+		}
+		if err = checkDependCycle(ctx, tx, rc.id, depList); err != nil {
+			return
+		}
+		if err = saveDepend(ctx, tx, rc.id, depList); err != nil {
+			return
+		}
+		if err = rc.registerHookFunc(ctx, tx); err != nil {
+			return
+		}
+	}
+
+	for key := range v {
+		rc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, rc.name, rc.key, rc.metaInfo, rc.id); err != nil {
+		return
+	}
+	if refresh {
+		err = rc.refreshAll(ctx, tx)
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	obj, err := rc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	value, err := rc.compute(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	attr = &RollupAttribute{
+		class: rc,
+		value: value,
+	}
+
+	//hook
+	rc.DoPreHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.InsertAttribute, attr))
+	defer func() { rc.DoAfterHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), rc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := rc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	if err != nil {
+		return
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := rc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, rc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrRollup := &RollupAttribute{
+		class: rc,
+		value: nil,
+	}
+	if err = attrRollup.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrRollup
+	return
+}
+
+// 汇总的值由关联对象计算得出，传入的attr会被忽略
+func (rc *RollupAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, _ common.Attribute) (err error) {
+	obj, err := rc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	value, err := rc.compute(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	attr := common.Attribute(&RollupAttribute{
+		class: rc,
+		value: value,
+	})
+
+	//hook
+	rc.DoPreHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.UpdateAttribute, attr))
+	defer func() { rc.DoAfterHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), rc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := rc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	if _, err = tx.Exac(update, opId, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := rc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	rc.DoPreHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.DeleteAttribute, nil))
+	defer func() { rc.DoAfterHook(ctx, rc.db, tx, NewOp(rc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), rc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := rc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := rc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have updated_table")
+		return
+	}
+	common.DeleteAfterAttributeHook(rc.id)
+
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, rc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = rc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, rc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = rc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), rc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	if err = dropDepend(ctx, tx, rc.id); err != nil {
+		return
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, rc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (rc *RollupAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrRollup := &RollupAttribute{
+		class: rc,
+		value: nil,
+	}
+	attr = attrRollup
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, rc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrRollup.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 构建查询
+func (rc *RollupAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	jsonPath, ok := rc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have json_value_path")
+		return
+	}
+	return buildScalarQuery(jsonPath, v)
+}
+
+// 支持的筛选操作，汇总结果的类型不固定，值可以是任意标量
+func (rc *RollupAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"eq":   common.FilterValueScalar,
+		"neq":  common.FilterValueScalar,
+		"gt":   common.FilterValueScalar,
+		"gte":  common.FilterValueScalar,
+		"lt":   common.FilterValueScalar,
+		"lte":  common.FilterValueScalar,
+		"like": common.FilterValueScalar,
+	}
+}
+
+// 构建排序
+func (rc *RollupAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	jsonPath, ok := rc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("RollupAttribute metainfo dont have json_value_path")
+		return
+	}
+	key, err = sortKey(fmt.Sprintf("data ->> '%s'", jsonPath), nil, v)
+	return
+}
+
+func (t *RollupAttribute) GetJSON() string {
+	data, err := json.Marshal(map[string]interface{}{"value": t.value})
+	if err != nil {
+		return `{"value":null}`
+	}
+	return string(data)
+}
+func (t *RollupAttribute) String() string {
+	return formulaToString(t.value)
+}
+func (t *RollupAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+
+// 汇总属性只读
+func (t *RollupAttribute) SetValue(v map[string]interface{}) (err error) {
+	err = fmt.Errorf("rollup attribute is read only")
+	return
+}
+func (t *RollupAttribute) Parse(v string) error {
+	result := gjson.Get(v, "value")
+	if !result.Exists() {
+		return fmt.Errorf("parse error: %v", v)
+	}
+	t.value = result.Value()
+	return nil
+}
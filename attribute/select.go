@@ -0,0 +1,607 @@
+package attribute
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"paroket/utils"
+
+	"github.com/rs/xid"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+type SelectAttributeClass struct {
+	AttributeClassInfo
+}
+
+type SelectAttribute struct {
+	class *SelectAttributeClass
+	value string
+}
+
+func newSelectAttributeClass(_ context.Context, db common.Database, tx tx.WriteTx) (ac common.AttributeClass, err error) {
+
+	id, err := common.NewAttributeClassId()
+	if err != nil {
+		return
+	}
+	jsonValuePath := fmt.Sprintf(`$."%v"."value"`, id)
+	updateTable := fmt.Sprintf(`select_%v`, id)
+
+	act := &SelectAttributeClass{
+		AttributeClassInfo{
+			db:       db,
+			id:       id,
+			name:     "select",
+			key:      id.String(),
+			attrType: AttributeTypeSelect,
+			metaInfo: utils.JSONMap{
+				"json_value_path":  jsonValuePath,
+				"updated_table":    updateTable,
+				"gjson_value_path": "value",
+				"gjson_idx_path":   "idx",
+				"options":          "[]",
+			},
+		},
+	}
+	ac = act
+
+	stmt := `
+  INSERT INTO attribute_classes
+  (class_id,attribute_name,attribute_key,attribute_type,attribute_meta_info)
+  VALUES
+  (?,?,?,?,?)`
+	createUpdate := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %v(
+    object_id BLOB PRIMARY KEY,
+    updated BLOB NOT NULL,
+	FOREIGN KEY (object_id) REFERENCES objects(object_id) ON DELETE CASCADE
+)`, updateTable)
+	if _, err = tx.Exac(stmt, act.id, act.name, act.key, act.attrType, act.metaInfo); err != nil {
+		return
+	}
+	if _, err = tx.Exac(createUpdate); err != nil {
+		return
+	}
+
+	return
+}
+
+func parseSelectAttributeClass(_ context.Context, _ tx.ReadTx, acProto *AttributeClassInfo) (ac common.AttributeClass, err error) {
+	ac = &SelectAttributeClass{*acProto}
+	return
+}
+
+func (sc *SelectAttributeClass) GetMetaInfo(ctx context.Context, tx tx.ReadTx) (v utils.JSONMap, err error) {
+	m := utils.JSONMap{}
+	for key := range sc.metaInfo {
+		m[key] = sc.metaInfo[key]
+	}
+	return m, nil
+}
+
+func (sc *SelectAttributeClass) Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error) {
+	oldName := sc.name
+	oldkey := sc.key
+	oldMetaInfo := utils.JSONMap{}
+	for key := range sc.metaInfo {
+		oldMetaInfo[key] = sc.metaInfo[key]
+	}
+	defer func() {
+		if err != nil {
+			sc.name = oldName
+			sc.key = oldkey
+			sc.metaInfo = oldMetaInfo
+		}
+	}()
+
+	if name, ok := v["name"]; ok {
+		switch value := name.(type) {
+		case string:
+			sc.name = value
+		default:
+			err = fmt.Errorf("set name with error type")
+			return
+		}
+		delete(v, "name")
+	}
+
+	if key, ok := v["key"]; ok {
+		switch value := key.(type) {
+		case string:
+			sc.key = value
+		default:
+			err = fmt.Errorf("set key with error type")
+			return
+		}
+		delete(v, "key")
+	}
+
+	refresh := false
+	if options, ok := v["options"]; ok {
+		var optionsStr string
+		if optionsStr, err = normalizeSelectOptions(options); err != nil {
+			return
+		}
+		sc.metaInfo["options"] = optionsStr
+		refresh = true
+		delete(v, "options")
+	}
+	for key := range v {
+		sc.metaInfo[key] = v[key]
+	}
+	stmt := `
+  UPDATE attribute_classes
+  SET (attribute_name,attribute_key,attribute_meta_info) =
+  (?,?,?)
+  WHERE class_id = ?`
+	if _, err = tx.Exac(stmt, sc.name, sc.key, sc.metaInfo, sc.id); err != nil {
+		return
+	}
+	if refresh {
+		err = sc.refreshOptions(ctx, tx)
+	}
+	return
+}
+
+func (sc *SelectAttributeClass) Insert(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (attr common.Attribute, err error) {
+
+	attrSelect := &SelectAttribute{
+		class: sc,
+		value: "",
+	}
+	attr = attrSelect
+	obj, err := sc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	sc.DoPreHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.InsertAttribute, attr))
+	defer func() { sc.DoAfterHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.InsertAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), sc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := sc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+INSERT INTO %s
+  (object_id, updated)
+VALUES
+  (?,?)`, updateTable)
+	opId := xid.New()
+
+	_, err = tx.Exac(update, oid, opId)
+	if err != nil {
+		return
+	}
+	return
+
+}
+func (sc *SelectAttributeClass) FindId(ctx context.Context, tx tx.ReadTx, oid common.ObjectId) (attr common.Attribute, err error) {
+	obj, err := sc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, sc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		err = sql.ErrNoRows
+		return
+	}
+	attrSelect := &SelectAttribute{
+		class: sc,
+		value: "",
+	}
+	if err = attrSelect.Parse(attrData.Raw); err != nil {
+		return
+	}
+	attr = attrSelect
+	return
+}
+func (sc *SelectAttributeClass) Update(ctx context.Context, tx tx.WriteTx, oid common.ObjectId, attr common.Attribute) (err error) {
+	obj, err := sc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	sc.DoPreHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.UpdateAttribute, attr))
+	defer func() { sc.DoAfterHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.UpdateAttribute, attr)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.SetRaw(string(data), sc.id.String(), attr.GetJSON())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+
+	updateTable, ok := sc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have updated_table")
+		return
+	}
+	update := fmt.Sprintf(`
+UPDATE %s SET updated = ?
+  WHERE object_id = ?
+    `, updateTable)
+	opId := xid.New()
+
+	if _, err = tx.Exac(update, opId, oid); err != nil {
+		return
+	}
+
+	return
+}
+func (sc *SelectAttributeClass) Delete(ctx context.Context, tx tx.WriteTx, oid common.ObjectId) (err error) {
+	obj, err := sc.db.OpenObject(ctx, tx, oid)
+	if err != nil {
+		return
+	}
+
+	//hook
+	sc.DoPreHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.DeleteAttribute, nil))
+	defer func() { sc.DoAfterHook(ctx, sc.db, tx, NewOp(sc.id, obj, common.DeleteAttribute, nil)) }()
+	//hook
+
+	data := obj.Data()
+	newValue, err := sjson.Delete(string(data), sc.id.String())
+	if err != nil {
+		return
+	}
+	err = obj.Update(ctx, tx, []byte(newValue))
+	if err != nil {
+		return
+	}
+	updateTable, ok := sc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have updated_table")
+		return
+	}
+	deleteRecord := fmt.Sprintf(`
+DELETE FROM %s WHERE object_id = ?
+`, updateTable)
+	if _, err = tx.Exac(deleteRecord, oid); err != nil {
+		return
+	}
+	return
+}
+
+func (sc *SelectAttributeClass) Drop(ctx context.Context, tx tx.WriteTx) (err error) {
+
+	updateTable, ok := sc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have updated_table")
+		return
+	}
+	//先删除相关表的索引
+	tidList := []common.TableId{}
+	queryTableId := `
+	SELECT table_id FROM table_to_attribute_classes WHERE class_id = ?`
+	rows, err := tx.Query(queryTableId, sc.id)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	for _, tid := range tidList {
+		var table common.Table
+		table, err = sc.db.OpenTable(ctx, tx, tid)
+		if err != nil {
+			return
+		}
+		table.DeleteAttributeClass(ctx, tx, sc)
+	}
+
+	// 从相关的object中移除attribute
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err = tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+
+	for _, oid := range oidList {
+		var obj common.Object
+		var newValue string
+		obj, err = sc.db.OpenObject(ctx, tx, oid)
+		if err != nil {
+			return
+		}
+		data := obj.Data()
+		newValue, err = sjson.Delete(string(data), sc.id.String())
+		if err != nil {
+			return
+		}
+		err = obj.Update(ctx, tx, []byte(newValue))
+		if err != nil {
+			return
+		}
+	}
+
+	dropTable := fmt.Sprintf("DROP TABLE %s", updateTable)
+	if _, err = tx.Exac(dropTable); err != nil {
+		return
+	}
+
+	deleteAttributeClassStmt := `DELETE FROM attribute_classes WHERE class_id = ?`
+	if _, err = tx.Exac(deleteAttributeClassStmt, sc.id); err != nil {
+		return
+	}
+	return
+}
+
+func (sc *SelectAttributeClass) FromObject(obj common.Object) (attr common.Attribute, err error) {
+	attrSelect := &SelectAttribute{
+		class: sc,
+		value: "",
+	}
+	attr = attrSelect
+
+	data := obj.Data()
+	attrPath := fmt.Sprintf(`%v`, sc.id)
+	attrData := gjson.Get(string(data), attrPath)
+	if attrData.Type == gjson.Null {
+		return
+	}
+
+	if err = attrSelect.Parse(attrData.Raw); err != nil {
+		return
+	}
+
+	return
+}
+
+// 选项变化后移除已删除的选项，并更新索引中的选项名称
+func (sc *SelectAttributeClass) refreshOptions(ctx context.Context, tx tx.WriteTx) (err error) {
+	updateTable, ok := sc.metaInfo["updated_table"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have updated_table")
+		return
+	}
+	options, err := parseSelectOptions(sc.metaInfo)
+	if err != nil {
+		return
+	}
+	oidList := []common.ObjectId{}
+	queryObjectId := fmt.Sprintf(`
+	SELECT object_id FROM %s`, updateTable)
+	rows, err := tx.Query(queryObjectId)
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var oid common.ObjectId
+		if err = rows.Scan(&oid); err != nil {
+			return
+		}
+		oidList = append(oidList, oid)
+	}
+	for _, oid := range oidList {
+		var attr common.Attribute
+		if attr, err = sc.FindId(ctx, tx, oid); err != nil {
+			return
+		}
+		attrSelect := attr.(*SelectAttribute)
+		if _, ok := findSelectOption(options, attrSelect.value); !ok {
+			attrSelect.value = ""
+		}
+		if err = sc.Update(ctx, tx, oid, attrSelect); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 构建查询，查询值可以是选项id或选项名称
+func (sc *SelectAttributeClass) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	jsonPath, ok := sc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	options, err := parseSelectOptions(sc.metaInfo)
+	if err != nil {
+		return
+	}
+	if op == "is_empty" {
+		empty, ok := v["value"].(bool)
+		if !ok {
+			empty = true
+		}
+		if empty {
+			stmt = fmt.Sprintf(`(data ->> '%s' IS NULL)`, jsonPath)
+		} else {
+			stmt = fmt.Sprintf(`(data ->> '%s' IS NOT NULL)`, jsonPath)
+		}
+		return
+	}
+
+	idList, err := resolveSelectOptionList(options, v["value"])
+	if err != nil {
+		return
+	}
+	if (op == "is" || op == "is_not") && len(idList) != 1 {
+		err = fmt.Errorf("invaild select option list:%v", v["value"])
+		return
+	}
+	switch op {
+	case "is":
+		stmt = fmt.Sprintf(`(data ->> '%s' = ?)`, jsonPath)
+		args = selectIdListArgs(idList)
+	case "is_not":
+		stmt = fmt.Sprintf(
+			`(data ->> '%s' IS NULL OR data ->> '%s' != ?)`,
+			jsonPath,
+			jsonPath,
+		)
+		args = selectIdListArgs(idList)
+	case "contains_any":
+		stmt = fmt.Sprintf(`(data ->> '%s' IN (%s))`, jsonPath, placeholders(len(idList)))
+		args = selectIdListArgs(idList)
+	case "contains_all":
+		// 单选最多只有一个选项，空列表总是满足
+		if len(idList) == 0 {
+			stmt = "(1)"
+			return
+		}
+		if len(idList) > 1 {
+			stmt = "(0)"
+			return
+		}
+		stmt = fmt.Sprintf(`(data ->> '%s' = ?)`, jsonPath)
+		args = selectIdListArgs(idList)
+	default:
+		err = fmt.Errorf("unsupport op:%s", op)
+	}
+	return
+}
+
+// 支持的筛选操作，选项可以使用选项id或名称
+func (sc *SelectAttributeClass) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"is":           common.FilterValueString,
+		"is_not":       common.FilterValueString,
+		"contains_any": common.FilterValueStrings,
+		"contains_all": common.FilterValueStrings,
+		"is_empty":     common.FilterValueOptionalBool,
+	}
+}
+
+// 构建排序，按选项顺序而不是选项名称排序
+func (sc *SelectAttributeClass) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	jsonPath, ok := sc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	options, err := parseSelectOptions(sc.metaInfo)
+	if err != nil {
+		return
+	}
+	orderExpr, args := selectOrderExpr(fmt.Sprintf("data ->> '%s'", jsonPath), options)
+	key, err = sortKey(orderExpr, args, v)
+	return
+}
+
+// 按选项分组，分组键为选项id
+func (sc *SelectAttributeClass) BuildGroup(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	jsonPath, ok := sc.metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("SelectAttribute metainfo dont have json_value_path")
+		return
+	}
+	stmt = fmt.Sprintf(`json_array(data ->> '%s')`, jsonPath)
+	return
+}
+
+func (t *SelectAttribute) option() (option SelectOption, ok bool) {
+	if t.value == "" {
+		return
+	}
+	options, err := parseSelectOptions(t.class.metaInfo)
+	if err != nil {
+		return
+	}
+	return findSelectOption(options, t.value)
+}
+
+func (t *SelectAttribute) GetJSON() string {
+	value := map[string]interface{}{"value": nil, "idx": ""}
+	if option, ok := t.option(); ok {
+		value["value"] = option.Id
+		value["idx"] = option.Name
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return `{"value":null,"idx":""}`
+	}
+	return string(data)
+}
+func (t *SelectAttribute) String() string {
+	option, _ := t.option()
+	return option.Name
+}
+func (t *SelectAttribute) GetClass() common.AttributeClass {
+	return t.class
+}
+
+// value为选项id或选项名称，nil或空字符串表示清空
+func (t *SelectAttribute) SetValue(v map[string]interface{}) (err error) {
+	value, ok := v["value"]
+	if !ok {
+		err = fmt.Errorf("invaild set value:%v", v)
+		return
+	}
+	if value == nil || value == "" {
+		t.value = ""
+		return
+	}
+	ref, ok := value.(string)
+	if !ok {
+		err = fmt.Errorf("invaild set value:%v", v)
+		return
+	}
+	options, err := parseSelectOptions(t.class.metaInfo)
+	if err != nil {
+		return
+	}
+	option, ok := findSelectOption(options, ref)
+	if !ok {
+		err = fmt.Errorf("invaild select option:%s", ref)
+		return
+	}
+	t.value = option.Id
+	return
+}
+func (t *SelectAttribute) Parse(v string) error {
+	result := gjson.Get(v, "value")
+	switch result.Type {
+	case gjson.Null:
+		t.value = ""
+	case gjson.String:
+		t.value = result.Str
+	default:
+		return fmt.Errorf("parse error: %v", v)
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package attribute
+
+import (
+	"encoding/json"
+	"fmt"
+	"paroket/utils"
+	"slices"
+	"strings"
+
+	"github.com/rs/xid"
+)
+
+// 单选、多选的选项，顺序即排序顺序
+type SelectOption struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+var SelectColorList = []string{
+	"default", "gray", "brown", "orange", "yellow",
+	"green", "blue", "purple", "pink", "red",
+}
+
+// 读取metainfo中的选项列表
+func parseSelectOptions(metaInfo utils.JSONMap) (options []SelectOption, err error) {
+	optionsStr, ok := metaInfo["options"].(string)
+	if !ok {
+		err = fmt.Errorf("select metainfo dont have options")
+		return
+	}
+	options = []SelectOption{}
+	if err = json.Unmarshal([]byte(optionsStr), &options); err != nil {
+		err = fmt.Errorf("select options:error json:%v", optionsStr)
+	}
+	return
+}
+
+// 校验新的选项列表，为没有id的选项生成id
+// 接受json字符串或[]interface{}
+func normalizeSelectOptions(v interface{}) (optionsStr string, err error) {
+	var raw []byte
+	switch value := v.(type) {
+	case string:
+		raw = []byte(value)
+	case []interface{}, []SelectOption:
+		if raw, err = json.Marshal(value); err != nil {
+			return
+		}
+	default:
+		err = fmt.Errorf("set options with error type")
+		return
+	}
+	options := []SelectOption{}
+	if err = json.Unmarshal(raw, &options); err != nil {
+		err = fmt.Errorf("set options:error json:%s", raw)
+		return
+	}
+	idSet := map[string]bool{}
+	nameSet := map[string]bool{}
+	for i := range options {
+		if options[i].Name == "" {
+			err = fmt.Errorf("select option name is empty")
+			return
+		}
+		if nameSet[options[i].Name] {
+			err = fmt.Errorf("duplicate select option name:%s", options[i].Name)
+			return
+		}
+		nameSet[options[i].Name] = true
+		if options[i].Id == "" {
+			options[i].Id = xid.New().String()
+		}
+		if idSet[options[i].Id] {
+			err = fmt.Errorf("duplicate select option id:%s", options[i].Id)
+			return
+		}
+		idSet[options[i].Id] = true
+		if options[i].Color == "" {
+			options[i].Color = SelectColorList[0]
+		}
+		if !slices.Contains(SelectColorList, options[i].Color) {
+			err = fmt.Errorf("unsupport select option color:%s", options[i].Color)
+			return
+		}
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return
+	}
+	optionsStr = string(data)
+	return
+}
+
+// 按id或名称查找选项
+func findSelectOption(options []SelectOption, ref string) (option SelectOption, ok bool) {
+	for _, option = range options {
+		if option.Id == ref {
+			return option, true
+		}
+	}
+	for _, option = range options {
+		if option.Name == ref {
+			return option, true
+		}
+	}
+	return SelectOption{}, false
+}
+
+// 将id或名称的列表转换为选项id列表
+func resolveSelectOptionList(options []SelectOption, v interface{}) (idList []string, err error) {
+	refList := []string{}
+	switch value := v.(type) {
+	case string:
+		refList = append(refList, value)
+	case []string:
+		refList = value
+	case []interface{}:
+		for _, item := range value {
+			ref, ok := item.(string)
+			if !ok {
+				err = fmt.Errorf("invaild select option:%v", item)
+				return
+			}
+			refList = append(refList, ref)
+		}
+	default:
+		err = fmt.Errorf("invaild select option list:%v", v)
+		return
+	}
+	idList = []string{}
+	for _, ref := range refList {
+		option, ok := findSelectOption(options, ref)
+		if !ok {
+			err = fmt.Errorf("invaild select option:%s", ref)
+			return
+		}
+		if !slices.Contains(idList, option.Id) {
+			idList = append(idList, option.Id)
+		}
+	}
+	return
+}
+
+// 选项id列表转换为sql参数
+func selectIdListArgs(idList []string) (args []interface{}) {
+	args = []interface{}{}
+	for _, id := range idList {
+		args = append(args, id)
+	}
+	return
+}
+
+// 按选项顺序排序的表达式，没有选项时为NULL，空值的位置由排序的nulls决定
+func selectOrderExpr(expr string, options []SelectOption) (stmt string, args []interface{}) {
+	args = []interface{}{}
+	if len(options) == 0 {
+		return "NULL", args
+	}
+	var buffer strings.Builder
+	buffer.WriteString(fmt.Sprintf("CASE %s", expr))
+	for idx, option := range options {
+		buffer.WriteString(fmt.Sprintf(" WHEN ? THEN %d", idx))
+		args = append(args, option.Id)
+	}
+	buffer.WriteString(" ELSE NULL END")
+	stmt = buffer.String()
+	return
+}
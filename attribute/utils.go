@@ -1,32 +1,120 @@
-package attribute
-
-import (
-	"database/sql"
-	"fmt"
-	"paroket/common"
-)
-
-// AttributeClass的具体实现接口
-type attributeClassImpl interface {
-	NewAttribute() (attr common.Attribute, err error)                       //创建Attrbute
-	SearchByID(tx *sql.Tx, objId common.ObjectId) (common.Attribute, error) //根据ObjectId搜索属性
-	CreateDataTable(tx *sql.Tx) error                                       // 创建AttributeClass相关的表
-	GetDataTableName() string                                               //获取数据表名称
-	GetDataIndexName() string                                               //获取索引表名称
-	BuildQuery(v map[string]interface{}) (string, error)                    //构建查询
-	BuildSort(v map[string]interface{}) (string, error)                     //构建排序
-}
-
-func genTableNameByTypeAndID(attrType string, cid common.AttributeClassId) string {
-	return fmt.Sprintf(
-		`%s_%s`,
-		attrType,
-		cid.String())
-}
-
-func genIndexNameByTypeAndID(attrType string, cid common.AttributeClassId) string {
-	return fmt.Sprintf(
-		`%s_%s_idx`,
-		attrType,
-		cid.String())
-}
+package attribute
+
+import (
+	"database/sql"
+	"fmt"
+	"paroket/common"
+	"strings"
+)
+
+// AttributeClass的具体实现接口
+type attributeClassImpl interface {
+	NewAttribute() (attr common.Attribute, err error)                       //创建Attrbute
+	SearchByID(tx *sql.Tx, objId common.ObjectId) (common.Attribute, error) //根据ObjectId搜索属性
+	CreateDataTable(tx *sql.Tx) error                                       // 创建AttributeClass相关的表
+	GetDataTableName() string                                               //获取数据表名称
+	GetDataIndexName() string                                               //获取索引表名称
+	BuildQuery(v map[string]interface{}) (string, error)                    //构建查询
+	BuildSort(v map[string]interface{}) (string, error)                     //构建排序
+}
+
+func genTableNameByTypeAndID(attrType string, cid common.AttributeClassId) string {
+	return fmt.Sprintf(
+		`%s_%s`,
+		attrType,
+		cid.String())
+}
+
+func genIndexNameByTypeAndID(attrType string, cid common.AttributeClassId) string {
+	return fmt.Sprintf(
+		`%s_%s_idx`,
+		attrType,
+		cid.String())
+}
+
+// 检查查询值的类型，查询值只通过参数绑定传入sql
+func queryArg(v interface{}) (arg interface{}, err error) {
+	switch value := v.(type) {
+	case string, float64, bool:
+		arg = value
+	case int:
+		arg = float64(value)
+	default:
+		err = fmt.Errorf("unsupport query value type:%T", v)
+	}
+	return
+}
+
+// 转义LIKE中的通配符，配合ESCAPE '\'使用
+func escapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// 生成n个参数占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// 公式、汇总等计算属性的值类型不固定，使用通用的比较查询
+func buildScalarQuery(jsonPath string, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	value, err := queryArg(v["value"])
+	if err != nil {
+		return
+	}
+	args = []interface{}{value}
+	switch op {
+	case "eq":
+		stmt = fmt.Sprintf(`(data ->> '%s' = ?)`, jsonPath)
+	case "neq":
+		stmt = fmt.Sprintf(`(data ->> '%s' != ?)`, jsonPath)
+	case "gt":
+		stmt = fmt.Sprintf(`(data ->> '%s' > ?)`, jsonPath)
+	case "gte":
+		stmt = fmt.Sprintf(`(data ->> '%s' >= ?)`, jsonPath)
+	case "lt":
+		stmt = fmt.Sprintf(`(data ->> '%s' < ?)`, jsonPath)
+	case "lte":
+		stmt = fmt.Sprintf(`(data ->> '%s' <= ?)`, jsonPath)
+	case "like":
+		stmt = fmt.Sprintf(`(data ->> '%s' LIKE '%%' || ? || '%%')`, jsonPath)
+	default:
+		err = fmt.Errorf("unsupport op:%s", op)
+	}
+	return
+}
+
+// 生成排序项，mode为asc或desc，nulls为first或last时指定空值的位置，
+// 未指定nulls时与sqlite一致：升序空值在前，降序空值在后
+func sortKey(expr string, args []interface{}, v map[string]interface{}) (key common.SortKey, err error) {
+	key = common.SortKey{Expr: expr, Args: args}
+	mode, _ := v["mode"].(string)
+	switch mode {
+	case "asc":
+	case "desc":
+		key.Desc = true
+	default:
+		err = fmt.Errorf("invaild sort value:%s", v)
+		return
+	}
+	key.NullsFirst = !key.Desc
+	nulls, ok := v["nulls"]
+	if !ok {
+		return
+	}
+	switch nulls {
+	case "first":
+		key.NullsFirst = true
+	case "last":
+		key.NullsFirst = false
+	default:
+		err = fmt.Errorf("invaild sort nulls:%v", nulls)
+	}
+	return
+}
@@ -0,0 +1,171 @@
+package collate
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 比较函数，a<b返回负数，a==b返回0，a>b返回正数
+type Compare func(a string, b string) int
+
+const (
+	CollationBinary  = "binary"  // sqlite默认的按字节比较
+	CollationNocase  = "nocase"  // 忽略大小写，支持非ASCII字母
+	CollationNatural = "natural" // 数字按数值比较，"Card 2"排在"Card 10"之前
+	CollationPinyin  = "pinyin"  // 汉字按拼音比较
+)
+
+// 注册到sqlite中的排序规则名称，避免与sqlite内置的NOCASE冲突
+var collationSqlName = map[string]string{
+	CollationNocase:  "paroket_nocase",
+	CollationNatural: "paroket_natural",
+	CollationPinyin:  "paroket_pinyin",
+}
+
+var CollationMap = map[string]Compare{
+	CollationNocase:  compareNocase,
+	CollationNatural: compareNatural,
+	CollationPinyin:  comparePinyin,
+}
+
+// 排序规则在sql中的名称，binary或空字符串表示不使用自定义的排序规则
+func SqlName(name string) (sqlName string, err error) {
+	if name == "" || name == CollationBinary {
+		return
+	}
+	sqlName, ok := collationSqlName[name]
+	if !ok {
+		err = fmt.Errorf("collate: unsupport collation %s", name)
+	}
+	return
+}
+
+// 需要注册到sqlite连接中的排序规则
+func SqlCollations() map[string]Compare {
+	collations := map[string]Compare{}
+	for name, sqlName := range collationSqlName {
+		collations[sqlName] = CollationMap[name]
+	}
+	return collations
+}
+
+// 只有大小写不同的字符串相等，也用于忽略大小写的相等查询
+func compareNocase(a string, b string) int {
+	return compareFold([]rune(a), []rune(b))
+}
+
+func compareFold(a []rune, b []rune) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ra, rb := unicode.ToLower(a[i]), unicode.ToLower(b[i])
+		if ra != rb {
+			if ra < rb {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+// 连续的数字按数值比较，其余部分忽略大小写比较
+func compareNatural(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	i, j := 0, 0
+	for i < len(ra) && j < len(rb) {
+		if isDigit(ra[i]) && isDigit(rb[j]) {
+			si, sj := i, j
+			for i < len(ra) && isDigit(ra[i]) {
+				i++
+			}
+			for j < len(rb) && isDigit(rb[j]) {
+				j++
+			}
+			if cmp := compareNumber(ra[si:i], rb[sj:j]); cmp != 0 {
+				return cmp
+			}
+			continue
+		}
+		ci, cj := unicode.ToLower(ra[i]), unicode.ToLower(rb[j])
+		if ci != cj {
+			if ci < cj {
+				return -1
+			}
+			return 1
+		}
+		i++
+		j++
+	}
+	if cmp := (len(ra) - i) - (len(rb) - j); cmp != 0 {
+		return cmp
+	}
+	return strings.Compare(a, b)
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// 去掉前导0后先比较位数再逐位比较，不受数值大小的限制
+func compareNumber(a []rune, b []rune) int {
+	for len(a) > 1 && a[0] == '0' {
+		a = a[1:]
+	}
+	for len(b) > 1 && b[0] == '0' {
+		b = b[1:]
+	}
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+	return strings.Compare(string(a), string(b))
+}
+
+var (
+	pinyinOnce  sync.Once
+	pinyinIndex map[rune]int
+)
+
+// 汉字在拼音顺序中的位置，不在表中的汉字排在表中的汉字之后
+func pinyinWeight(r rune) (weight int, han bool) {
+	pinyinOnce.Do(func() {
+		pinyinIndex = map[rune]int{}
+		idx := 0
+		for _, c := range pinyinOrder {
+			pinyinIndex[c] = idx
+			idx++
+		}
+	})
+	if idx, ok := pinyinIndex[r]; ok {
+		return idx, true
+	}
+	if unicode.Is(unicode.Han, r) {
+		return len(pinyinIndex) + int(r), true
+	}
+	return int(unicode.ToLower(r)), false
+}
+
+// 非汉字排在汉字之前，汉字之间按拼音比较
+func comparePinyin(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	for i := 0; i < len(ra) && i < len(rb); i++ {
+		wa, hanA := pinyinWeight(ra[i])
+		wb, hanB := pinyinWeight(rb[i])
+		if hanA != hanB {
+			if hanB {
+				return -1
+			}
+			return 1
+		}
+		if wa != wb {
+			if wa < wb {
+				return -1
+			}
+			return 1
+		}
+	}
+	if len(ra) != len(rb) {
+		return len(ra) - len(rb)
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,175 @@
+package collate
+
+// GB2312中的汉字按拼音排列：一级汉字使用GB2312的顺序（按常用读音的拼音排列），
+// 二级汉字按Unicode::Collate::CJK::Pinyin中的位置插入到一级汉字之间
+const pinyinOrder = "" +
+	"啊阿埃挨嗳锿捱哎唉哀皑癌蔼霭矮艾碍暧瑷爱砹隘嗌嫒鞍氨庵谙鹌安桉俺埯铵揞犴按暗黯岸" +
+	"胺案肮昂盎凹敖嗷廒遨熬獒翱聱螯鳌鏖拗袄媪岙坳傲奥骜懊澳鏊芭捌粑扒岜叭吧笆八疤巴拔" +
+	"茇菝跋魃靶把钯耙鲅坝霸灞罢掰擘爸白柏捭百摆佰败拜稗斑班搬瘢癍阪坂扳般颁板版钣舨扮" +
+	"拌伴瓣半办绊邦帮梆浜榜膀绑棒磅蚌镑勹傍谤蒡苞胞煲龅包孢褒剥薄雹保鸨堡葆褓饱宝抱报" +
+	"暴豹趵鲍爆陂杯碑鹎悲卑北辈碚蓓褙鞴鐾呗背贝孛钡倍悖狈邶备惫焙被奔贲锛苯畚坌本笨崩" +
+	"嘣绷甭泵蹦迸甏逼荸鼻匕比吡妣鄙笔舭彼秕俾碧箅蓖裨跸蔽毕毙狴铋婢庳毖荜币庇畀哔痹闭" +
+	"敝萆弼愎筚滗弊必辟壁嬖篦薜臂髀璧襞避濞陛鞭边砭笾编煸蝙鳊贬扁窆匾碥褊便缏变卞弁忭" +
+	"汴苄辨辩辫灬杓遍标飑髟彪骠膘瘭镖飙飚镳表婊裱鳔鳖憋别蹩瘪玢彬傧斌濒豳滨缤槟镔宾摈" +
+	"殡膑髌鬓冫兵冰柄丙邴秉饼摒禀炳病并玻菠播拨钵饽波博勃亳钹搏铂箔踣礴跛簸檗伯帛舶脖" +
+	"膊逋晡醭卟渤鹁泊驳捕卜啵哺补埠瓿不布步簿嚓部钸怖钚擦礤猜裁材才财睬踩采彩菜蔡餐参" +
+	"骖蚕残惭惨黪灿苍舱仓伧沧藏操糙槽艚螬曹嘈漕草艹厕恻策岑涔噌侧册测层蹭插馇锸叉杈茬" +
+	"茶查碴檫衩镲汊搽猹槎察岔差诧姹拆钗侪柴豺虿瘥觇搀婵掺孱粲璨蝉廛潺澶镡蟾躔馋谗禅缠" +
+	"铲产谄阐蒇骣冁忏颤羼伥昌娼猖菖阊鲳场昶惝氅怅尝常徜嫦长偿肠苌厂敞畅唱倡鬯超晁抄怊" +
+	"钞焯朝嘲潮巢吵炒耖车砗扯屮撤掣彻坼澈抻郴琛嗔臣辰尘晨柽忱沉陈宸谌碜趁榇谶衬龀撑瞠" +
+	"丞称蛏城橙成呈乘埕晟铖程裎塍酲惩澄诚承枨逞骋秤吃哧蚩鸱眵笞嗤媸痴螭魑持墀踟篪匙池" +
+	"迟坻茌弛驰耻褫彳叱齿侈尺赤饬翅敕啻傺瘛斥炽充冲忡茺舂憧艟虫崇宠铳抽瘳酬畴踌雠稠愁" +
+	"筹仇俦帱惆绸瞅丑臭初樗刍出橱厨躇蹰杵锄蜍雏滁除楚础储楮褚亍矗搐触憷黜处怵绌揣搋啜" +
+	"嘬膪踹巛川氚穿椽舛传舡船遄喘串钏疮窗幢床闯创怆吹炊捶棰锤垂陲春椿蝽醇唇莼淳纯蠢鹑" +
+	"踔戳辶绰辍龊呲疵茨磁雌鹚糍辞慈瓷词祠茈此刺赐次聪葱骢璁囱苁枞匆从丛淙琮凑腠辏粗徂" +
+	"殂醋簇蹙蹴汆撺镩促猝酢蔟蹿篡爨窜摧榱璀崔催脆啐悴瘁粹淬萃毳翠村皴存忖寸磋撮蹉嵯痤" +
+	"矬鹾脞厝搓措锉挫错哒耷嗒搭褡达妲怛沓笪答靼鞑瘩打大呆呔歹傣戴黛带殆玳代岱甙绐迨骀" +
+	"贷埭袋待逮怠耽担眈丹单郸聃殚瘅箪儋掸赕胆疸旦氮澹但惮淡萏诞啖弹蛋当裆挡党谠凼宕砀" +
+	"荡档菪铛刀刂叨忉氘捣蹈倒岛祷导到稻纛锝悼焘道盗德得的蹬灯登噔簦等戥瞪磴镫氐凳嶝邓" +
+	"堤低羝滴镝迪敌笛觌嘀狄籴涤荻翟诋邸嫡抵柢砥骶底地蒂碲嗲第谛棣睇帝娣弟递缔颠巅癫掂" +
+	"滇碘踮点典靛癜簟垫玷钿电佃阽坫甸店惦奠淀殿碉叼雕鲷凋貂刁掉铞铫吊钓调跌爹碟蝶蹀鲽" +
+	"迭垤瓞谍喋堞揲耋叠牒丁仃盯叮玎疔钉耵酊顶鼎锭定啶铤腚碇订丢铥东冬咚岽氡鸫董懂动栋" +
+	"侗垌峒恫冻洞胨胴硐兜蔸篼抖斗陡蚪豆逗痘窦嘟都督毒犊碡黩髑笃独读渎椟牍堵睹芏赌杜镀" +
+	"蠹肚度渡妒端短锻簖段断缎椴煅堆兑怼碓憝镦队对墩礅吨蹲盹趸敦顿囤沌炖钝盾砘遁掇裰哆" +
+	"多咄夺铎踱哚垛缍躲朵婀屙钶跺舵剁柁惰堕蛾峨莪锇鹅俄额讹娥恶厄呃扼苊轭垩遏腭锷鹗颚" +
+	"噩鳄诶鄂阏愕萼饿恩蒽摁而鸸鲕儿耳迩尔饵珥铒洱二佴贰发罚筏伐垡乏阀砝法珐藩帆番幡蕃" +
+	"翻樊燔矾钒繁蹯蘩凡烦反返范贩畈梵匚犯饭泛坊芳枋钫方邡肪鲂房防妨仿访彷纺舫放菲扉蜚" +
+	"霏鲱非啡绯飞妃肥淝腓匪诽悱斐榧翡篚吠芾肺废沸狒费痱镄芬酚吩氛分纷坟焚鼢汾棼粉奋份" +
+	"忿偾愤粪鲼瀵丰封枫蜂酆峰锋风沣疯砜烽葑逢冯缝缶讽唪奉俸凤佛孚否呋夫敷肤趺麸稃跗孵" +
+	"扶芙怫拂辐幞蝠黻呒幅氟祓罘茯郛符艴菔伏凫俘服绂绋苻浮砩莩蚨匐桴涪福蜉袱弗甫抚辅俯" +
+	"釜斧脯腑滏府拊腐黼阝赴副覆馥赋复傅旮呷付阜驸父腹鲋赙蝮鳆负富讣附妇缚咐噶尕尬嘎钆" +
+	"尜该陔垓赅改丐概钙盖溉戤干旰绀淦甘杆柑竿疳酐肝坩泔矸苷赶感澉橄擀秆敢赣冈刚钢罡缸" +
+	"肛纲岗港筻戆杠篙皋高槔睾膏羔糕杲搞缟槁镐藁稿告诰郜锆哥歌搁戈仡圪纥鸽胳袼疙割革葛" +
+	"格鬲蛤阁隔嗝塥搿膈镉骼哿舸铬个各虼硌给根跟哏艮亘茛耕赓更庚羹哽埂绠耿梗鲠工攻功恭" +
+	"龚觥廾供肱躬公宫弓巩汞拱珙贡蚣共钩缑篝鞲岣勾佝沟苟枸笱狗垢构诟购够媾彀遘觏辜酤菇" +
+	"咕箍估呱沽轱鸪菰蛄觚孤姑鼓嘏鹘臌瞽古汩诂蛊鹄毂骨罟钴谷股牯故顾崮梏牿固雇痼锢鲴刮" +
+	"胍栝鸹聒瓜剐寡卦诖挂褂乖掴拐怪棺鳏莞关官冠倌观管馆罐惯掼涫盥灌鹳贯光咣桄胱广犷逛" +
+	"瑰鲑宄规皈圭妫硅归龟闺轨庋匦鬼晷簋诡癸桂桧柜跪鳜丨衮绲贵刽刿辊滚磙鲧棍呙埚锅蝈郭" +
+	"崞国帼虢馘果猓椁蜾裹过哈铪骸孩海胲醢氦顸蚶亥害骇酣憨鼾邗邯韩含涵焓寒函晗喊阚罕翰" +
+	"瀚撼捍旱憾悍焊菡颔撖汗汉夯杭绗珩航颃沆蒿嚆薅蚝壕濠嚎豪毫嗥郝好耗皓颢灏诃号昊浩呵" +
+	"锕嗄喝嗬荷菏蚵颌阖翮核盍禾和何劾合盒貉阂河曷涸赫褐鹤壑贺嘿黑痕很狠恨哼亨横衡蘅恒" +
+	"桁轰哄訇烘薨虹鸿蕻黉讧洪荭宏闳泓弘红喉侯猴瘊篌糇骺吼厚後逅堠鲎候虍后呼乎忽烀轷唿" +
+	"惚滹囫瑚鹕槲壶斛葫煳胡蝴醐觳狐糊湖猢弧虎浒琥唬护互沪岵怙戽祜笏扈瓠鹱户冱花哗华骅" +
+	"铧猾滑画划化话桦槐踝徊怀淮坏獾欢肓环郇洹桓萑锾圜寰缳鬟还缓换浣患唤痪豢漶鲩擐焕逭" +
+	"涣宦幻奂荒慌黄徨磺蝗癀簧蟥鳇皇凰隍惶湟遑煌潢璜篁晃幌恍谎灰诙咴挥虺晖珲辉麾徽隳恢" +
+	"蛔回洄茴毁悔慧蕙蟪卉惠缋晦贿彗秽喙会烩汇讳哕浍诲恚绘荟荤昏婚阍魂诨浑馄混溷耠锪劐" +
+	"豁攉活伙钬夥火获或惑霍镬嚯藿蠖丌货砉祸击叽圾基机玑畸跻稽齑墼积笄箕畿肌芨矶饥乩迹" +
+	"剞唧激羁讥鸡咭姬屐绩嵇犄缉赍吉岌极亟佶诘棘殛辑籍集及急笈疾汲即嫉楫蒺瘠蕺级挤掎戟" +
+	"嵴麂彐几脊戢己虮蓟暨跽霁鲚稷鲫技芰冀髻骥季哜伎祭剂悸济荠寄寂计记既洎忌际妓继觊偈" +
+	"纪嘉镓郏枷浃珈夹伽佳家痂笳袈葭跏加荚恝戛袷铗蛱颊贾甲岬胛钾假瘕稼戋价架驾嫁歼监坚" +
+	"尖笺菅湔犍间煎缣蒹鲣鹣鞯囝兼肩艰奸缄搛茧检趼睑柬碱翦謇蹇硷裥锏拣枧捡笕简谫戬俭剪" +
+	"减荐槛鉴践贱见键僭箭踺件健舰剑牮饯渐谏楗毽溅腱涧建僵缰礓姜将茳浆豇江疆蒋耩桨奖讲" +
+	"匠艽酱犟糨降洚绛蕉椒礁鹪焦蛟跤僬鲛胶交郊姣浇茭骄娇嚼矍爝搅湫铰矫侥挢脚狡角佼饺皎" +
+	"缴绞剿敫徼教酵噍醮轿较叫峤窖揭接皆秸喈嗟街卩孑阶疖截碣劫节讦桔杰拮捷颉睫竭鲒羯洁" +
+	"结桀婕解姐戒藉芥界借蚧骱介疥诫届巾筋斤钅金今津矜衿襟紧堇锦廑馑槿瑾仅谨进荩靳觐噤" +
+	"晋禁缙近烬赆浸尽卺劲妗荆兢茎睛冂扃炅迥晶腈鲸京泾惊旌菁精粳经井阱刭肼警景儆憬颈静" +
+	"境獍敬靓镜径迳胫痉靖竟竞婧净弪炯窘揪鬏究鸠赳阄啾纠玖韭久灸九酒厩救旧臼舅僦鹫咎就" +
+	"疚柩桕鞠鞫拘狙苴疽掬菹椐琚趄锔裾雎居驹菊橘局咀矩举莒榉榘龃踽沮聚屦拒苣据巨具距犋" +
+	"飓踞遽醵锯窭俱倨句讵惧炬钜剧捐涓鹃镌蠲娟倦桊狷眷鄄噘卷锩绢隽撅孓攫抉珏掘桷觖厥劂" +
+	"谲獗蕨噱橛倔崛爵镢蹶觉决诀绝均菌麇钧皲军君峻捃俊竣咔浚郡骏喀咖卡佧胩咯开揩锎楷锴" +
+	"忾凯剀垲恺铠慨蒈刊堪戡勘龛坎侃砍莰看瞰闶康慷糠扛抗亢伉炕钪尻考拷栲烤铐犒靠坷岢苛" +
+	"柯珂棵颏嗑稞窠磕蝌髁颗瞌科轲疴壳咳嗨可渴克刻客恪课氪骒缂溘锞肯啃龈裉垦恳坑铿吭空" +
+	"倥崆箜恐孔控抠芤眍口叩扣寇筘蔻刳枯哭堀窟骷苦酷库绔喾裤夸侉垮挎跨蒯胯块筷侩郐哙狯" +
+	"脍快宽髋款匡诓哐筐狂诳夼邝圹纩框矿贶眶旷况亏盔岿悝窥葵暌奎逵隗馗喹揆魁睽蝰夔跬匮" +
+	"喟愦傀馈篑聩愧溃蒉坤昆琨锟髡醌鲲悃捆阃困括蛞扩廓阔垃拉邋旯剌砬喇蜡腊瘌辣啦莱铼赉" +
+	"睐来崃徕涞赖濑癞籁蓝婪栏拦篮镧阑兰岚澜褴斓谰揽览懒缆榄漤罱烂滥啷琅榔稂锒螂狼阆廊" +
+	"郎朗浪莨蒗捞劳牢唠崂痨铹醪老佬姥栳铑酪烙耢涝勒乐叻泐鳓雷嫘缧镭羸耒诔蕾磊累酹嘞塄" +
+	"儡垒擂檑肋仂类泪棱楞冷愣厘梨犁喱鹂黎篱罹藜黧蠡狸离骊漓缡蓠蜊嫠理锂李里俚娌逦鲤澧" +
+	"醴鳢礼莉唳笠荔轹郦吏栗猁砺丽厉励呖坜砾莅历利傈例戾枥疠俐俪栎疬痢詈跞雳溧篥立粒粝" +
+	"蛎沥苈隶力璃鲡哩俩奁联裢莲连镰蠊廉鲢濂臁怜涟帘敛琏脸裣蔹链楝潋恋殓炼练粮凉梁椋粱" +
+	"墚踉良两魉辆量晾亮谅撩聊僚疗燎鹩钌蓼尥寥嘹寮獠缭辽潦了撂镣廖料列裂趔躐鬣咧烈捩劣" +
+	"冽洌埒猎琳粼嶙遴辚林磷霖瞵临啉邻鳞麟淋凛廪懔檩赁蔺膦躏吝拎玲瓴菱蛉零龄鲮酃铃伶羚" +
+	"翎聆凌灵囹泠苓柃陵棂绫岭领另呤令溜熘琉榴硫旒遛馏骝留刘浏瘤镏鎏流柳绺锍六鹨龙聋咙" +
+	"泷茏栊珑胧砻笼窿隆癃垄垅拢陇楼耧蝼髅嵝娄偻蒌搂篓漏瘘镂喽噜撸陋芦垆泸卢颅鲈庐炉栌" +
+	"胪轳鸬舻掳卤虏鲁橹镥麓碌露氇路漉赂辂渌逯鹿潞璐簏鹭禄录陆戮辘驴闾榈吕铝侣捋旅稆履" +
+	"屡缕膂褛虑氯律率滤娈绿峦挛栾鸾脔孪滦銮卵乱锊掠略谔抡轮伦囵仑沦纶论萝螺倮罗猡脶逻" +
+	"椤锣箩骡镙裸瘰蠃泺落摞漯雒洛骆珞络荦妈嬷麻玛码蚂犸杩马骂唛嘛蟆吗埋霾买荬劢麦卖迈" +
+	"脉颟瞒鞔鳗馒蛮满螨蔓熳镘邙曼墁幔慢漫缦谩芒茫硭盲氓忙莽漭蟒猫茅茆旄锚髦蝥蟊毛矛牦" +
+	"铆卯峁泖昴茂冒帽瑁瞀貌懋贸耄袤么玫枚梅酶镅鹛霉煤没眉莓媒嵋湄猸楣镁每美浼昧袂寐魅" +
+	"妹媚门扪钔闷焖懑们虻萌蒙甍瞢朦檬礞艨勐盟锰艋蜢懵蠓猛梦咪孟眯醚靡蘼糜縻麋迷猕谜弥" +
+	"祢米芈弭敉脒冖糸汨宓秘觅泌蜜宀密幂谧嘧棉眠绵冕渑湎免沔黾勉眄娩缅腼面喵苗描瞄鹋杪" +
+	"眇藐邈秒淼渺缈庙乜咩妙蔑篾蠛灭民岷苠珉缗抿泯皿闵敏愍鳘悯闽明螟酩鸣茗冥铭溟暝瞑名" +
+	"命谬摸谟嫫馍摹蘑模膜麽磨摩魔抹末殁莫墨默貘耱哞沫茉漠蓦貊瘼镆寞陌秣谋蛑缪鍪牟侔眸" +
+	"某毪拇嗯牡坶亩姆母墓暮幕募慕木仫目沐睦牧苜钼穆拿镎哪呐钠捺那娜衲纳肭氖乃奶艿耐萘" +
+	"鼐囡奈柰南男难喃楠赧腩蝻囔囊馕曩攮孬呶挠硇铙猱蛲垴脑瑙恼闹淖疒讷呢馁内恁嫩能妮霓" +
+	"鲵倪铌猊泥尼坭怩拟旎伲昵你匿腻逆溺睨蔫拈年鲇鲶黏碾廿撵捻辇念埝娘酿鸟茑袅嬲尿脲捏" +
+	"陧聂臬孽蘖啮嗫镊镍颞蹑涅您柠聍甯狞凝佞宁咛拧泞妞牛忸扭狃钮纽脓浓农侬哝弄耨奴孥驽" +
+	"努弩胬怒女钕恧衄暖虐疟挪傩懦糯喔噢诺喏搦锘哦讴欧鸥殴瓯藕怄呕偶耦沤啪葩杷趴爬帕怕" +
+	"琶筢拍俳排牌哌徘湃蒎派攀爿潘盘磐蹒蟠盼畔袢襻判拚泮叛乓滂庞逄旁螃耪胖抛脬咆庖狍刨" +
+	"炮疱袍匏跑泡呸胚醅培裴赔锫陪配辔霈佩帔旆沛喷盆湓怦砰抨烹嘭澎彭蓬棚硼篷丕膨蟛朋堋" +
+	"鹏捧碰坯砒铍霹批纰邳披劈噼琵毗啤埤脾罴蜱貔鼙疲蚍郫陴皮芘枇匹庀疋仳圮痞擗癖僻甓屁" +
+	"淠媲睥譬篇翩骈胼蹁谝偏犏片骗剽缥飘螵嫖漂氕瓢殍瞟票嘌撇瞥丿苤姘拼频颦贫嫔品榀牝聘" +
+	"乒俜娉坪苹萍鲆钋平凭瓶评屏枰坡泼颇婆鄱皤叵钷笸破魄迫珀粕剖掊裒扑噗匍铺仆攴莆葡菩" +
+	"蒲璞濮镤埔朴圃普溥浦谱氆镨蹼曝瀑期欺嘁栖桤萋戚妻七凄漆槭蹊亓柒沏其棋琦琪祺蛴奇歧" +
+	"畦萁骐崎淇脐颀齐圻岐芪旗綦蜞蕲鳍麒祈俟耆祁骑起绮綮岂芑乞企屺启杞契砌葺碛器憩气迄" +
+	"弃汽泣讫汔掐葜恰洽髂牵悭扦芊钎铅千迁佥岍签骞搴褰仟阡谦愆乾尴黔凵钱钳掮箝前钤虔潜" +
+	"遣浅肷谴缱堑嵌椠慊欠芡茜倩歉枪跄呛腔蜣锖锵镪丬羌戕戗墙嫱蔷樯强抢羟襁炝橇缲锹敲悄" +
+	"硗跷劁桥谯憔鞒樵瞧乔侨荞巧愀鞘撬翘峭俏诮窍切妾茄迦且怯郄窃挈惬箧锲钦衾芩侵亲秦琴" +
+	"勤嗪溱噙芹擒檎螓锓禽寝吣沁揿青轻氢倾卿圊清蜻鲭擎檠黥苘晴氰情顷请庆箐磬罄謦芎邛琼" +
+	"蛩跫銎穷穹茕筇秋蚯楸鳅丘邱球赇巯遒裘蝤鼽糗求虬囚犰酋逑泅俅趋麴黢劬朐鸲区蛆曲岖诎" +
+	"躯蛐屈祛驱渠蕖磲璩瞿蘧氍癯衢蠼取娶龋趣悛去阒觑圈颧权诠醛鬈泉荃全痊铨筌蜷拳辁犬畎" +
+	"绻券犭劝缺阙炔瘸却悫鹊逡榷确阕雀裙群蚺然髯燃冉苒染禳瓤穰壤攘嚷让娆荛饶桡扰绕惹热" +
+	"壬仁人亻忍荏稔韧饪衽葚任认仞刃妊轫纫扔仍日戎肜狨茸蓉榕荣融熔蝾溶容嵘绒冗揉糅蹂鞣" +
+	"柔肉茹铷蠕颥儒嚅孺濡薷襦如辱乳汝入洳溽缛蓐褥软蕤阮朊蕊芮枘蚋瑞睿锐闰润若偌弱箬仨" +
+	"挲撒洒卅飒脎萨腮噻鳃塞赛三叁毵伞糁馓霰散桑嗓搡磉颡丧搔骚缫臊鳋扫嫂埽瘙瑟穑色涩啬" +
+	"铯森僧莎铩痧砂杀刹沙纱傻唼啥煞裟鲨筛酾晒珊舢跚苫杉芟姗山彡删煽潸膻衫钐埏闪陕讪擅" +
+	"赡蟮鳝膳善骟鄯汕疝剡扇缮嬗墒熵垧伤殇商觞赏晌上尚绱裳梢捎稍筲艄蛸烧芍苕勺韶少劭哨" +
+	"潲邵绍奢猞赊畲蛇舌佘舍厍赦摄滠歙麝射慑涉社设砷莘申呻伸身深娠绅诜神沈审哂矧谂婶渖" +
+	"甚肾胂慎椹蜃渗声生甥牲笙升绳省眚盛剩嵊胜圣师失狮施湿蓍鲺诗尸虱十饣石拾时什食埘莳" +
+	"鲥蚀实炻识史矢豕使屎驶始式示士世柿贳事拭誓逝铈豉弑谥势是嗜筮噬螫适舐轼仕侍释饰氏" +
+	"礻市恃室视试收手首艏守寿授绶售受狩瘦扌兽蔬秫枢姝倏梳殊抒纾输叔舒摅毹淑菽疏书殳赎" +
+	"塾孰熟薯暑曙署蜀黍鼠属术述树束沭戍竖墅庶数腧漱澍恕刷唰耍摔衰甩帅蟀闩栓涮拴霜孀双" +
+	"爽谁水睡氵税吮瞬顺舜说妁硕搠蒴槊厶纟朔铄烁斯缌蛳厮锶撕澌嘶思鸶私咝司丝死肆忪寺汜" +
+	"兕姒祀泗嗣四伺似饲驷笥耜巳松凇崧淞菘嵩耸竦怂悚颂嗖送宋讼诵搜溲馊飕锼艘螋叟嗾瞍擞" +
+	"薮嗽苏酥稣俗夙素速粟谡嗉僳蔌觫簌塑愫溯宿诉狻肃涑酸蒜算攵虽荽眭睢濉隋随绥髓碎岁穗" +
+	"邃遂隧燧祟谇孙狲荪飧损笋隼榫蓑梭睃嗍羧唆娑桫缩琐索锁嗦所唢塌溻他它她趿铊塔獭鳎挞" +
+	"闼遢榻蹋踏胎苔炱跆鲐薹抬台邰泰酞太态肽钛汰坍摊贪瘫滩坛昙檀忐痰锬潭谭谈郯覃坦毯袒" +
+	"钽碳探叹炭汤铴耥羰镗饧塘搪溏瑭樘堂棠膛唐糖螗螳醣帑倘躺淌傥趟烫掏涛滔韬饕洮绦萄鼗" +
+	"桃逃淘陶啕讨套忑忒特铽慝藤腾疼誊滕梯剔踢荑绨锑提缇鹈题蹄醍啼体替裼嚏惕涕逖剃倜悌" +
+	"屉天添填阗忝殄田甜恬畋舔掭佻腆挑祧条迢笤龆蜩髫鲦窕眺粜跳贴萜铁餮帖厅听町烃汀廷停" +
+	"婷葶蜓霆亭庭莛挺梃艇通嗵仝桐砼酮僮潼瞳同佟铜彤茼童桶捅筒恸统痛偷亠投骰钭头透凸秃" +
+	"突图徒荼途涂屠菟酴土吐钍兔堍湍团抟疃彖推颓腿蜕褪退煺吞暾屯饨豚臀氽乇拖托脱鸵跎酡" +
+	"橐鼍陀坨沱沲砣驮佗驼椭柝妥庹拓唾箨挖哇蛙洼娲娃瓦佤袜腽歪崴外豌弯剜湾蜿玩顽丸纨芄" +
+	"烷完碗挽晚绾脘菀琬皖畹惋宛婉万腕汪王亡枉网往罔惘辋魍旺望忘妄威偎巍囗微煨薇危韦圩" +
+	"违闱桅涠围帏沩唯帷惟为潍维嵬苇萎逶隈葳委炜玮洧娓诿猥痿艉韪鲔伟伪尾纬未蔚味畏胃軎" +
+	"喂魏猬位渭谓尉慰卫瘟温蚊阌雯刎文闻纹玟吻稳紊问汶璺嗡蓊翁瓮蕹挝倭蜗涡莴窝我斡龌卧" +
+	"幄握渥硪沃肟巫呜钨乌圬污邬诬屋无芜唔浯梧蜈鼯吾吴毋武五捂牾鹉午仵妩庑忤怃舞兀伍侮" +
+	"坞杌芴迕戊阢雾寤鹜鋈晤焐婺痦骛物勿务悟误昔熙蜥析穸郗唏奚浠西硒菥矽晰嘻嬉吸锡僖牺" +
+	"稀粞翕舾息希悉膝樨熹羲螅蟋醯曦鼷夕兮惜欷淅熄烯溪皙汐犀檄袭觋席习媳隰喜葸屣蓰禧铣" +
+	"洗玺徙系饩隙禊戏细阋舄瞎虾匣霞黠辖暇瑕峡柙侠狎狭硖遐下厦歃霎夏罅吓掀跹酰锨先仙鲜" +
+	"暹纤氙祆籼莶咸贤衔痫鹇舷闲涎娴弦嫌冼显险猃蚬筅跣藓燹现献县岘苋腺馅羡宪陷限线相厢" +
+	"镶香箱襄骧湘缃葙乡芗翔祥详庠想鲞响饷飨享项巷橡蟓枭哓枵骁像向象萧硝霄魈削哮嚣崤销" +
+	"潇箫消绡逍宵淆晓筱小孝校肖啸笑效楔些歇蝎鞋协挟偕携勰撷缬邪斜胁谐写械亵渫卸蟹躞懈" +
+	"獬薤邂燮瀣泄泻绁谢榍榭廨屑薪馨鑫囟芯锌欣辛昕新歆忻心信衅忄星腥猩惺兴刑型荥硎形陉" +
+	"邢行醒擤幸杏性荇悻姓兄凶胸匈汹雄熊休修咻庥羞鸺貅馐髹朽嗅溴锈秀岫袖绣墟戌盱胥需虚" +
+	"嘘须顼徐许诩栩糈醑蓄蓿酗叙旭序畜恤洫勖絮煦婿溆绪续轩喧揎萱暄煊儇宣谖悬旋漩璇玄痃" +
+	"选癣泫炫眩铉渲楦碹镟绚靴薛学泶踅穴雪鳕血谑勋埙熏窨獯薰曛醺循鲟旬询峋恂洵浔荀荨寻" +
+	"驯巡殉巽蕈汛训讯逊迅徇压吖押垭鸦桠鸭呀恹丫芽琊牙伢岈蚜崖衙涯睚雅哑痖亚讶迓娅砑氩" +
+	"揠焉菸咽阉湮腌鄢嫣讠烟胭崦淹盐严妍芫研蜒岩延言颜檐兖阎筵炎沿奄俨掩眼郾琰罨衍偃厣" +
+	"演魇鼹艳堰燕赝厌闫砚雁滟酽谳餍唁彦焰焱宴晏谚验殃央泱鸯鞅秧杨炀扬佯疡徉羊洋烊蛘阳" +
+	"氧仰痒怏恙养样漾幺夭吆邀爻腰妖瑶繇鳐杳摇尧肴遥窑谣徭姚轺珧咬窈舀崾药要耀椰噎耶揶" +
+	"铘爷野冶也页邺掖业叶曳腋靥夜晔烨液谒一壹医揖欹漪噫黟铱依咿猗伊衣颐夷遗移仪圯胰痍" +
+	"疑嶷沂诒怡迤饴咦宜姨贻眙彝椅旖蚁倚酏已乙矣苡舣以钇艺抑易邑佾峄怿屹亿弋刈役臆癔镱" +
+	"懿衤逸肄疫羿轶悒挹亦裔瘗蜴意毅熠镒劓殪薏翳忆义益溢缢诣驿奕弈议谊埸翊译异佚呓翼翌" +
+	"绎茵荫因殷氤铟喑堙音阴姻洇吟垠狺银鄞夤霪廴淫寅饮蚓尹引吲隐瘾印茚胤英莺樱璎鹦膺婴" +
+	"瑛嘤撄鹰应缨罂莹萤营萦楹滢蓥潆嬴荧蝇迎茔赢瀛郢颍盈影瘿颖硬媵映哟唷拥佣臃鳙饔喁痈" +
+	"邕庸雍墉慵壅镛踊蛹咏泳俑涌永甬恿勇用幽优悠尢忧攸呦尤由邮铀蚰犹油疣莜莸游鱿猷蝣酉" +
+	"莠铕牖黝有卣友纡右佑侑囿宥柚釉鼬诱蚴又幼迂淤瘀于盂臾榆瑜虞觎窬愚舆蝓余妤欤於俞禺" +
+	"竽舁逾鱼愉揄渝腴渔萸隅雩嵛予伛娱狳谀馀雨俣与屿禹宇语圄圉庾瘐窳龉肀羽玉域芋妪饫郁" +
+	"昱吁遇鹆喻峪御愈煜蓣欲谕阈狱育誉毓蜮浴钰寓裕预豫燠鹬鬻鸢驭聿鸳渊箢冤眢元垣爰袁原" +
+	"援辕橼螈园沅员圆猿源缘鼋塬远苑愿怨院垸媛掾瑗曰约越樾龠瀹跃钥鹞曜岳粤月刖悦钺阅耘" +
+	"筠云郧匀纭芸昀陨殒允狁运郓恽蕴酝愠韫晕氲韵熨孕匝咂拶砸杂栽哉灾甾宰崽载糌簪再在咱" +
+	"昝攒趱暂赞錾瓒赃臧驵奘脏葬遭糟凿藻枣早澡蚤躁噪造皂唣灶燥责迮啧帻笮舴箦赜仄昃择则" +
+	"泽贼怎谮增憎缯罾锃甑曾赠扎吒哳喳揸渣楂齄札轧铡闸眨砟栅榨咋乍炸痄蚱诈咤摘斋宅窄债" +
+	"砦寨瘵瞻毡旃詹谵粘沾盏斩辗崭搌展蘸栈占战站湛绽樟璋蟑仉章鄣嫜彰漳獐张掌涨杖丈帐账" +
+	"仗胀瘴钊障嶂幛招昭啁找沼赵笊棹照罩兆诏肇蜇召遮折哲辄蛰谪摺磔辙者锗赭褶蔗这柘浙鹧" +
+	"珍胗桢斟真甄蓁榛箴砧祯臻贞针侦浈枕轸畛疹缜稹圳诊震振朕赈镇阵鸩蒸徵挣睁铮筝征狰钲" +
+	"争怔诤峥整拯正政帧症郑证芝枝支卮吱蜘知肢栀祗胝脂汁之织职直植殖絷跖摭踯夂执值埴侄" +
+	"址指枳轵止趾黹酯只旨纸芷祉咫志忮豸挚桎掷至致贽轾置雉膣觯踬帜峙栉陟制帙智秩稚质郅" +
+	"炙痔滞痣蛭骘治窒鸷彘中盅忠钟舯衷锺螽终种冢踵肿重仲众舟周州洲诌粥妯轴肘纣帚咒皱酎" +
+	"宙绉昼胄荮骤籀珠株蛛槠潴橥朱侏猪铢诸诛邾洙茱逐舳瘃躅丶竹竺烛煮拄渚瞩伫嘱麈主著柱" +
+	"炷助苎杼蛀贮铸箸翥筑住注祝疰驻抓爪拽专砖颛转啭撰赚篆馔桩庄装妆撞隹壮状椎槌锥追骓" +
+	"赘坠惴缒缀肫窀谆准捉拙倬卓桌涿琢茁斫酌啄禚擢濯镯着灼浊浞诼兹咨资姿赀滋粢辎觜訾趑" +
+	"锱龇髭鲻淄缁谘孳嵫孜紫仔姊秭籽耔笫梓滓子自恣渍眦字鬃棕腙踪宗综总偬纵粽邹驺诹陬鄹" +
+	"鲰走奏揍楱租足卒族镞祖诅阻组俎钻躜缵纂攥嘴醉最罪蕞尊遵樽鳟撙昨左佐柞做作坐阼怍祚" +
+	"胙唑座"
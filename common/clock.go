@@ -0,0 +1,25 @@
+package common
+
+import (
+	"context"
+	"time"
+)
+
+type clockKey struct{}
+
+// 时钟，用于在上下文中替换当前时间，方便测试相对时间的查询
+type Clock func() time.Time
+
+func WithClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// 获取上下文中的当前时间，没有设置时钟时使用系统时间
+func Now(ctx context.Context) time.Time {
+	if ctx != nil {
+		if clock, ok := ctx.Value(clockKey{}).(Clock); ok && clock != nil {
+			return clock()
+		}
+	}
+	return time.Now()
+}
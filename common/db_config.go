@@ -1,5 +1,5 @@
-package common
-
-type Config struct {
-	SQLiteConnectionOptions map[string]string
-}
+package common
+
+type Config struct {
+	SQLiteConnectionOptions map[string]string
+}
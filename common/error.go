@@ -1,10 +1,12 @@
-package common
-
-import "fmt"
-
-var (
-	ErrObjectNotFound         = fmt.Errorf("object not found")
-	ErrTableNotFound          = fmt.Errorf("table not found")
-	ErrAttributeClassNotFound = fmt.Errorf("attribute class not found")
-	ErrAttributeNotFound      = fmt.Errorf("attribute not found")
-)
+package common
+
+import "fmt"
+
+var (
+	ErrObjectNotFound         = fmt.Errorf("object not found")
+	ErrTableNotFound          = fmt.Errorf("table not found")
+	ErrAttributeClassNotFound = fmt.Errorf("attribute class not found")
+	ErrAttributeNotFound      = fmt.Errorf("attribute not found")
+
+	ErrAttributeClassDependCycle = fmt.Errorf("attribute class depend cycle")
+)
@@ -1,29 +1,97 @@
-package common
-
-import (
-	"context"
-	"paroket/tx"
-)
-
-type Query interface {
-	Filter(ctx context.Context, tx tx.ReadTx, filter string) (q Query)
-	OrderBy(ctx context.Context, tx tx.ReadTx, order string) (q Query)
-	Limit(limit int) (q Query)
-	Offset(offset int) (q Query)
-	Find(ctx context.Context, tx tx.ReadTx) (objList []Object, err error)
-}
-
-type QueryBuilder interface {
-	ParseFilter(ctx context.Context, tx tx.ReadTx, filter string) (err error)
-	ParseOrder(ctx context.Context, tx tx.ReadTx, order string) (err error)
-	BuildFilter(ctx context.Context, tx tx.ReadTx) (stmt string, err error)
-	BuildSort(ctx context.Context, tx tx.ReadTx) (stmt string, err error)
-}
-
-type FilterField interface {
-	BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (string, error)
-}
-
-type SortField interface {
-	BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (string, error)
-}
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"paroket/tx"
+)
+
+type Query interface {
+	Filter(ctx context.Context, tx tx.ReadTx, filter string) (q Query)
+	Where(cond Condition) (q Query)                                      // 与Filter相同，使用query包构建的条件
+	FilterExpr(ctx context.Context, tx tx.ReadTx, expr string) (q Query) // 与Filter相同，使用筛选表达式，见query.ParseExpr
+	OrderBy(ctx context.Context, tx tx.ReadTx, order string) (q Query)
+	Sort(orders ...Ordering) (q Query) // 与OrderBy相同，使用query包构建的排序项
+	Limit(limit int) (q Query)
+	Offset(offset int) (q Query)
+	Find(ctx context.Context, tx tx.ReadTx) (objList []Object, err error)
+}
+
+// query包中构建的筛选条件和排序项，序列化后与视图中保存的json一致
+type Condition interface {
+	json.Marshaler
+}
+
+type Ordering interface {
+	json.Marshaler
+}
+
+type QueryBuilder interface {
+	ParseFilter(ctx context.Context, tx tx.ReadTx, filter string) (err error)
+	ParseOrder(ctx context.Context, tx tx.ReadTx, order string) (err error)
+	BuildFilter(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error)
+	BuildSort(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error)
+	SortKeys(ctx context.Context, tx tx.ReadTx) (keys []SortKey, err error) // 排序项，不包含最后的对象id
+	SearchQuery() (query string, ok bool)                                   // 过滤条件中第一个全文搜索的搜索词
+}
+
+// 排序项拆分出的表达式、方向和空值的位置
+type SortKey struct {
+	Expr       string
+	Args       []interface{}
+	Desc       bool
+	NullsFirst bool
+}
+
+// ORDER BY中的一项，空值的位置与sqlite默认的一致时省略NULLS
+func (k SortKey) Stmt() string {
+	stmt := k.Expr + " ASC"
+	if k.Desc {
+		stmt = k.Expr + " DESC"
+	}
+	switch {
+	case k.NullsFirst && k.Desc:
+		stmt += " NULLS FIRST"
+	case !k.NullsFirst && !k.Desc:
+		stmt += " NULLS LAST"
+	}
+	return stmt
+}
+
+// 构建的sql片段中的用户输入一律使用?占位，对应的值按顺序放在args中
+type FilterField interface {
+	BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error)
+}
+
+// 属性声明支持的筛选操作和每个操作的值类型，用于在保存筛选之前校验
+type FilterOpsField interface {
+	FilterOps() map[string]FilterValueType
+}
+
+// 筛选值的类型，对应json解析后的值
+type FilterValueType string
+
+const (
+	FilterValueString       FilterValueType = "string"
+	FilterValueNumber       FilterValueType = "number"
+	FilterValueInteger      FilterValueType = "integer"
+	FilterValueBool         FilterValueType = "bool"
+	FilterValueOptionalBool FilterValueType = "optional_bool" // 布尔值或null，null时使用默认值
+	FilterValueScalar       FilterValueType = "scalar"        // 字符串、数值或布尔值
+	FilterValueStrings      FilterValueType = "strings"       // 一个字符串或非空的字符串数组
+	FilterValueStringList   FilterValueType = "string_list"   // 可以为空，in为空时不匹配任何对象
+	FilterValueNumberList   FilterValueType = "number_list"
+	FilterValueBoolList     FilterValueType = "bool_list" // 至少有一个值
+	FilterValueStringPair   FilterValueType = "string_pair"
+	FilterValueNumberPair   FilterValueType = "number_pair"
+)
+
+type SortField interface {
+	BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key SortKey, err error)
+}
+
+// 分组字段，stmt为对象所属分组键的json数组
+// 一个对象可以属于多个分组（多选、关联），数组为空或只有null时属于空分组
+type GroupField interface {
+	BuildGroup(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error)
+}
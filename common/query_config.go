@@ -1,6 +1,31 @@
-package common
-
-type QueryConfig struct {
-	Limit  int
-	Offset int
-}
+package common
+
+// 查询视图时临时覆盖视图的设置，不会修改视图
+type QueryConfig struct {
+	Limit  int    // 为0时使用视图的设置
+	Offset int    //
+	Filter string // 与视图的筛选同时生效的额外筛选
+}
+
+// 全文搜索结果的高亮配置
+type HighlightConfig struct {
+	StartMark string // 匹配文本前插入的标记
+	EndMark   string // 匹配文本后插入的标记
+	Ellipsis  string // 片段被截断时使用的省略符
+	Context   int    // 匹配文本前后保留的字符数
+}
+
+func DefaultHighlightConfig() *HighlightConfig {
+	return &HighlightConfig{
+		StartMark: "<mark>",
+		EndMark:   "</mark>",
+		Ellipsis:  "...",
+		Context:   20,
+	}
+}
+
+// 对象中匹配搜索的属性及其高亮片段
+type Highlight struct {
+	ClassId AttributeClassId
+	Snippet string
+}
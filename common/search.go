@@ -0,0 +1,17 @@
+package common
+
+// 跨表全文搜索的选项
+type SearchOption struct {
+	TableIdList []TableId        // 搜索的表，为空时搜索全部表
+	Limit       int              // 为0时使用默认值
+	Offset      int              //
+	Highlight   *HighlightConfig // 不为nil时返回高亮片段
+}
+
+// 跨表全文搜索的结果，同一个对象只出现一次
+type SearchHit struct {
+	Object      Object
+	TableIdList []TableId   // 对象所属的全部表
+	Score       float64     // bm25相关度，越小越相关；没有fts5时为0
+	Highlights  []Highlight // 开启高亮时，对象在匹配的表中各个属性的高亮片段
+}
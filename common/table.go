@@ -1,39 +1,45 @@
-package common
-
-import (
-	"context"
-	"paroket/tx"
-	"paroket/utils"
-)
-
-type Table interface {
-	Name() string
-
-	TableId() TableId
-
-	MetaInfo() utils.JSONMap
-
-	Fields() []AttributeClassId
-
-	Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error)
-
-	FindId(ctx context.Context, tx tx.ReadTx, oidList ...ObjectId) ([]Object, error)
-
-	Insert(ctx context.Context, tx tx.WriteTx, oidList ...ObjectId) error
-
-	Delete(ctx context.Context, tx tx.WriteTx, oidList ...ObjectId) error
-
-	AddAttributeClass(ctx context.Context, tx tx.WriteTx, ac AttributeClass) error
-
-	DeleteAttributeClass(ctx context.Context, tx tx.WriteTx, ac AttributeClass) error
-
-	NewView(ctx context.Context, tx tx.WriteTx) (View, error)
-
-	ListView(ctx context.Context, tx tx.ReadTx) ([]View, error)
-
-	View(ctx context.Context, tx tx.ReadTx, vid ViewId) (View, error)
-
-	GetViewData(ctx context.Context, tx tx.ReadTx, vid ViewId) (TableResult, error)
-
-	DropTable(ctx context.Context, tx tx.WriteTx) error
-}
+package common
+
+import (
+	"context"
+	"paroket/tx"
+	"paroket/utils"
+)
+
+type Table interface {
+	Name() string
+
+	TableId() TableId
+
+	MetaInfo() utils.JSONMap
+
+	Fields() []AttributeClassId
+
+	Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error)
+
+	FindId(ctx context.Context, tx tx.ReadTx, oidList ...ObjectId) ([]Object, error)
+
+	Insert(ctx context.Context, tx tx.WriteTx, oidList ...ObjectId) error
+
+	Delete(ctx context.Context, tx tx.WriteTx, oidList ...ObjectId) error
+
+	AddAttributeClass(ctx context.Context, tx tx.WriteTx, ac AttributeClass) error
+
+	DeleteAttributeClass(ctx context.Context, tx tx.WriteTx, ac AttributeClass) error
+
+	NewView(ctx context.Context, tx tx.WriteTx) (View, error)
+
+	ListView(ctx context.Context, tx tx.ReadTx) ([]View, error) // 按视图的顺序排列
+
+	View(ctx context.Context, tx tx.ReadTx, vid ViewId) (View, error)
+
+	DeleteView(ctx context.Context, tx tx.WriteTx, vid ViewId) error
+
+	DuplicateView(ctx context.Context, tx tx.WriteTx, vid ViewId) (View, error) // 副本排在原视图之后
+
+	Query() Query // 不创建视图的临时查询
+
+	GetViewData(ctx context.Context, tx tx.ReadTx, vid ViewId, config *QueryConfig) (TableResult, error) // config为nil时按视图的设置查询
+
+	DropTable(ctx context.Context, tx tx.WriteTx) error
+}
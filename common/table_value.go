@@ -1,100 +1,237 @@
-package common
-
-import (
-	"bytes"
-	"context"
-	"fmt"
-	"paroket/tx"
-)
-
-type TableResult interface {
-	Raw() []Object
-	RawData(ctx context.Context, tx tx.ReadTx) (ret []Result, err error)
-	Marshal(ctx context.Context, tx tx.ReadTx) (ret string, err error)
-}
-
-type Result struct {
-	oid  ObjectId
-	data []Attribute
-}
-
-type tableResultImpl struct {
-	db      Database
-	fields  []AttributeClassId
-	objList []Object
-}
-
-func NewTableResult(db Database, fields []AttributeClassId, objList []Object) (ret TableResult) {
-	ret = &tableResultImpl{
-		db:      db,
-		fields:  fields,
-		objList: objList,
-	}
-	return
-}
-
-func (v *tableResultImpl) Raw() []Object {
-	return v.objList
-}
-
-func (v *tableResultImpl) RawData(ctx context.Context, tx tx.ReadTx) (ret []Result, err error) {
-	ret = []Result{}
-	acList := []AttributeClass{}
-	for _, acid := range v.fields {
-		var ac AttributeClass
-		ac, err = v.db.OpenAttributeClass(ctx, tx, acid)
-		if err != nil {
-			return
-		}
-		acList = append(acList, ac)
-	}
-	for _, obj := range v.objList {
-		attrList := []Attribute{}
-		for _, ac := range acList {
-			var attr Attribute
-			attr, err = ac.FromObject(obj)
-			if err != nil {
-				return
-			}
-			attrList = append(attrList, attr)
-
-		}
-		ret = append(ret, Result{
-			oid:  obj.ObjectId(),
-			data: attrList,
-		})
-	}
-	return
-
-}
-
-func (v *tableResultImpl) Marshal(ctx context.Context, tx tx.ReadTx) (ret string, err error) {
-	resultList, err := v.RawData(ctx, tx)
-	if err != nil {
-		return
-	}
-	retBuffer := &bytes.Buffer{}
-	retBuffer.WriteString("[")
-	for idx, result := range resultList {
-		if idx != 0 {
-			retBuffer.WriteString(",")
-		}
-
-		retBuffer.WriteString("{")
-
-		objectIdData := fmt.Sprintf(`"object_id":"%v"`, result.oid)
-		retBuffer.WriteString(objectIdData)
-
-		for _, attr := range result.data {
-			retBuffer.WriteString(",")
-			attrData := fmt.Sprintf(`"%v":%s`, attr.GetClass().ClassId(), attr.GetJSON())
-			retBuffer.WriteString(attrData)
-		}
-
-		retBuffer.WriteString("}")
-
-	}
-	retBuffer.WriteString("]")
-	ret = retBuffer.String()
-	return
-}
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"paroket/tx"
+)
+
+type TableResult interface {
+	Raw() []Object
+	RawData(ctx context.Context, tx tx.ReadTx) (ret []Result, err error)
+	Marshal(ctx context.Context, tx tx.ReadTx) (ret string, err error)
+	Highlights(oid ObjectId) []Highlight // 对象的高亮片段，查询未开启高亮时为nil
+	Groups() []Group                     // 分组视图的分组，视图未分组时为nil
+	Total() int                          // 匹配筛选的对象总数，不受分页影响
+	Cursor() string                      // 最后一个对象的游标，用于View.QueryAfter，没有下一页时为空
+}
+
+// 分组视图中的一个分组
+type Group struct {
+	Key        interface{}   // 分组键，空分组为nil
+	Count      int           // 分组中的对象数
+	Aggregates []interface{} // 与视图group_by中aggregates的顺序一致
+	Rows       TableResult   // 分组中当前页的对象
+}
+
+type Result struct {
+	oid  ObjectId
+	data []Attribute
+}
+
+type tableResultImpl struct {
+	db         Database
+	fields     []AttributeClassId
+	objList    []Object
+	highlights map[ObjectId][]Highlight
+	groups     []Group
+}
+
+func NewTableResult(db Database, fields []AttributeClassId, objList []Object) (ret TableResult) {
+	ret = &tableResultImpl{
+		db:      db,
+		fields:  fields,
+		objList: objList,
+	}
+	return
+}
+
+// 带有全文搜索高亮的查询结果
+func NewHighlightTableResult(db Database, fields []AttributeClassId, objList []Object, highlights map[ObjectId][]Highlight) (ret TableResult) {
+	ret = &tableResultImpl{
+		db:         db,
+		fields:     fields,
+		objList:    objList,
+		highlights: highlights,
+	}
+	return
+}
+
+// 分组视图的查询结果，Raw返回全部分组当前页的对象
+func NewGroupTableResult(db Database, fields []AttributeClassId, groups []Group) (ret TableResult) {
+	objList := []Object{}
+	for _, group := range groups {
+		objList = append(objList, group.Rows.Raw()...)
+	}
+	ret = &tableResultImpl{
+		db:      db,
+		fields:  fields,
+		objList: objList,
+		groups:  groups,
+	}
+	return
+}
+
+// 分页查询的结果，附带总数和下一页的游标
+type pageTableResult struct {
+	TableResult
+	total  int
+	cursor string
+}
+
+func NewPageTableResult(result TableResult, total int, cursor string) (ret TableResult) {
+	ret = &pageTableResult{
+		TableResult: result,
+		total:       total,
+		cursor:      cursor,
+	}
+	return
+}
+
+func (v *pageTableResult) Total() int {
+	return v.total
+}
+
+func (v *pageTableResult) Cursor() string {
+	return v.cursor
+}
+
+func (v *tableResultImpl) Raw() []Object {
+	return v.objList
+}
+
+func (v *tableResultImpl) Groups() []Group {
+	return v.groups
+}
+
+// 未分页的结果总数为对象数，分组结果为各分组的对象数之和
+func (v *tableResultImpl) Total() int {
+	if v.groups != nil {
+		total := 0
+		for _, group := range v.groups {
+			total += group.Count
+		}
+		return total
+	}
+	return len(v.objList)
+}
+
+func (v *tableResultImpl) Cursor() string {
+	return ""
+}
+
+func (v *tableResultImpl) RawData(ctx context.Context, tx tx.ReadTx) (ret []Result, err error) {
+	ret = []Result{}
+	acList := []AttributeClass{}
+	for _, acid := range v.fields {
+		var ac AttributeClass
+		ac, err = v.db.OpenAttributeClass(ctx, tx, acid)
+		if err != nil {
+			return
+		}
+		acList = append(acList, ac)
+	}
+	for _, obj := range v.objList {
+		attrList := []Attribute{}
+		for _, ac := range acList {
+			var attr Attribute
+			attr, err = ac.FromObject(obj)
+			if err != nil {
+				return
+			}
+			attrList = append(attrList, attr)
+
+		}
+		ret = append(ret, Result{
+			oid:  obj.ObjectId(),
+			data: attrList,
+		})
+	}
+	return
+
+}
+
+func (v *tableResultImpl) Highlights(oid ObjectId) []Highlight {
+	if v.highlights == nil {
+		return nil
+	}
+	return v.highlights[oid]
+}
+
+func (v *tableResultImpl) Marshal(ctx context.Context, tx tx.ReadTx) (ret string, err error) {
+	if v.groups != nil {
+		return v.marshalGroups(ctx, tx)
+	}
+	resultList, err := v.RawData(ctx, tx)
+	if err != nil {
+		return
+	}
+	retBuffer := &bytes.Buffer{}
+	retBuffer.WriteString("[")
+	for idx, result := range resultList {
+		if idx != 0 {
+			retBuffer.WriteString(",")
+		}
+
+		retBuffer.WriteString("{")
+
+		objectIdData := fmt.Sprintf(`"object_id":"%v"`, result.oid)
+		retBuffer.WriteString(objectIdData)
+
+		for _, attr := range result.data {
+			retBuffer.WriteString(",")
+			attrData := fmt.Sprintf(`"%v":%s`, attr.GetClass().ClassId(), attr.GetJSON())
+			retBuffer.WriteString(attrData)
+		}
+
+		if v.highlights != nil {
+			retBuffer.WriteString(`,"$highlight":[`)
+			for hIdx, highlight := range v.highlights[result.oid] {
+				if hIdx != 0 {
+					retBuffer.WriteString(",")
+				}
+				var snippet []byte
+				if snippet, err = json.Marshal(highlight.Snippet); err != nil {
+					return
+				}
+				retBuffer.WriteString(fmt.Sprintf(`{"field":"%v","snippet":%s}`, highlight.ClassId, snippet))
+			}
+			retBuffer.WriteString("]")
+		}
+
+		retBuffer.WriteString("}")
+
+	}
+	retBuffer.WriteString("]")
+	ret = retBuffer.String()
+	return
+}
+
+// 分组结果序列化为 [{"key":...,"count":...,"aggregates":[...],"rows":[...]}]
+func (v *tableResultImpl) marshalGroups(ctx context.Context, tx tx.ReadTx) (ret string, err error) {
+	retBuffer := &bytes.Buffer{}
+	retBuffer.WriteString("[")
+	for idx, group := range v.groups {
+		if idx != 0 {
+			retBuffer.WriteString(",")
+		}
+		var key, aggregates []byte
+		if key, err = json.Marshal(group.Key); err != nil {
+			return
+		}
+		if aggregates, err = json.Marshal(group.Aggregates); err != nil {
+			return
+		}
+		var rows string
+		if rows, err = group.Rows.Marshal(ctx, tx); err != nil {
+			return
+		}
+		retBuffer.WriteString(fmt.Sprintf(`{"key":%s,"count":%d,"aggregates":%s,"rows":%s}`,
+			key, group.Count, aggregates, rows))
+	}
+	retBuffer.WriteString("]")
+	ret = retBuffer.String()
+	return
+}
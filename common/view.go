@@ -1,17 +1,44 @@
-package common
-
-import (
-	"context"
-	"paroket/tx"
-)
-
-type View interface {
-	ViewId() ViewId
-	Filter(tx tx.WriteTx, filter string) (err error)
-	SortBy(tx tx.WriteTx, order string) (err error)
-	Limit(limit int) (v View)
-	Offset(offset int) (v View)
-	Set(v map[string]interface{}) (err error)
-	Query(ctx context.Context, tx tx.ReadTx) (queryData TableResult, err error)
-	Marshal() string
-}
+package common
+
+import (
+	"context"
+	"paroket/tx"
+	"paroket/utils"
+)
+
+type View interface {
+	ViewId() ViewId
+	Name() string
+	Description() string
+	Position() int // 视图在表中的顺序，从0开始连续编号
+	Filter(tx tx.WriteTx, filter string) (err error)
+	SortBy(tx tx.WriteTx, order string) (err error)
+	Limit(limit int) (v View)
+	Offset(offset int) (v View)
+	Highlight(config *HighlightConfig) (v View)                                                                            // 使用全文搜索过滤时，在结果中返回匹配的属性和高亮片段
+	Set(ctx context.Context, tx tx.WriteTx, v utils.JSONMap) (err error)                                                   // 修改名称、描述、顺序、显示的列、列顺序、列宽、分页、筛选、排序和分组
+	Query(ctx context.Context, tx tx.ReadTx) (queryData TableResult, err error)                                            // 设置了group_by时返回分组
+	QueryAfter(ctx context.Context, tx tx.ReadTx, cursor string) (queryData TableResult, err error)                        // 从TableResult.Cursor之后查询下一页
+	Summary(ctx context.Context, tx tx.ReadTx, spec SummarySpec) (summary SummaryResult, err error)                        // 在视图的筛选下统计各列
+	QueryGroup(ctx context.Context, tx tx.ReadTx, key interface{}, config *QueryConfig) (queryData TableResult, err error) // 分页查询分组中的对象，key为Group.Key
+	Pruned() []ViewPrune                                                                                                   // 删除属性时被移除的列、筛选和排序，提示用户后调用ClearPruned清除
+	ClearPruned(tx tx.WriteTx) (err error)
+	Marshal() string
+}
+
+// 从表中删除属性时，视图中被移除的内容
+type ViewPrune struct {
+	ClassId AttributeClassId
+	Field   bool     // 是否从显示的列中移除
+	Filter  []string // 移除的筛选条件
+	Order   []string // 移除的排序项
+	GroupBy bool     // 是否移除了分组或分组中的统计
+}
+
+// 列统计的设置，key为属性，value为统计方式：
+// 所有属性支持count、count_empty、count_unique，
+// 数值属性支持sum、avg、min、max、median、std，日期属性支持earliest、latest
+type SummarySpec map[AttributeClassId][]string
+
+// 列统计的结果，result[acid][统计方式]
+type SummaryResult map[AttributeClassId]map[string]interface{}
@@ -0,0 +1,105 @@
+package fts
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 搜索中需要高亮的词，NOT下的词不会出现在结果中，不参与高亮
+func (n *Node) Terms() (terms []string) {
+	terms = []string{}
+	switch n.Type {
+	case NodeTerm, NodePhrase:
+		if n.Text != "" {
+			terms = append(terms, n.Text)
+		}
+	case NodeNot:
+	default:
+		for _, child := range n.ChildNodes {
+			terms = append(terms, child.Terms()...)
+		}
+	}
+	return
+}
+
+type matchRange struct {
+	start int
+	end   int
+}
+
+// 在文本中查找所有词出现的位置，忽略大小写，重叠的位置会合并
+func findMatches(runes []rune, terms []string) (matches []matchRange) {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	for _, term := range terms {
+		termRunes := []rune(term)
+		for i, r := range termRunes {
+			termRunes[i] = unicode.ToLower(r)
+		}
+		if len(termRunes) == 0 {
+			continue
+		}
+		for i := 0; i+len(termRunes) <= len(lower); i++ {
+			if string(lower[i:i+len(termRunes)]) == string(termRunes) {
+				matches = append(matches, matchRange{i, i + len(termRunes)})
+			}
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].start < matches[j].start
+	})
+	merged := []matchRange{}
+	for _, m := range matches {
+		if last := len(merged) - 1; last >= 0 && m.start <= merged[last].end {
+			if m.end > merged[last].end {
+				merged[last].end = m.end
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+// 生成高亮片段，片段从第一个匹配前context个字符开始，
+// 相邻匹配间隔不超过2*context时合并到同一片段中
+// 文本中没有匹配时ok为false
+func Snippet(text string, terms []string, startMark string, endMark string, ellipsis string, context int) (snippet string, ok bool) {
+	runes := []rune(text)
+	matches := findMatches(runes, terms)
+	if len(matches) == 0 {
+		return
+	}
+	ok = true
+	if context < 0 {
+		context = 0
+	}
+	last := 0
+	for last+1 < len(matches) && matches[last+1].start-matches[last].end <= 2*context {
+		last++
+	}
+	start := max(matches[0].start-context, 0)
+	end := min(matches[last].end+context, len(runes))
+
+	buffer := &strings.Builder{}
+	if start > 0 {
+		buffer.WriteString(ellipsis)
+	}
+	pos := start
+	for _, m := range matches[:last+1] {
+		buffer.WriteString(string(runes[pos:m.start]))
+		buffer.WriteString(startMark)
+		buffer.WriteString(string(runes[m.start:m.end]))
+		buffer.WriteString(endMark)
+		pos = m.end
+	}
+	buffer.WriteString(string(runes[pos:end]))
+	if end < len(runes) {
+		buffer.WriteString(ellipsis)
+	}
+	snippet = buffer.String()
+	return
+}
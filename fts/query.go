@@ -0,0 +1,421 @@
+package fts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 全文搜索语法：
+//
+//	词          cat
+//	短语        "black cat"
+//	前缀        cat*
+//	或          cat OR dog
+//	与          cat dog / cat AND dog
+//	非          cat NOT dog / cat -dog
+//	邻近        NEAR(cat dog, 5)
+//	分组        (cat OR dog) food
+
+type NodeType string
+
+const (
+	NodeTerm   NodeType = "term"
+	NodePhrase NodeType = "phrase"
+	NodeAnd    NodeType = "and"
+	NodeOr     NodeType = "or"
+	NodeNot    NodeType = "not"
+	NodeNear   NodeType = "near"
+)
+
+// NEAR未指定距离时的默认值，与fts5一致
+const DefaultNearDistance = 10
+
+type Node struct {
+	Type       NodeType
+	Text       string
+	Prefix     bool
+	ChildNodes []*Node
+	Distance   int
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokLParen
+	tokRParen
+	tokComma
+	tokMinus
+	tokStar
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(src string) (tokens []token, err error) {
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case r == '*':
+			tokens = append(tokens, token{tokStar, "*", i})
+			i++
+		case r == '-' && (i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '('):
+			tokens = append(tokens, token{tokMinus, "-", i})
+			i++
+		case r == '"':
+			start := i
+			i++
+			var buf strings.Builder
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '"' {
+					// 两个双引号表示一个双引号
+					if i+1 < len(runes) && runes[i+1] == '"' {
+						buf.WriteRune('"')
+						i += 2
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				buf.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				err = fmt.Errorf("fts: unterminated phrase at %d", start)
+				return
+			}
+			tokens = append(tokens, token{tokString, buf.String(), start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()",*`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, token{tokWord, string(runes[start:i]), start})
+		}
+	}
+	tokens = append(tokens, token{tokEOF, "", len(runes)})
+	return
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) isKeyword(tok token, keyword string) bool {
+	return tok.kind == tokWord && tok.text == keyword
+}
+
+// 解析搜索字符串
+func Parse(src string) (node *Node, err error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		err = fmt.Errorf("fts: empty search")
+		return
+	}
+	node, err = p.parseOr()
+	if err != nil {
+		return
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		err = fmt.Errorf("fts: unexpected %q at %d", tok.text, tok.pos)
+	}
+	return
+}
+
+func (p *parser) parseOr() (node *Node, err error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return
+	}
+	childNodes := []*Node{left}
+	for p.isKeyword(p.peek(), "OR") {
+		p.next()
+		var right *Node
+		if right, err = p.parseAnd(); err != nil {
+			return
+		}
+		childNodes = append(childNodes, right)
+	}
+	if len(childNodes) == 1 {
+		node = left
+		return
+	}
+	node = &Node{Type: NodeOr, ChildNodes: childNodes}
+	return
+}
+
+func (p *parser) parseAnd() (node *Node, err error) {
+	childNodes := []*Node{}
+	for {
+		tok := p.peek()
+		if tok.kind == tokEOF || tok.kind == tokRParen || p.isKeyword(tok, "OR") {
+			break
+		}
+		if p.isKeyword(tok, "AND") {
+			p.next()
+			continue
+		}
+		var child *Node
+		if child, err = p.parseUnary(); err != nil {
+			return
+		}
+		childNodes = append(childNodes, child)
+	}
+	if len(childNodes) == 0 {
+		tok := p.peek()
+		err = fmt.Errorf("fts: expect term at %d", tok.pos)
+		return
+	}
+	if len(childNodes) == 1 {
+		node = childNodes[0]
+		return
+	}
+	node = &Node{Type: NodeAnd, ChildNodes: childNodes}
+	return
+}
+
+func (p *parser) parseUnary() (node *Node, err error) {
+	tok := p.peek()
+	if tok.kind == tokMinus || p.isKeyword(tok, "NOT") {
+		p.next()
+		var child *Node
+		if child, err = p.parseUnary(); err != nil {
+			return
+		}
+		node = &Node{Type: NodeNot, ChildNodes: []*Node{child}}
+		return
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node *Node, err error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokLParen:
+		if node, err = p.parseOr(); err != nil {
+			return
+		}
+		if end := p.next(); end.kind != tokRParen {
+			err = fmt.Errorf("fts: expect ) at %d", end.pos)
+		}
+		return
+	case tokString:
+		node = &Node{Type: NodePhrase, Text: tok.text}
+	case tokWord:
+		if tok.text == "NEAR" && p.peek().kind == tokLParen {
+			return p.parseNear()
+		}
+		node = &Node{Type: NodeTerm, Text: tok.text}
+	default:
+		err = fmt.Errorf("fts: unexpected %q at %d", tok.text, tok.pos)
+		return
+	}
+	if p.peek().kind == tokStar {
+		p.next()
+		node.Prefix = true
+	}
+	return
+}
+
+// NEAR(a b "c d", 5)
+func (p *parser) parseNear() (node *Node, err error) {
+	p.next()
+	node = &Node{Type: NodeNear, Distance: DefaultNearDistance}
+	for {
+		tok := p.next()
+		switch tok.kind {
+		case tokWord:
+			node.ChildNodes = append(node.ChildNodes, &Node{Type: NodeTerm, Text: tok.text})
+		case tokString:
+			node.ChildNodes = append(node.ChildNodes, &Node{Type: NodePhrase, Text: tok.text})
+		case tokStar:
+			if len(node.ChildNodes) == 0 {
+				err = fmt.Errorf("fts: unexpected * at %d", tok.pos)
+				return
+			}
+			node.ChildNodes[len(node.ChildNodes)-1].Prefix = true
+		case tokComma:
+			distance := p.next()
+			var n int
+			if n, err = strconv.Atoi(distance.text); err != nil || n < 0 {
+				err = fmt.Errorf("fts: invaild NEAR distance at %d", distance.pos)
+				return
+			}
+			node.Distance = n
+			if end := p.next(); end.kind != tokRParen {
+				err = fmt.Errorf("fts: expect ) at %d", end.pos)
+				return
+			}
+			return node, node.checkNear()
+		case tokRParen:
+			return node, node.checkNear()
+		default:
+			err = fmt.Errorf("fts: unexpected %q in NEAR at %d", tok.text, tok.pos)
+			return
+		}
+	}
+}
+
+func (n *Node) checkNear() error {
+	if len(n.ChildNodes) < 2 {
+		return fmt.Errorf("fts: NEAR needs at least two terms")
+	}
+	return nil
+}
+
+// 生成fts5的MATCH表达式，所有词都加引号，避免与fts5的关键字冲突
+// 词和短语先经过分词器切分，与建立索引时的分词保持一致
+func (n *Node) Match(tokenizer Tokenizer) (expr string, err error) {
+	switch n.Type {
+	case NodeTerm, NodePhrase:
+		tokens, prefix := tokenizer.TokenizeQuery(n.Text)
+		if len(tokens) == 0 {
+			err = fmt.Errorf("fts: no searchable term in %q", n.Text)
+			return
+		}
+		expr = quote(strings.Join(tokens, " "))
+		if n.Prefix || prefix {
+			expr += " *"
+		}
+	case NodeNear:
+		phraseList := []string{}
+		for _, child := range n.ChildNodes {
+			var childExpr string
+			if childExpr, err = child.Match(tokenizer); err != nil {
+				return
+			}
+			phraseList = append(phraseList, childExpr)
+		}
+		expr = fmt.Sprintf("NEAR(%s, %d)", strings.Join(phraseList, " "), n.Distance)
+	case NodeOr:
+		exprList := []string{}
+		for _, child := range n.ChildNodes {
+			if child.Type == NodeNot {
+				err = fmt.Errorf("fts: NOT must follow a positive term")
+				return
+			}
+			var childExpr string
+			if childExpr, err = child.Match(tokenizer); err != nil {
+				return
+			}
+			exprList = append(exprList, childExpr)
+		}
+		expr = fmt.Sprintf("(%s)", strings.Join(exprList, " OR "))
+	case NodeAnd:
+		// fts5的NOT是二元运算符，先拼接肯定的部分再逐个排除
+		positiveList := []string{}
+		negativeList := []string{}
+		for _, child := range n.ChildNodes {
+			var childExpr string
+			if child.Type == NodeNot {
+				if childExpr, err = child.ChildNodes[0].Match(tokenizer); err != nil {
+					return
+				}
+				negativeList = append(negativeList, childExpr)
+				continue
+			}
+			if childExpr, err = child.Match(tokenizer); err != nil {
+				return
+			}
+			positiveList = append(positiveList, childExpr)
+		}
+		if len(positiveList) == 0 {
+			err = fmt.Errorf("fts: NOT must follow a positive term")
+			return
+		}
+		expr = fmt.Sprintf("(%s)", strings.Join(positiveList, " AND "))
+		for _, negative := range negativeList {
+			expr = fmt.Sprintf("(%s NOT %s)", expr, negative)
+		}
+	case NodeNot:
+		err = fmt.Errorf("fts: NOT must follow a positive term")
+	default:
+		err = fmt.Errorf("fts: unknown node type %s", n.Type)
+	}
+	return
+}
+
+func quote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// 没有fts5时的退化实现，在column上做LIKE子串匹配
+// NEAR退化为同时包含
+func (n *Node) Like(column string) (stmt string, args []interface{}, err error) {
+	switch n.Type {
+	case NodeTerm, NodePhrase:
+		stmt = fmt.Sprintf(`%s LIKE ? ESCAPE '\'`, column)
+		args = []interface{}{"%" + escapeLike(n.Text) + "%"}
+	case NodeAnd, NodeOr, NodeNear:
+		connect := " AND "
+		if n.Type == NodeOr {
+			connect = " OR "
+		}
+		stmtList := []string{}
+		for _, child := range n.ChildNodes {
+			var childStmt string
+			var childArgs []interface{}
+			if childStmt, childArgs, err = child.Like(column); err != nil {
+				return
+			}
+			stmtList = append(stmtList, childStmt)
+			args = append(args, childArgs...)
+		}
+		stmt = fmt.Sprintf("(%s)", strings.Join(stmtList, connect))
+	case NodeNot:
+		var childStmt string
+		if childStmt, args, err = n.ChildNodes[0].Like(column); err != nil {
+			return
+		}
+		stmt = fmt.Sprintf("(NOT %s)", childStmt)
+	default:
+		err = fmt.Errorf("fts: unknown node type %s", n.Type)
+	}
+	return
+}
+
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `%`, `\%`)
+	s = strings.ReplaceAll(s, `_`, `\_`)
+	return s
+}
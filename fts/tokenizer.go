@@ -0,0 +1,180 @@
+package fts
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// 分词器，建立索引和解析搜索词时使用同一个分词器
+// 分词结果以空格连接后写入fts5索引表，因此词中只能包含字母和数字
+type Tokenizer interface {
+	// 建立索引时的分词
+	Tokenize(text string) []string
+	// 搜索词的分词，prefix表示最后一个词需要前缀匹配
+	TokenizeQuery(text string) (tokens []string, prefix bool)
+}
+
+const (
+	TokenizerUnicode    = "unicode"
+	TokenizerCJKBigram  = "cjk_bigram"
+	TokenizerCJKUnigram = "cjk_unigram"
+)
+
+// 表未指定分词器时使用
+const DefaultTokenizer = TokenizerCJKBigram
+
+var TokenizerMap = map[string]Tokenizer{}
+
+func init() {
+	RegisterTokenizer(TokenizerUnicode, &unicodeTokenizer{})
+
+	RegisterTokenizer(TokenizerCJKBigram, &cjkTokenizer{bigram: true})
+
+	RegisterTokenizer(TokenizerCJKUnigram, &cjkTokenizer{bigram: false})
+
+}
+
+func RegisterTokenizer(name string, tokenizer Tokenizer) (err error) {
+	if tokenizer == nil {
+		err = fmt.Errorf("fts: tokenizer is nil")
+		return
+	}
+	for _, r := range name {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			err = fmt.Errorf("fts: invaild tokenizer name %q", name)
+			return
+		}
+	}
+	TokenizerMap[name] = tokenizer
+	return
+}
+
+func GetTokenizer(name string) (tokenizer Tokenizer, err error) {
+	if name == "" {
+		name = DefaultTokenizer
+	}
+	tokenizer, ok := TokenizerMap[name]
+	if !ok {
+		err = fmt.Errorf("fts: unsupport tokenizer %s", name)
+	}
+	return
+}
+
+// 用于写入索引的文本
+func TokenizeText(tokenizer Tokenizer, text string) string {
+	return strings.Join(tokenizer.Tokenize(text), " ")
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.Is(unicode.Mn, r)
+}
+
+// 按字母数字切分单词，连续的中日韩文字视为一个词
+type unicodeTokenizer struct{}
+
+func (t *unicodeTokenizer) Tokenize(text string) []string {
+	return splitWords(text)
+}
+
+func (t *unicodeTokenizer) TokenizeQuery(text string) (tokens []string, prefix bool) {
+	return splitWords(text), false
+}
+
+func splitWords(text string) (words []string) {
+	words = []string{}
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !isWordRune(r) }) {
+		words = append(words, strings.ToLower(word))
+	}
+	return
+}
+
+// 中日韩文字按单字或双字切分，其他文字按单词切分
+//
+// 双字切分时每个字都产生一个词，连续文字的最后一个字单独成词，
+// 以保证索引中词的位置与文字一一对应，短语查询才能正确匹配：
+//
+//	中文搜索 => 中文 文搜 搜索 索
+type cjkTokenizer struct {
+	bigram bool
+}
+
+// 切分成单词和连续的中日韩文字
+func (t *cjkTokenizer) segment(text string, fn func(word []rune, cjk bool)) {
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if !isWordRune(r) {
+			i++
+			continue
+		}
+		cjk := isCJK(r)
+		start := i
+		for i < len(runes) && isWordRune(runes[i]) && isCJK(runes[i]) == cjk {
+			i++
+		}
+		fn(runes[start:i], cjk)
+	}
+}
+
+func (t *cjkTokenizer) Tokenize(text string) []string {
+	tokens := []string{}
+	t.segment(text, func(word []rune, cjk bool) {
+		if !cjk {
+			tokens = append(tokens, strings.ToLower(string(word)))
+			return
+		}
+		for i := range word {
+			end := i + 1
+			if t.bigram && i+1 < len(word) {
+				end = i + 2
+			}
+			tokens = append(tokens, string(word[i:end]))
+		}
+	})
+	return tokens
+}
+
+// 搜索词中连续文字的最后一个字不单独成词，否则无法匹配索引中更长的连续文字；
+// 但如果后面还有其他词，需要保留它来占位
+func (t *cjkTokenizer) TokenizeQuery(text string) (tokens []string, prefix bool) {
+	type segment struct {
+		word []rune
+		cjk  bool
+	}
+	segmentList := []segment{}
+	t.segment(text, func(word []rune, cjk bool) {
+		segmentList = append(segmentList, segment{word, cjk})
+	})
+	tokens = []string{}
+	for idx, seg := range segmentList {
+		last := idx == len(segmentList)-1
+		if !seg.cjk {
+			tokens = append(tokens, strings.ToLower(string(seg.word)))
+			continue
+		}
+		if !t.bigram {
+			for _, r := range seg.word {
+				tokens = append(tokens, string(r))
+			}
+			continue
+		}
+		if len(seg.word) == 1 {
+			tokens = append(tokens, string(seg.word))
+			// 单字可能是索引中某个双字词的第一个字
+			prefix = last
+			continue
+		}
+		for i := 0; i+1 < len(seg.word); i++ {
+			tokens = append(tokens, string(seg.word[i:i+2]))
+		}
+		if !last {
+			tokens = append(tokens, string(seg.word[len(seg.word)-1:]))
+		}
+	}
+	return
+}
@@ -1 +1 @@
-package paroket
+package paroket
@@ -1,548 +1,2806 @@
-package paroket_test
-
-import (
-	"bytes"
-	"context"
-	"database/sql"
-	"fmt"
-	"os"
-	"paroket"
-	"paroket/attribute"
-	"paroket/common"
-	"paroket/tx"
-	"paroket/utils"
-	"testing"
-
-	"github.com/stretchr/testify/assert"
-	"github.com/tidwall/pretty"
-)
-
-var testAttributeType = []common.AttributeType{
-	attribute.AttributeTypeText,
-	attribute.AttributeTypeNumber,
-	attribute.AttributeTypeLink,
-}
-
-func TestSqliteImpl(t *testing.T) {
-	// 创建testdata目录
-	err := os.MkdirAll("testdata", 0755)
-	assert.NoError(t, err)
-	ctx := context.Background()
-
-	// 为每个测试用例创建独立的数据库文件
-	dbPath := fmt.Sprintf("testdata/test_%s.db", t.Name())
-
-	// 初始化sqlite实例
-	sqlite := paroket.NewSqliteImpl()
-
-	// 确保数据库连接关闭并删除测试数据库
-	defer func() {
-		sqlite.Close(ctx)
-
-		// if err := os.Remove(dbPath); err != nil {
-		// 	t.Logf("Failed to remove database file: %v", err)
-		// } else {
-		// 	t.Log("Database file removed successfully")
-		// }
-	}()
-	// 测试数据库初始化
-	err = sqlite.Open(ctx, dbPath, nil)
-	assert.NoError(t, err)
-
-	// 在每个测试用例前清理数据库
-	cleanupDatabase := func() {
-		sqlite.Close(ctx)
-
-		if err = os.Remove(dbPath); err != nil {
-			t.Logf("Failed to remove database file: %v", err)
-		} else {
-			t.Log("Database file removed successfully")
-		}
-
-		if err = sqlite.Open(ctx, dbPath, nil); err != nil {
-			t.Logf("Failed to open database file: %v", err)
-		}
-	}
-
-	// 验证数据库表结构
-	t.Run("Test Database Schema", func(t *testing.T) {
-		cleanupDatabase()
-		tables := []string{
-			"tables", "table_views", "objects",
-			"attribute_classes", "table_to_attribute_classes",
-			"object_to_attribute_classes",
-		}
-		tx, err := sqlite.ReadTx(ctx)
-		defer tx.Commit()
-
-		for _, tableName := range tables {
-			var exists bool
-
-			assert.NoError(t, err)
-			err = tx.QueryRow(
-				"SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name=?)",
-				tableName,
-			).Scan(&exists)
-			assert.NoError(t, err)
-			assert.True(t, exists, "Table %s should exist", tableName)
-		}
-	})
-
-	// 测试对象操作
-	t.Run("Test Object Operations", func(t *testing.T) {
-		cleanupDatabase()
-		// 创建新对象
-		tx, err := sqlite.WriteTx(ctx)
-		defer tx.Commit()
-		assert.NoError(t, err)
-		obj, err := sqlite.CreateObject(ctx, tx)
-		assert.NoError(t, err)
-
-		// 验证对象是否存在于数据库
-		nobj, err := sqlite.OpenObject(ctx, tx, obj.ObjectId())
-		assert.NoError(t, err)
-		assert.Equal(t, obj.ObjectId(), nobj.ObjectId())
-
-		// 删除对象
-		err = sqlite.DeleteObject(ctx, tx, obj.ObjectId())
-		assert.NoError(t, err)
-
-		// 验证对象是否已删除
-		_, err = sqlite.OpenObject(ctx, tx, obj.ObjectId())
-		assert.Equal(t, sql.ErrNoRows, err)
-	})
-
-	// 测试属性类基本操作
-	t.Run("Test Attribute Class Operations", func(t *testing.T) {
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		acList := []common.AttributeClass{}
-		acIdMap := map[common.AttributeClassId]bool{}
-		// 创建新属性类
-		for _, acType := range testAttributeType {
-			ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
-			assert.NoError(t, err)
-			acList = append(acList, ac)
-			acIdMap[ac.ClassId()] = true
-
-			// 验证属性类更新表是否创建
-			metainfo, err := ac.GetMetaInfo(ctx, tx)
-			assert.NoError(t, err)
-			tableName, ok := metainfo["updated_table"].(string)
-			assert.True(t, ok)
-			assert.NoError(t, err)
-			var readName string
-			err = tx.QueryRow(
-				"SELECT name FROM sqlite_master WHERE type='table' AND name=?",
-				tableName,
-			).Scan(&readName)
-			assert.NoError(t, err)
-
-			// 更新属性类
-			newName := "new name"
-			ac.Set(ctx, tx, utils.JSONMap{
-				"name": newName,
-			})
-			nac, err := sqlite.OpenAttributeClass(ctx, tx, ac.ClassId())
-			assert.NoError(t, err)
-			assert.Equal(t, ac.Name(), nac.Name())
-
-		}
-		qAcList, err := sqlite.ListAttributeClass(ctx, tx)
-		assert.NoError(t, err)
-		qAcMap := map[common.AttributeClassId]bool{}
-		for _, qac := range qAcList {
-			qAcMap[qac.ClassId()] = true
-		}
-		for key := range acIdMap {
-			assert.True(t, qAcMap[key])
-		}
-
-		for _, ac := range acList {
-			var readName string
-			metainfo, err := ac.GetMetaInfo(ctx, tx)
-			assert.NoError(t, err)
-			tableName, ok := metainfo["updated_table"].(string)
-			assert.True(t, ok)
-			assert.NoError(t, err)
-			// 删除属性类
-			err = sqlite.DeleteAttributeClass(ctx, tx, ac.ClassId())
-			assert.NoError(t, err)
-
-			// 验证属性类表是否删除
-			assert.NoError(t, err)
-			err = tx.QueryRow(
-				"SELECT name FROM sqlite_master WHERE type='table' AND name=?",
-				tableName,
-			).Scan(&readName)
-			assert.Equal(t, sql.ErrNoRows, err)
-		}
-	})
-
-	// 测试表操作
-	t.Run("Test Table Operations", func(t *testing.T) {
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		// 创建新表
-		table, err := sqlite.CreateTable(ctx, tx)
-		assert.NoError(t, err)
-		// 验证表数据表是否创建
-		metainfo := table.MetaInfo()
-		tableKey, ok := metainfo["data_table"].(string)
-		assert.True(t, ok)
-		var tableName string
-		assert.NoError(t, err)
-		err = tx.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?",
-			tableKey).Scan(&tableName)
-		assert.NoError(t, err)
-
-		// 更新表
-		newName := "new name"
-		err = table.Set(ctx, tx, utils.JSONMap{
-			"name": newName,
-		})
-		assert.NoError(t, err)
-		assert.Equal(t, newName, table.Name())
-
-		// 删除表
-		err = sqlite.DeleteTable(ctx, tx, table.TableId())
-		assert.NoError(t, err)
-
-		// 验证表数据表是否删除
-		err = tx.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?",
-			tableKey).Scan(&tableName)
-		assert.Equal(t, sql.ErrNoRows, err)
-	})
-
-	// 测试文本属性与对象联合操作
-	t.Run("Test Object TextAttributeClass Operations", func(t *testing.T) {
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		// 创建新对象
-		obj, err := sqlite.CreateObject(ctx, tx)
-		assert.NoError(t, err)
-
-		// 创建新属性类
-		ac, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
-		assert.NoError(t, err)
-
-		// 给对象添加属性
-		attr, err := ac.Insert(ctx, tx, obj.ObjectId())
-		assert.NoError(t, err)
-		assert.Equal(t, attr.GetClass(), ac)
-
-		//设置属性
-		newValue := "name"
-		err = attr.SetValue(map[string]interface{}{"value": newValue})
-		assert.NoError(t, err)
-		err = ac.Update(ctx, tx, obj.ObjectId(), attr)
-		assert.NoError(t, err)
-
-		// 验证设置的属性
-		nAttr, err := ac.FindId(ctx, tx, obj.ObjectId())
-		assert.NoError(t, err)
-		assert.Equal(t, newValue, nAttr.String())
-
-		assert.Equal(t, nAttr.GetClass(), ac)
-
-		// 删除属性
-		err = ac.Delete(ctx, tx, obj.ObjectId())
-		assert.NoError(t, err)
-
-		// 验证是否删除
-		_, err = ac.FindId(ctx, tx, obj.ObjectId())
-		assert.Equal(t, sql.ErrNoRows, err)
-
-	})
-
-	// 测试对象与表联合操作
-	t.Run("Test table Objects Constraints", func(t *testing.T) {
-
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		var objNum = 20
-		// 创建新对象列表
-		objIdList := []common.ObjectId{}
-		objIdMap := map[common.ObjectId]bool{}
-		for i := 0; i < objNum; i++ {
-			obj, err := sqlite.CreateObject(ctx, tx)
-			assert.NoError(t, err)
-
-			objIdList = append(objIdList, obj.ObjectId())
-			objIdMap[obj.ObjectId()] = true
-		}
-
-		// 创建新表
-		table, err := sqlite.CreateTable(ctx, tx)
-		assert.NoError(t, err)
-
-		err = table.Insert(ctx, tx, objIdList...)
-		assert.NoError(t, err)
-
-		newObjList, err := table.FindId(ctx, tx, objIdList...)
-		assert.NoError(t, err)
-
-		newObjIdMap := map[common.ObjectId]bool{}
-		for _, nobj := range newObjList {
-			assert.True(t, objIdMap[nobj.ObjectId()])
-			newObjIdMap[nobj.ObjectId()] = true
-		}
-		for objId := range objIdMap {
-			assert.True(t, newObjIdMap[objId])
-
-		}
-		// 测试删除
-		err = table.Delete(ctx, tx, objIdList...)
-		assert.NoError(t, err)
-		delObjList, err := table.FindId(ctx, tx, objIdList...)
-		assert.NoError(t, err)
-		assert.Equal(t, len(delObjList), 0)
-
-	})
-
-	// 测试属性类与表的关联操作
-	t.Run("Test Attribute Class Table Operations", func(t *testing.T) {
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		var objNum = 20
-		var acNum = 4
-		// 创建新对象列表
-		objIdList := []common.ObjectId{}
-		objIdMap := map[common.ObjectId]bool{}
-		for i := 0; i < objNum; i++ {
-			obj, err := sqlite.CreateObject(ctx, tx)
-			assert.NoError(t, err)
-
-			objIdList = append(objIdList, obj.ObjectId())
-			objIdMap[obj.ObjectId()] = true
-		}
-
-		// 创建属性类列表
-		acList := []common.AttributeClass{}
-		for _, acType := range testAttributeType {
-			for i := 1; i < acNum; i++ {
-				ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
-				assert.NoError(t, err)
-				acList = append(acList, ac)
-
-			}
-		}
-		table, err := sqlite.CreateTable(ctx, tx)
-		assert.NoError(t, err)
-
-		//添加属性到对象
-		for _, oid := range objIdList {
-			for _, ac := range acList {
-				_, err = ac.Insert(ctx, tx, oid)
-				assert.NoError(t, err)
-
-			}
-		}
-		//添加属性到表
-		for _, ac := range acList {
-			err = table.AddAttributeClass(ctx, tx, ac)
-			assert.NoError(t, err)
-		}
-
-		//插入对象到表
-		table.Insert(ctx, tx, objIdList...)
-
-		for idx, ac := range acList {
-			if idx%2 == 0 {
-				continue
-			}
-			err = table.DeleteAttributeClass(ctx, tx, ac)
-			assert.NoError(t, err)
-		}
-		for idx, oid := range objIdList {
-			if idx%2 == 0 {
-				continue
-			}
-			table.Delete(ctx, tx, oid)
-		}
-	})
-
-	// 测试视图查询
-	t.Run("Test Table view Operations", func(t *testing.T) {
-		cleanupDatabase()
-		tx, err := sqlite.WriteTx(ctx)
-		assert.NoError(t, err)
-		defer tx.Commit()
-		var objNum = 20
-		var acNum = 4
-		// 创建新对象列表
-		objIdList := []common.ObjectId{}
-		objIdMap := map[common.ObjectId]bool{}
-		for i := 0; i < objNum; i++ {
-			obj, err := sqlite.CreateObject(ctx, tx)
-			assert.NoError(t, err)
-
-			objIdList = append(objIdList, obj.ObjectId())
-			objIdMap[obj.ObjectId()] = true
-		}
-
-		// 创建属性类列表
-		acList := []common.AttributeClass{}
-		for _, acType := range testAttributeType {
-			for i := 0; i < acNum; i++ {
-				ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
-				assert.NoError(t, err)
-				err = SetAC(t, ctx, tx, ac, i)
-				assert.NoError(t, err)
-
-				acList = append(acList, ac)
-
-			}
-		}
-		table, err := sqlite.CreateTable(ctx, tx)
-		assert.NoError(t, err)
-
-		//添加属性到对象
-		for i, oid := range objIdList {
-			for j, ac := range acList {
-				err = SetValue(t, ctx, tx, ac, oid, i, j)
-				assert.NoError(t, err)
-
-			}
-		}
-		//添加属性到表
-		for _, ac := range acList {
-			err = table.AddAttributeClass(ctx, tx, ac)
-			assert.NoError(t, err)
-		}
-
-		//插入对象到表
-		table.Insert(ctx, tx, objIdList...)
-
-		//新建视图
-		view, err := table.NewView(ctx, tx)
-		assert.NoError(t, err)
-
-		filter := fmt.Sprintf(`
-		{
-			"$or":[
-				{
-				"$not":[
-						{"$fts":{
-							"search":"测试_1_1"
-							}
-						}
-					]
-				},
-				{"$fts":{
-							"search":"测试 5"
-						}
-				},
-				{"%v":{
-					"like":"8"
-					}
-				}
-			]
-		}`, acList[0].ClassId())
-		err = view.Filter(tx, filter)
-		assert.NoError(t, err)
-
-		order := fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, acList[0].ClassId())
-		view.SortBy(tx, order)
-		assert.NoError(t, err)
-
-		result, err := view.Query(ctx, tx)
-		assert.NoError(t, err)
-		resultStr, err := result.Marshal(ctx, tx)
-		assert.NoError(t, err)
-
-		assert.NotEqual(t, 0, len(result.Raw()))
-		fmt.Println(string(pretty.Pretty([]byte(resultStr))))
-		fmt.Println("测试")
-	})
-}
-
-func SetAC(t *testing.T, ctx context.Context, tx tx.WriteTx, ac common.AttributeClass, i int) (err error) {
-
-	switch ac.Type() {
-	case attribute.AttributeTypeText:
-		newName := fmt.Sprintf("text_%d", i)
-		err = ac.Set(ctx, tx, map[string]interface{}{
-			"name": newName,
-		})
-		assert.NoError(t, err)
-	case attribute.AttributeTypeNumber:
-		newName := fmt.Sprintf("number_%d", i)
-		err = ac.Set(ctx, tx, map[string]interface{}{
-			"name": newName,
-		})
-		assert.NoError(t, err)
-	case attribute.AttributeTypeLink:
-		queryAcid := `SELECT class_id FROM attribute_classes WHERE attribute_type != 'link' limit 3`
-		rows, err := tx.Query(queryAcid)
-		assert.NoError(t, err)
-		buf := &bytes.Buffer{}
-		buf.WriteString("[")
-		idx := 0
-		for rows.Next() {
-			if idx != 0 {
-				buf.WriteString(",")
-			}
-			idx++
-			var acid common.AttributeClassId
-			rows.Scan(&acid)
-			buf.WriteString(fmt.Sprintf(`"%v"`, acid))
-		}
-		buf.WriteString("]")
-
-		nvalue := buf.String()
-		newName := fmt.Sprintf("link_%d", i)
-		err = ac.Set(ctx, tx, map[string]interface{}{
-			"dep_attribute": nvalue,
-			"name":          newName,
-		})
-		assert.NoError(t, err)
-	default:
-		err = fmt.Errorf("unsupport type")
-		return
-	}
-
-	assert.NoError(t, err)
-	return
-}
-
-func SetValue(t *testing.T, ctx context.Context, tx tx.WriteTx, ac common.AttributeClass, oid common.ObjectId, i, j int) (err error) {
-	attr, err := ac.Insert(ctx, tx, oid)
-	assert.NoError(t, err)
-	switch ac.Type() {
-	case attribute.AttributeTypeText:
-		nvalue := fmt.Sprintf("测试_%d_%d", j, i)
-		err = attr.SetValue(map[string]interface{}{
-			"value": nvalue,
-		})
-	case attribute.AttributeTypeNumber:
-		nvalue := 1000*j + i
-		err = attr.SetValue(map[string]interface{}{
-			"value": nvalue,
-		})
-	case attribute.AttributeTypeLink:
-		nvalue := fmt.Sprintf(`["%v"]`, oid)
-		err = attr.SetValue(map[string]interface{}{
-			"update": nvalue,
-			"ctx":    ctx,
-			"tx":     tx,
-		})
-	default:
-		err = fmt.Errorf("unsupport type")
-		return
-	}
-	assert.NoError(t, err)
-	err = ac.Update(ctx, tx, oid, attr)
-	assert.NoError(t, err)
-	return
-}
+package paroket_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"paroket"
+	"paroket/attribute"
+	"paroket/common"
+	"paroket/query"
+	"paroket/tx"
+	"paroket/utils"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/pretty"
+)
+
+var testAttributeType = []common.AttributeType{
+	attribute.AttributeTypeText,
+	attribute.AttributeTypeNumber,
+	attribute.AttributeTypeLink,
+}
+
+func TestSqliteImpl(t *testing.T) {
+	// 创建testdata目录
+	err := os.MkdirAll("testdata", 0755)
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	// 为每个测试用例创建独立的数据库文件
+	dbPath := fmt.Sprintf("testdata/test_%s.db", t.Name())
+
+	// 初始化sqlite实例
+	sqlite := paroket.NewSqliteImpl()
+
+	// 确保数据库连接关闭并删除测试数据库
+	defer func() {
+		sqlite.Close(ctx)
+
+		// if err := os.Remove(dbPath); err != nil {
+		// 	t.Logf("Failed to remove database file: %v", err)
+		// } else {
+		// 	t.Log("Database file removed successfully")
+		// }
+	}()
+	// 测试数据库初始化
+	err = sqlite.Open(ctx, dbPath, nil)
+	assert.NoError(t, err)
+
+	// 在每个测试用例前清理数据库
+	cleanupDatabase := func() {
+		sqlite.Close(ctx)
+
+		if err = os.Remove(dbPath); err != nil {
+			t.Logf("Failed to remove database file: %v", err)
+		} else {
+			t.Log("Database file removed successfully")
+		}
+
+		if err = sqlite.Open(ctx, dbPath, nil); err != nil {
+			t.Logf("Failed to open database file: %v", err)
+		}
+	}
+
+	// 验证数据库表结构
+	t.Run("Test Database Schema", func(t *testing.T) {
+		cleanupDatabase()
+		tables := []string{
+			"tables", "table_views", "objects",
+			"attribute_classes", "table_to_attribute_classes",
+			"object_to_attribute_classes",
+		}
+		tx, err := sqlite.ReadTx(ctx)
+		defer tx.Commit()
+
+		for _, tableName := range tables {
+			var exists bool
+
+			assert.NoError(t, err)
+			err = tx.QueryRow(
+				"SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name=?)",
+				tableName,
+			).Scan(&exists)
+			assert.NoError(t, err)
+			assert.True(t, exists, "Table %s should exist", tableName)
+		}
+	})
+
+	// 测试对象操作
+	t.Run("Test Object Operations", func(t *testing.T) {
+		cleanupDatabase()
+		// 创建新对象
+		tx, err := sqlite.WriteTx(ctx)
+		defer tx.Commit()
+		assert.NoError(t, err)
+		obj, err := sqlite.CreateObject(ctx, tx)
+		assert.NoError(t, err)
+
+		// 验证对象是否存在于数据库
+		nobj, err := sqlite.OpenObject(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, obj.ObjectId(), nobj.ObjectId())
+
+		// 删除对象
+		err = sqlite.DeleteObject(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+
+		// 验证对象是否已删除
+		_, err = sqlite.OpenObject(ctx, tx, obj.ObjectId())
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	// 测试属性类基本操作
+	t.Run("Test Attribute Class Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		acList := []common.AttributeClass{}
+		acIdMap := map[common.AttributeClassId]bool{}
+		// 创建新属性类
+		for _, acType := range testAttributeType {
+			ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
+			assert.NoError(t, err)
+			acList = append(acList, ac)
+			acIdMap[ac.ClassId()] = true
+
+			// 验证属性类更新表是否创建
+			metainfo, err := ac.GetMetaInfo(ctx, tx)
+			assert.NoError(t, err)
+			tableName, ok := metainfo["updated_table"].(string)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			var readName string
+			err = tx.QueryRow(
+				"SELECT name FROM sqlite_master WHERE type='table' AND name=?",
+				tableName,
+			).Scan(&readName)
+			assert.NoError(t, err)
+
+			// 更新属性类
+			newName := "new name"
+			ac.Set(ctx, tx, utils.JSONMap{
+				"name": newName,
+			})
+			nac, err := sqlite.OpenAttributeClass(ctx, tx, ac.ClassId())
+			assert.NoError(t, err)
+			assert.Equal(t, ac.Name(), nac.Name())
+
+		}
+		qAcList, err := sqlite.ListAttributeClass(ctx, tx)
+		assert.NoError(t, err)
+		qAcMap := map[common.AttributeClassId]bool{}
+		for _, qac := range qAcList {
+			qAcMap[qac.ClassId()] = true
+		}
+		for key := range acIdMap {
+			assert.True(t, qAcMap[key])
+		}
+
+		for _, ac := range acList {
+			var readName string
+			metainfo, err := ac.GetMetaInfo(ctx, tx)
+			assert.NoError(t, err)
+			tableName, ok := metainfo["updated_table"].(string)
+			assert.True(t, ok)
+			assert.NoError(t, err)
+			// 删除属性类
+			err = sqlite.DeleteAttributeClass(ctx, tx, ac.ClassId())
+			assert.NoError(t, err)
+
+			// 验证属性类表是否删除
+			assert.NoError(t, err)
+			err = tx.QueryRow(
+				"SELECT name FROM sqlite_master WHERE type='table' AND name=?",
+				tableName,
+			).Scan(&readName)
+			assert.Equal(t, sql.ErrNoRows, err)
+		}
+	})
+
+	// 测试表操作
+	t.Run("Test Table Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		// 创建新表
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		// 验证表数据表是否创建
+		metainfo := table.MetaInfo()
+		tableKey, ok := metainfo["data_table"].(string)
+		assert.True(t, ok)
+		var tableName string
+		assert.NoError(t, err)
+		err = tx.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?",
+			tableKey).Scan(&tableName)
+		assert.NoError(t, err)
+
+		// 更新表
+		newName := "new name"
+		err = table.Set(ctx, tx, utils.JSONMap{
+			"name": newName,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, newName, table.Name())
+
+		// 删除表
+		err = sqlite.DeleteTable(ctx, tx, table.TableId())
+		assert.NoError(t, err)
+
+		// 验证表数据表是否删除
+		err = tx.QueryRow("SELECT name FROM sqlite_master WHERE type='table' AND name=?",
+			tableKey).Scan(&tableName)
+		assert.Equal(t, sql.ErrNoRows, err)
+	})
+
+	// 测试文本属性与对象联合操作
+	t.Run("Test Object TextAttributeClass Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		// 创建新对象
+		obj, err := sqlite.CreateObject(ctx, tx)
+		assert.NoError(t, err)
+
+		// 创建新属性类
+		ac, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+
+		// 给对象添加属性
+		attr, err := ac.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, attr.GetClass(), ac)
+
+		//设置属性
+		newValue := "name"
+		err = attr.SetValue(map[string]interface{}{"value": newValue})
+		assert.NoError(t, err)
+		err = ac.Update(ctx, tx, obj.ObjectId(), attr)
+		assert.NoError(t, err)
+
+		// 验证设置的属性
+		nAttr, err := ac.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, newValue, nAttr.String())
+
+		assert.Equal(t, nAttr.GetClass(), ac)
+
+		// 删除属性
+		err = ac.Delete(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+
+		// 验证是否删除
+		_, err = ac.FindId(ctx, tx, obj.ObjectId())
+		assert.Equal(t, sql.ErrNoRows, err)
+
+	})
+
+	// 测试对象与表联合操作
+	t.Run("Test table Objects Constraints", func(t *testing.T) {
+
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		var objNum = 20
+		// 创建新对象列表
+		objIdList := []common.ObjectId{}
+		objIdMap := map[common.ObjectId]bool{}
+		for i := 0; i < objNum; i++ {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+
+			objIdList = append(objIdList, obj.ObjectId())
+			objIdMap[obj.ObjectId()] = true
+		}
+
+		// 创建新表
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+
+		err = table.Insert(ctx, tx, objIdList...)
+		assert.NoError(t, err)
+
+		newObjList, err := table.FindId(ctx, tx, objIdList...)
+		assert.NoError(t, err)
+
+		newObjIdMap := map[common.ObjectId]bool{}
+		for _, nobj := range newObjList {
+			assert.True(t, objIdMap[nobj.ObjectId()])
+			newObjIdMap[nobj.ObjectId()] = true
+		}
+		for objId := range objIdMap {
+			assert.True(t, newObjIdMap[objId])
+
+		}
+		// 测试删除
+		err = table.Delete(ctx, tx, objIdList...)
+		assert.NoError(t, err)
+		delObjList, err := table.FindId(ctx, tx, objIdList...)
+		assert.NoError(t, err)
+		assert.Equal(t, len(delObjList), 0)
+
+	})
+
+	// 测试属性类与表的关联操作
+	t.Run("Test Attribute Class Table Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		var objNum = 20
+		var acNum = 4
+		// 创建新对象列表
+		objIdList := []common.ObjectId{}
+		objIdMap := map[common.ObjectId]bool{}
+		for i := 0; i < objNum; i++ {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+
+			objIdList = append(objIdList, obj.ObjectId())
+			objIdMap[obj.ObjectId()] = true
+		}
+
+		// 创建属性类列表
+		acList := []common.AttributeClass{}
+		for _, acType := range testAttributeType {
+			for i := 1; i < acNum; i++ {
+				ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
+				assert.NoError(t, err)
+				acList = append(acList, ac)
+
+			}
+		}
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+
+		//添加属性到对象
+		for _, oid := range objIdList {
+			for _, ac := range acList {
+				_, err = ac.Insert(ctx, tx, oid)
+				assert.NoError(t, err)
+
+			}
+		}
+		//添加属性到表
+		for _, ac := range acList {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+
+		//插入对象到表
+		table.Insert(ctx, tx, objIdList...)
+
+		for idx, ac := range acList {
+			if idx%2 == 0 {
+				continue
+			}
+			err = table.DeleteAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+		for idx, oid := range objIdList {
+			if idx%2 == 0 {
+				continue
+			}
+			table.Delete(ctx, tx, oid)
+		}
+	})
+
+	// 测试视图查询
+	t.Run("Test Table view Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+		var objNum = 20
+		var acNum = 4
+		// 创建新对象列表
+		objIdList := []common.ObjectId{}
+		objIdMap := map[common.ObjectId]bool{}
+		for i := 0; i < objNum; i++ {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+
+			objIdList = append(objIdList, obj.ObjectId())
+			objIdMap[obj.ObjectId()] = true
+		}
+
+		// 创建属性类列表
+		acList := []common.AttributeClass{}
+		for _, acType := range testAttributeType {
+			for i := 0; i < acNum; i++ {
+				ac, err := sqlite.CreateAttributeClass(ctx, tx, acType)
+				assert.NoError(t, err)
+				err = SetAC(t, ctx, tx, ac, i)
+				assert.NoError(t, err)
+
+				acList = append(acList, ac)
+
+			}
+		}
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+
+		//添加属性到对象
+		for i, oid := range objIdList {
+			for j, ac := range acList {
+				err = SetValue(t, ctx, tx, ac, oid, i, j)
+				assert.NoError(t, err)
+
+			}
+		}
+		//添加属性到表
+		for _, ac := range acList {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+
+		//插入对象到表
+		table.Insert(ctx, tx, objIdList...)
+
+		//新建视图
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+
+		filter := fmt.Sprintf(`
+		{
+			"$or":[
+				{
+				"$not":[
+						{"$fts":{
+							"search":"测试_1_1"
+							}
+						}
+					]
+				},
+				{"$fts":{
+							"search":"测试 5"
+						}
+				},
+				{"%v":{
+					"like":"8"
+					}
+				}
+			]
+		}`, acList[0].ClassId())
+		err = view.Filter(tx, filter)
+		assert.NoError(t, err)
+
+		order := fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, acList[0].ClassId())
+		view.SortBy(tx, order)
+		assert.NoError(t, err)
+
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		resultStr, err := result.Marshal(ctx, tx)
+		assert.NoError(t, err)
+
+		assert.NotEqual(t, 0, len(result.Raw()))
+		fmt.Println(string(pretty.Pretty([]byte(resultStr))))
+		fmt.Println("测试")
+	})
+
+	// 测试公式属性
+	t.Run("Test Formula Attribute Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		obj, err := sqlite.CreateObject(ctx, tx)
+		assert.NoError(t, err)
+		numAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		err = textAc.Set(ctx, tx, utils.JSONMap{"key": "front"})
+		assert.NoError(t, err)
+
+		formulaAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeFormula)
+		assert.NoError(t, err)
+		expression := fmt.Sprintf(
+			`if(prop("%v") > 10, concat(prop("front"), "-", prop("%v") * 2), "small")`,
+			numAc.ClassId(), numAc.ClassId(),
+		)
+		err = formulaAc.Set(ctx, tx, utils.JSONMap{"expression": expression})
+		assert.NoError(t, err)
+
+		_, err = formulaAc.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		attr, err := formulaAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "small", attr.String())
+
+		// 依赖属性变化后公式自动更新
+		textAttr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		err = textAttr.SetValue(map[string]interface{}{"value": "card"})
+		assert.NoError(t, err)
+		err = textAc.Update(ctx, tx, obj.ObjectId(), textAttr)
+		assert.NoError(t, err)
+		numAttr, err := numAc.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		err = numAttr.SetValue(map[string]interface{}{"value": 21})
+		assert.NoError(t, err)
+		err = numAc.Update(ctx, tx, obj.ObjectId(), numAttr)
+		assert.NoError(t, err)
+
+		attr, err = formulaAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "card-42", attr.String())
+
+		// 公式之间的依赖
+		dateAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeFormula)
+		assert.NoError(t, err)
+		err = dateAc.Set(ctx, tx, utils.JSONMap{
+			"expression": fmt.Sprintf(`date_diff(date_add("2024-01-01", len(prop("%v")), "days"), "2024-01-01", "days")`, formulaAc.ClassId()),
+		})
+		assert.NoError(t, err)
+		_, err = dateAc.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		attr, err = dateAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "7", attr.String())
+
+		// 拒绝循环依赖
+		err = formulaAc.Set(ctx, tx, utils.JSONMap{
+			"expression": fmt.Sprintf(`prop("%v") + 1`, dateAc.ClassId()),
+		})
+		assert.ErrorIs(t, err, common.ErrAttributeClassDependCycle)
+		err = formulaAc.Set(ctx, tx, utils.JSONMap{
+			"expression": fmt.Sprintf(`prop("%v")`, formulaAc.ClassId()),
+		})
+		assert.ErrorIs(t, err, common.ErrAttributeClassDependCycle)
+
+		// 计算出错时写入null，不返回错误，依赖变化后可以恢复
+		badAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeFormula)
+		assert.NoError(t, err)
+		err = badAc.Set(ctx, tx, utils.JSONMap{"expression": `to_number(prop("front"))`})
+		assert.NoError(t, err)
+		_, err = badAc.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		attr, err = badAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "", attr.String())
+		err = textAttr.SetValue(map[string]interface{}{"value": "12"})
+		assert.NoError(t, err)
+		err = textAc.Update(ctx, tx, obj.ObjectId(), textAttr)
+		assert.NoError(t, err)
+		attr, err = badAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "12", attr.String())
+		err = textAttr.SetValue(map[string]interface{}{"value": "card"})
+		assert.NoError(t, err)
+		err = badAc.Update(ctx, tx, obj.ObjectId(), nil)
+		assert.NoError(t, err)
+		err = textAc.Update(ctx, tx, obj.ObjectId(), textAttr)
+		assert.NoError(t, err)
+		attr, err = badAc.FindId(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "", attr.String())
+
+		// 公式可以像普通属性一样筛选
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, formulaAc)
+		assert.NoError(t, err)
+		err = table.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Filter(tx, fmt.Sprintf(`{"%v":{"eq":"card-42"}}`, formulaAc.ClassId()))
+		assert.NoError(t, err)
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(result.Raw()))
+	})
+
+	t.Run("Test Rollup Attribute Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		project, err := sqlite.CreateObject(ctx, tx)
+		assert.NoError(t, err)
+		numAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		linkAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeLink)
+		assert.NoError(t, err)
+
+		taskList := []common.ObjectId{}
+		for i := 1; i <= 3; i++ {
+			task, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			numAttr, err := numAc.Insert(ctx, tx, task.ObjectId())
+			assert.NoError(t, err)
+			err = numAttr.SetValue(map[string]interface{}{"value": i * 2})
+			assert.NoError(t, err)
+			err = numAc.Update(ctx, tx, task.ObjectId(), numAttr)
+			assert.NoError(t, err)
+			taskList = append(taskList, task.ObjectId())
+		}
+
+		rollupAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeRollup)
+		assert.NoError(t, err)
+		err = rollupAc.Set(ctx, tx, utils.JSONMap{
+			"link_attribute":   linkAc.ClassId().String(),
+			"target_attribute": numAc.ClassId().String(),
+			"aggregation":      attribute.RollupSum,
+		})
+		assert.NoError(t, err)
+		_, err = rollupAc.Insert(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		attr, err := rollupAc.FindId(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "0", attr.String())
+
+		// link变化后自动重新汇总
+		linkAttr, err := linkAc.Insert(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		err = linkAttr.SetValue(map[string]interface{}{
+			"update": fmt.Sprintf(`["%v","%v"]`, taskList[0], taskList[1]),
+			"ctx":    ctx,
+			"tx":     tx,
+		})
+		assert.NoError(t, err)
+		err = linkAc.Update(ctx, tx, project.ObjectId(), linkAttr)
+		assert.NoError(t, err)
+		attr, err = rollupAc.FindId(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "6", attr.String())
+
+		// 被关联对象的值变化后自动重新汇总
+		numAttr, err := numAc.FindId(ctx, tx, taskList[1])
+		assert.NoError(t, err)
+		err = numAttr.SetValue(map[string]interface{}{"value": 10})
+		assert.NoError(t, err)
+		err = numAc.Update(ctx, tx, taskList[1], numAttr)
+		assert.NoError(t, err)
+		attr, err = rollupAc.FindId(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "12", attr.String())
+
+		aggregationList := map[string]string{
+			attribute.RollupCount:        "2",
+			attribute.RollupAvg:          "6",
+			attribute.RollupMin:          "2",
+			attribute.RollupMax:          "10",
+			attribute.RollupStd:          "4",
+			attribute.RollupConcatUnique: "2, 10",
+		}
+		for aggregation, expect := range aggregationList {
+			err = rollupAc.Set(ctx, tx, utils.JSONMap{"aggregation": aggregation})
+			assert.NoError(t, err)
+			attr, err = rollupAc.FindId(ctx, tx, project.ObjectId())
+			assert.NoError(t, err)
+			assert.Equal(t, expect, attr.String(), aggregation)
+		}
+
+		// 删除被关联的对象后自动重新汇总
+		err = rollupAc.Set(ctx, tx, utils.JSONMap{"aggregation": attribute.RollupCount})
+		assert.NoError(t, err)
+		err = sqlite.DeleteObject(ctx, tx, taskList[0])
+		assert.NoError(t, err)
+		attr, err = rollupAc.FindId(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "1", attr.String())
+		err = rollupAc.Set(ctx, tx, utils.JSONMap{"aggregation": attribute.RollupSum})
+		assert.NoError(t, err)
+		attr, err = rollupAc.FindId(ctx, tx, project.ObjectId())
+		assert.NoError(t, err)
+		assert.Equal(t, "10", attr.String())
+
+		err = rollupAc.Set(ctx, tx, utils.JSONMap{"aggregation": "median"})
+		assert.Error(t, err)
+		err = rollupAc.Set(ctx, tx, utils.JSONMap{"link_attribute": numAc.ClassId().String()})
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Select Attribute Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		doneAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeCheckbox)
+		assert.NoError(t, err)
+		statusAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeSelect)
+		assert.NoError(t, err)
+		err = statusAc.Set(ctx, tx, utils.JSONMap{
+			"options": `[{"name":"todo","color":"gray"},{"name":"doing","color":"blue"},{"name":"done","color":"green"}]`,
+		})
+		assert.NoError(t, err)
+		err = statusAc.Set(ctx, tx, utils.JSONMap{"options": `[{"name":"todo","color":"black"}]`})
+		assert.Error(t, err)
+		tagAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeMultiSelect)
+		assert.NoError(t, err)
+		err = tagAc.Set(ctx, tx, utils.JSONMap{
+			"options": `[{"name":"go"},{"name":"sqlite"},{"name":"json"}]`,
+		})
+		assert.NoError(t, err)
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{doneAc, statusAc, tagAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+
+		// 名称按字母顺序与选项顺序不同，用于检查排序
+		dataList := []struct {
+			done   bool
+			status string
+			tags   []interface{}
+		}{
+			{false, "todo", []interface{}{"json"}},
+			{true, "done", []interface{}{"go", "sqlite"}},
+			{false, "doing", []interface{}{"sqlite"}},
+			{true, "", []interface{}{}},
+		}
+		oidList := []common.ObjectId{}
+		for _, data := range dataList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+
+			doneAttr, err := doneAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = doneAttr.SetValue(map[string]interface{}{"value": data.done})
+			assert.NoError(t, err)
+			err = doneAc.Update(ctx, tx, obj.ObjectId(), doneAttr)
+			assert.NoError(t, err)
+
+			statusAttr, err := statusAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = statusAttr.SetValue(map[string]interface{}{"value": data.status})
+			assert.NoError(t, err)
+			err = statusAc.Update(ctx, tx, obj.ObjectId(), statusAttr)
+			assert.NoError(t, err)
+
+			tagAttr, err := tagAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = tagAttr.SetValue(map[string]interface{}{"value": data.tags})
+			assert.NoError(t, err)
+			err = tagAc.Update(ctx, tx, obj.ObjectId(), tagAttr)
+			assert.NoError(t, err)
+
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		attr, err := tagAc.FindId(ctx, tx, oidList[1])
+		assert.NoError(t, err)
+		assert.Equal(t, "go, sqlite", attr.String())
+		attr, err = statusAc.FindId(ctx, tx, oidList[2])
+		assert.NoError(t, err)
+		assert.Equal(t, "doing", attr.String())
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		queryIdList := func(filter string, order string) (idList []common.ObjectId) {
+			err := view.Filter(tx, filter)
+			assert.NoError(t, err)
+			err = view.SortBy(tx, order)
+			assert.NoError(t, err)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+
+		filterList := []struct {
+			filter string
+			expect []common.ObjectId
+		}{
+			{fmt.Sprintf(`{"%v":{"is":true}}`, doneAc.ClassId()), []common.ObjectId{oidList[1], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"is_empty":true}}`, doneAc.ClassId()), []common.ObjectId{oidList[0], oidList[2]}},
+			{fmt.Sprintf(`{"%v":{"is":"done"}}`, statusAc.ClassId()), []common.ObjectId{oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"is_not":"done"}}`, statusAc.ClassId()), []common.ObjectId{oidList[0], oidList[2], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"contains_any":["todo","doing"]}}`, statusAc.ClassId()), []common.ObjectId{oidList[0], oidList[2]}},
+			{fmt.Sprintf(`{"%v":{"is_empty":true}}`, statusAc.ClassId()), []common.ObjectId{oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"is":"sqlite"}}`, tagAc.ClassId()), []common.ObjectId{oidList[2], oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"contains_any":["go","json"]}}`, tagAc.ClassId()), []common.ObjectId{oidList[0], oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"contains_all":["go","sqlite"]}}`, tagAc.ClassId()), []common.ObjectId{oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"is_empty":true}}`, tagAc.ClassId()), []common.ObjectId{oidList[3]}},
+		}
+		for _, item := range filterList {
+			order := fmt.Sprintf(`[{"field":"%v","mode":"asc","nulls":"last"}]`, statusAc.ClassId())
+			assert.Equal(t, item.expect, queryIdList(item.filter, order), item.filter)
+		}
+
+		// is和is_not只接受一个选项，contains_all为空列表时总是满足
+		for ac, options := range map[common.AttributeClass][]string{statusAc: {"todo", "doing"}, tagAc: {"go", "sqlite"}} {
+			for _, value := range []interface{}{[]string{}, options} {
+				_, err = table.Query().Where(query.Op(ac, "is", value)).Find(ctx, tx)
+				assert.Error(t, err)
+				_, err = table.Query().Where(query.Op(ac, "is_not", value)).Find(ctx, tx)
+				assert.Error(t, err)
+			}
+			// 校验拒绝空列表，属性自身构建查询时也不会生成无效的sql
+			_, err = table.Query().Where(query.Op(ac, "contains_all", []string{})).Find(ctx, tx)
+			assert.Error(t, err)
+			stmt, _, err := ac.BuildQuery(ctx, tx, map[string]interface{}{"op": "contains_all", "value": []interface{}{}})
+			assert.NoError(t, err)
+			assert.Equal(t, "(1)", stmt)
+		}
+
+		// 按选项顺序排序，而不是按名称，空值与其他属性一致默认升序在前
+		order := fmt.Sprintf(`[{"field":"%v","mode":"asc","nulls":"last"}]`, statusAc.ClassId())
+		assert.Equal(t, []common.ObjectId{oidList[0], oidList[2], oidList[1], oidList[3]}, queryIdList("{}", order))
+		order = fmt.Sprintf(`[{"field":"%v","mode":"asc","nulls":"last"}]`, tagAc.ClassId())
+		assert.Equal(t, []common.ObjectId{oidList[1], oidList[2], oidList[0], oidList[3]}, queryIdList("{}", order))
+		for _, ac := range []common.AttributeClass{statusAc, tagAc} {
+			order = fmt.Sprintf(`[{"field":"%v","mode":"desc","nulls":"first"}]`, ac.ClassId())
+			assert.Equal(t, oidList[3], queryIdList("{}", order)[0])
+			order = fmt.Sprintf(`[{"field":"%v","mode":"asc"}]`, ac.ClassId())
+			assert.Equal(t, oidList[3], queryIdList("{}", order)[0])
+			order = fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, ac.ClassId())
+			assert.Equal(t, oidList[3], queryIdList("{}", order)[3])
+		}
+
+		// 删除选项后对象上的值同步移除
+		err = statusAc.Set(ctx, tx, utils.JSONMap{
+			"options": `[{"name":"todo","color":"gray"},{"name":"doing","color":"blue"}]`,
+		})
+		assert.NoError(t, err)
+		attr, err = statusAc.FindId(ctx, tx, oidList[1])
+		assert.NoError(t, err)
+		assert.Equal(t, "", attr.String())
+	})
+
+	t.Run("Test Date Attribute Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		dueAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeDate)
+		assert.NoError(t, err)
+		err = dueAc.Set(ctx, tx, utils.JSONMap{"timezone": "Asia/Shanghai", "include_time": true})
+		assert.NoError(t, err)
+		err = dueAc.Set(ctx, tx, utils.JSONMap{"timezone": "Mars/Olympus"})
+		assert.Error(t, err)
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, dueAc)
+		assert.NoError(t, err)
+
+		// 东八区的00:30在UTC中仍是前一天
+		valueList := []string{"2024-03-01T00:30:00", "2024-03-05 18:00", "2024-03-10T09:00:00Z", ""}
+		oidList := []common.ObjectId{}
+		for _, value := range valueList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+			attr, err := dueAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = dueAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		attr, err := dueAc.FindId(ctx, tx, oidList[2])
+		assert.NoError(t, err)
+		assert.Equal(t, "2024-03-10T17:00:00+08:00", attr.String())
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc"}]`, dueAc.ClassId()))
+		assert.NoError(t, err)
+		clock := func() time.Time {
+			return time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+		}
+		queryCtx := common.WithClock(ctx, clock)
+		queryIdList := func(filter string) (idList []common.ObjectId) {
+			err := view.Filter(tx, filter)
+			assert.NoError(t, err)
+			result, err := view.Query(queryCtx, tx)
+			assert.NoError(t, err)
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+
+		filterList := []struct {
+			filter string
+			expect []common.ObjectId
+		}{
+			{fmt.Sprintf(`{"%v":{"on":"2024-03-01"}}`, dueAc.ClassId()), []common.ObjectId{oidList[0]}},
+			{fmt.Sprintf(`{"%v":{"before":"2024-03-05"}}`, dueAc.ClassId()), []common.ObjectId{oidList[0]}},
+			{fmt.Sprintf(`{"%v":{"after":"2024-03-05"}}`, dueAc.ClassId()), []common.ObjectId{oidList[2]}},
+			{fmt.Sprintf(`{"%v":{"between":["2024-03-01","2024-03-05"]}}`, dueAc.ClassId()), []common.ObjectId{oidList[0], oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"within_past_n_days":3}}`, dueAc.ClassId()), []common.ObjectId{oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"within_next_n_days":7}}`, dueAc.ClassId()), []common.ObjectId{oidList[2]}},
+			{fmt.Sprintf(`{"%v":{"is_empty":true}}`, dueAc.ClassId()), []common.ObjectId{oidList[3]}},
+		}
+		for _, item := range filterList {
+			assert.Equal(t, item.expect, queryIdList(item.filter), item.filter)
+		}
+
+		// 天数只能是非负整数
+		for _, op := range []string{"within_past_n_days", "within_next_n_days"} {
+			for _, n := range []float64{2.5, -1} {
+				filter := fmt.Sprintf(`{"%v":{"%s":%v}}`, dueAc.ClassId(), op, n)
+				err = view.Filter(tx, filter)
+				assert.Error(t, err, filter)
+				_, _, err = dueAc.BuildQuery(queryCtx, tx, map[string]interface{}{"op": op, "value": n})
+				assert.Error(t, err, filter)
+			}
+		}
+	})
+
+	t.Run("Test Link Query Operations", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		nameAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		linkAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeLink)
+		assert.NoError(t, err)
+		err = linkAc.Set(ctx, tx, utils.JSONMap{"dep_attribute": fmt.Sprintf(`["%v"]`, nameAc.ClassId())})
+		assert.NoError(t, err)
+
+		tagList := []common.ObjectId{}
+		for _, name := range []string{"alpha", "beta", "gamma"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			attr, err := nameAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": name})
+			assert.NoError(t, err)
+			err = nameAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			tagList = append(tagList, obj.ObjectId())
+		}
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, linkAc)
+		assert.NoError(t, err)
+		linkList := [][]common.ObjectId{
+			{tagList[1], tagList[2]},
+			{tagList[0]},
+			{},
+			{tagList[0], tagList[1], tagList[2]},
+		}
+		oidList := []common.ObjectId{}
+		for _, refList := range linkList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			attr, err := linkAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			buf := &bytes.Buffer{}
+			buf.WriteString("[")
+			for idx, ref := range refList {
+				if idx != 0 {
+					buf.WriteString(",")
+				}
+				buf.WriteString(fmt.Sprintf(`"%v"`, ref))
+			}
+			buf.WriteString("]")
+			err = attr.SetValue(map[string]interface{}{
+				"update": buf.String(),
+				"ctx":    ctx,
+				"tx":     tx,
+			})
+			assert.NoError(t, err)
+			err = linkAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		queryIdList := func(filter string, order string) (idList []common.ObjectId) {
+			err := view.Filter(tx, filter)
+			assert.NoError(t, err)
+			err = view.SortBy(tx, order)
+			assert.NoError(t, err)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+
+		countOrder := fmt.Sprintf(`[{"field":"%v","mode":"asc","by":"count"}]`, linkAc.ClassId())
+		filterList := []struct {
+			filter string
+			expect []common.ObjectId
+		}{
+			{fmt.Sprintf(`{"%v":{"contains":"%v"}}`, linkAc.ClassId(), tagList[0]), []common.ObjectId{oidList[1], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"contains_any":["%v","%v"]}}`, linkAc.ClassId(), tagList[0], tagList[2]), []common.ObjectId{oidList[1], oidList[0], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"contains_all":["%v","%v"]}}`, linkAc.ClassId(), tagList[1], tagList[2]), []common.ObjectId{oidList[0], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"is_empty":true}}`, linkAc.ClassId()), []common.ObjectId{oidList[2]}},
+			{fmt.Sprintf(`{"%v":{"count_gt":1}}`, linkAc.ClassId()), []common.ObjectId{oidList[0], oidList[3]}},
+			{fmt.Sprintf(`{"%v":{"count_lt":2}}`, linkAc.ClassId()), []common.ObjectId{oidList[2], oidList[1]}},
+			{fmt.Sprintf(`{"%v":{"like":"gam"}}`, linkAc.ClassId()), []common.ObjectId{oidList[0], oidList[3]}},
+		}
+		for _, item := range filterList {
+			assert.Equal(t, item.expect, queryIdList(item.filter, countOrder), item.filter)
+		}
+
+		showOrder := fmt.Sprintf(`[{"field":"%v","mode":"desc","by":"show"}]`, linkAc.ClassId())
+		assert.Equal(t, []common.ObjectId{oidList[0], oidList[3], oidList[1], oidList[2]}, queryIdList("{}", showOrder))
+	})
+
+	t.Run("Test Filter Value Binding", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		for _, value := range []string{"it's", "plain"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		// 引号作为普通字符匹配，不会改变sql结构
+		filterList := map[string]int{
+			fmt.Sprintf(`{"%v":{"eq":"it's"}}`, textAc.ClassId()):          1,
+			fmt.Sprintf(`{"%v":{"eq":"x' OR '1'='1"}}`, textAc.ClassId()):  0,
+			fmt.Sprintf(`{"%v":{"like":"' OR 1=1 --"}}`, textAc.ClassId()): 0,
+			`{"$fts":{"search":"it's"}}`:                                   1,
+		}
+		for filter, count := range filterList {
+			err = view.Filter(tx, filter)
+			assert.NoError(t, err)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err, filter)
+			if result != nil {
+				assert.Equal(t, count, len(result.Raw()), filter)
+			}
+		}
+	})
+
+	t.Run("Test Table Full Text Search", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		oidList := []common.ObjectId{}
+		for _, value := range []string{"black cat sleeps", "black dog", "cat cat cat food", "dog food"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		// 没有fts5时退化为LIKE匹配，以下搜索在两种实现下结果一致
+		searchList := map[string]int{
+			`cat`:                    2,
+			`"black cat"`:            1,
+			`ca*`:                    2,
+			`cat OR dog`:             4,
+			`food -cat`:              1,
+			`(cat OR dog) AND black`: 2,
+			`NEAR(black sleeps, 1)`:  1,
+		}
+		for search, count := range searchList {
+			filter, _ := json.Marshal(map[string]interface{}{"$fts": map[string]interface{}{"search": search}})
+			err = view.Filter(tx, string(filter))
+			assert.NoError(t, err, search)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err, search)
+			if result != nil {
+				assert.Equal(t, count, len(result.Raw()), search)
+			}
+		}
+		// 搜索语法错误在保存筛选时返回
+		err = view.Filter(tx, `{"$fts":{"search":"cat NOT"}}`)
+		assert.Error(t, err)
+
+		// 按bm25相关度排序
+		var fts5 bool
+		err = tx.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+		assert.NoError(t, err)
+		err = view.Filter(tx, `{"$fts":{"search":"cat"}}`)
+		assert.NoError(t, err)
+		err = view.SortBy(tx, `[{"field":"$fts"}]`)
+		assert.NoError(t, err)
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(result.Raw()))
+		if fts5 {
+			assert.Equal(t, oidList[2], result.Raw()[0].ObjectId())
+		}
+	})
+
+	t.Run("Test Full Text Search Tokenizer", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, "cjk_bigram", table.MetaInfo()["tokenizer"])
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		for _, value := range []string{"我们使用全文搜索", "中文分词测试", "English and 中文 mixed"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		searchCount := func(search string) int {
+			filter, _ := json.Marshal(map[string]interface{}{"$fts": map[string]interface{}{"search": search}})
+			err := view.Filter(tx, string(filter))
+			assert.NoError(t, err, search)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err, search)
+			if result == nil {
+				return -1
+			}
+			return len(result.Raw())
+		}
+		searchList := map[string]int{
+			`中文`:         2,
+			`搜索`:         1,
+			`索`:          1,
+			`文`:          3,
+			`文搜`:         1,
+			`分词测试`:       1,
+			`english 中文`: 1,
+			`"and 中文"`:   1,
+		}
+		for search, count := range searchList {
+			assert.Equal(t, count, searchCount(search), search)
+		}
+
+		// 切换分词器后重建索引
+		err = table.Set(ctx, tx, utils.JSONMap{"tokenizer": "not_exist"})
+		assert.Error(t, err)
+		err = table.Set(ctx, tx, utils.JSONMap{"tokenizer": "unicode"})
+		assert.NoError(t, err)
+		assert.Equal(t, "unicode", table.MetaInfo()["tokenizer"])
+		var fts5 bool
+		err = tx.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+		assert.NoError(t, err)
+		if fts5 {
+			assert.Equal(t, 1, searchCount(`中文`))
+			assert.Equal(t, 1, searchCount(`中文分词测试`))
+		}
+	})
+
+	t.Run("Test Full Text Search Highlight", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		titleAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		bodyAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{titleAc, bodyAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+		obj, err := sqlite.CreateObject(ctx, tx)
+		assert.NoError(t, err)
+		err = table.Insert(ctx, tx, obj.ObjectId())
+		assert.NoError(t, err)
+		valueMap := map[common.AttributeClass]string{
+			titleAc: "Cat care",
+			bodyAc:  "Feeding a cat twice a day keeps the cat healthy and happy all year round",
+		}
+		for ac, value := range valueMap {
+			attr, err := ac.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = ac.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Filter(tx, `{"$fts":{"search":"cat -dog"}}`)
+		assert.NoError(t, err)
+		// 未开启高亮时不返回片段
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Nil(t, result.Highlights(obj.ObjectId()))
+
+		result, err = view.Highlight(&common.HighlightConfig{
+			StartMark: "[",
+			EndMark:   "]",
+			Ellipsis:  "…",
+			Context:   6,
+		}).Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []common.Highlight{
+			{ClassId: titleAc.ClassId(), Snippet: "[Cat] care"},
+			{ClassId: bodyAc.ClassId(), Snippet: "…ing a [cat] twice…"},
+		}, result.Highlights(obj.ObjectId()))
+		data, err := result.Marshal(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, "[Cat] care", gjson.Get(data, `0.$highlight.0.snippet`).String())
+	})
+
+	t.Run("Test Database Search", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		tableList := []common.Table{}
+		for i := 0; i < 2; i++ {
+			table, err := sqlite.CreateTable(ctx, tx)
+			assert.NoError(t, err)
+			err = table.AddAttributeClass(ctx, tx, textAc)
+			assert.NoError(t, err)
+			tableList = append(tableList, table)
+		}
+		// 第一个对象同时属于两个表
+		oidList := []common.ObjectId{}
+		valueList := []string{"a black cat", "cat cat cat", "dog"}
+		memberList := [][]common.Table{tableList, tableList[1:], tableList[:1]}
+		for i, value := range valueList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			for _, table := range memberList[i] {
+				err = table.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+			}
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+		}
+
+		hits, err := sqlite.Search(ctx, tx, "cat", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(hits))
+		hitMap := map[common.ObjectId]common.SearchHit{}
+		for _, hit := range hits {
+			hitMap[hit.Object.ObjectId()] = hit
+		}
+		assert.ElementsMatch(t, []common.TableId{tableList[0].TableId(), tableList[1].TableId()}, hitMap[oidList[0]].TableIdList)
+		assert.Equal(t, []common.TableId{tableList[1].TableId()}, hitMap[oidList[1]].TableIdList)
+		var fts5 bool
+		err = tx.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&fts5)
+		assert.NoError(t, err)
+		if fts5 {
+			assert.Equal(t, oidList[1], hits[0].Object.ObjectId())
+		}
+
+		// 只搜索指定的表，结果分页
+		hits, err = sqlite.Search(ctx, tx, "cat OR dog", &common.SearchOption{
+			TableIdList: []common.TableId{tableList[0].TableId()},
+			Highlight:   common.DefaultHighlightConfig(),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(hits))
+		for _, hit := range hits {
+			assert.Equal(t, 1, len(hit.Highlights))
+		}
+		hits, err = sqlite.Search(ctx, tx, "cat OR dog", &common.SearchOption{Limit: 2, Offset: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(hits))
+
+		_, err = sqlite.Search(ctx, tx, "(cat", nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test View Set", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		acList := []common.AttributeClass{}
+		for i := 0; i < 2; i++ {
+			ac, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+			assert.NoError(t, err)
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+			acList = append(acList, ac)
+		}
+		otherAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"fields": []interface{}{acList[1].ClassId().String(), acList[0].ClassId().String()},
+			"widths": map[string]interface{}{acList[1].ClassId().String(): float64(240)},
+			"limit":  float64(2),
+			"offset": float64(1),
+			"order":  fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, acList[0].ClassId()),
+		})
+		assert.NoError(t, err)
+
+		// 重新读取视图，设置已保存
+		savedView, err := table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		data := savedView.Marshal()
+		assert.Equal(t, acList[1].ClassId().String(), gjson.Get(data, "fields.0").String())
+		assert.Equal(t, acList[0].ClassId().String(), gjson.Get(data, "fields.1").String())
+		assert.Equal(t, int64(240), gjson.Get(data, "widths."+acList[1].ClassId().String()).Int())
+		assert.Equal(t, int64(2), gjson.Get(data, "limit").Int())
+		assert.Equal(t, "desc", gjson.Get(data, "order.0.mode").String())
+		result, err := savedView.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, len(result.Raw()))
+
+		// 隐藏列时同时移除列宽
+		err = view.Set(ctx, tx, utils.JSONMap{"fields": fmt.Sprintf(`["%v"]`, acList[0].ClassId())})
+		assert.NoError(t, err)
+		assert.False(t, gjson.Get(view.Marshal(), "widths."+acList[1].ClassId().String()).Exists())
+
+		// 不合法的设置不会修改视图
+		before := view.Marshal()
+		invaildList := []utils.JSONMap{
+			{"fields": []interface{}{otherAc.ClassId().String()}},
+			{"fields": []interface{}{acList[0].ClassId().String(), acList[0].ClassId().String()}},
+			{"widths": map[string]interface{}{acList[0].ClassId().String(): float64(-1)}},
+			{"limit": float64(0), "offset": float64(1)},
+			{"filter": "{"},
+			{"unknown": 1},
+		}
+		for _, invaild := range invaildList {
+			err = view.Set(ctx, tx, invaild)
+			assert.Error(t, err, invaild)
+			assert.Equal(t, before, view.Marshal())
+		}
+	})
+
+	t.Run("Test Table GetViewData", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		oidList := []common.ObjectId{}
+		for _, value := range []string{"a", "b", "c", "d", "e"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+		}
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"filter": fmt.Sprintf(`{"%v":{"neq":"a"}}`, textAc.ClassId()),
+			"order":  fmt.Sprintf(`[{"field":"%v","mode":"asc"}]`, textAc.ClassId()),
+		})
+		assert.NoError(t, err)
+		saved := view.Marshal()
+
+		idList := func(result common.TableResult) (idList []common.ObjectId) {
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+		result, err := table.GetViewData(ctx, tx, view.ViewId(), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, oidList[1:], idList(result))
+		result, err = table.GetViewData(ctx, tx, view.ViewId(), &common.QueryConfig{Limit: 2, Offset: 1})
+		assert.NoError(t, err)
+		assert.Equal(t, oidList[2:4], idList(result))
+		// 额外的筛选与视图的筛选同时生效
+		result, err = table.GetViewData(ctx, tx, view.ViewId(), &common.QueryConfig{
+			Filter: fmt.Sprintf(`{"$or":[{"%v":{"eq":"a"}},{"%v":{"eq":"b"}},{"%v":{"eq":"c"}}]}`,
+				textAc.ClassId(), textAc.ClassId(), textAc.ClassId()),
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, oidList[1:3], idList(result))
+		_, err = table.GetViewData(ctx, tx, view.ViewId(), &common.QueryConfig{Filter: "{"})
+		assert.Error(t, err)
+		// 额外的筛选经过校验，结构错误时返回校验错误
+		for _, filter := range []string{
+			fmt.Sprintf(`{"%v":{}}`, textAc.ClassId()),
+			fmt.Sprintf(`{"%v":{"in":"a"}}`, textAc.ClassId()),
+			`{"$and":[]}`,
+		} {
+			_, err = table.GetViewData(ctx, tx, view.ViewId(), &common.QueryConfig{Filter: filter})
+			var errList query.ValidationErrors
+			assert.True(t, errors.As(err, &errList), filter)
+		}
+
+		// 直接写入的空连接在解析时同样被拒绝
+		for _, filter := range []string{`{"$and":[]}`, `{"$or":[]}`} {
+			stored, err := table.NewView(ctx, tx)
+			assert.NoError(t, err)
+			_, err = tx.Exac(`UPDATE table_views SET query = json_set(query, '$.filter', json(?)) WHERE view_id = ?`, filter, stored.ViewId())
+			assert.NoError(t, err)
+			_, err = table.GetViewData(ctx, tx, stored.ViewId(), nil)
+			if assert.Error(t, err, filter) {
+				assert.Contains(t, err.Error(), "invaild empty condition", filter)
+			}
+			err = table.DeleteView(ctx, tx, stored.ViewId())
+			assert.NoError(t, err)
+		}
+
+		savedView, err := table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, saved, savedView.Marshal())
+	})
+
+	t.Run("Test View Prune Deleted Attribute", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		acList := []common.AttributeClass{}
+		for i := 0; i < 2; i++ {
+			ac, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+			assert.NoError(t, err)
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+			acList = append(acList, ac)
+		}
+		removed, kept := acList[0].ClassId(), acList[1].ClassId()
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"widths": map[string]interface{}{removed.String(): float64(100)},
+			"filter": fmt.Sprintf(`{"$and":[{"%v":{"eq":"x"}},{"$or":[{"%v":{"eq":"y"}}]},{"%v":{"neq":"z"}}]}`, removed, removed, kept),
+			"order":  fmt.Sprintf(`[{"field":"%v","mode":"asc"},{"field":"%v","mode":"desc"}]`, removed, kept),
+		})
+		assert.NoError(t, err)
+		// 未引用该属性的视图不受影响
+		otherView, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = otherView.Set(ctx, tx, utils.JSONMap{"fields": []interface{}{kept.String()}})
+		assert.NoError(t, err)
+
+		err = table.DeleteAttributeClass(ctx, tx, acList[0])
+		assert.NoError(t, err)
+
+		savedView, err := table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		data := savedView.Marshal()
+		assert.Equal(t, fmt.Sprintf(`["%v"]`, kept), gjson.Get(data, "fields").Raw)
+		assert.Equal(t, "{}", gjson.Get(data, "widths").Raw)
+		assert.Equal(t, fmt.Sprintf(`{"$and":[{"%v":{"neq":"z"}}]}`, kept), gjson.Get(data, "filter").Raw)
+		assert.Equal(t, fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, kept), gjson.Get(data, "order").Raw)
+		_, err = savedView.Query(ctx, tx)
+		assert.NoError(t, err)
+
+		pruned := savedView.Pruned()
+		assert.Equal(t, 1, len(pruned))
+		assert.Equal(t, removed, pruned[0].ClassId)
+		assert.True(t, pruned[0].Field)
+		assert.Equal(t, []string{
+			fmt.Sprintf(`{"%v":{"eq":"x"}}`, removed),
+			fmt.Sprintf(`{"%v":{"eq":"y"}}`, removed),
+		}, pruned[0].Filter)
+		assert.Equal(t, []string{fmt.Sprintf(`{"field":"%v","mode":"asc"}`, removed)}, pruned[0].Order)
+
+		savedOther, err := table.View(ctx, tx, otherView.ViewId())
+		assert.NoError(t, err)
+		assert.Empty(t, savedOther.Pruned())
+
+		err = savedView.ClearPruned(tx)
+		assert.NoError(t, err)
+		savedView, err = table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		assert.Empty(t, savedView.Pruned())
+	})
+
+	t.Run("Test View Name Duplicate Delete", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, name := range []string{"A", "B", "C"} {
+			view, err := table.NewView(ctx, tx)
+			assert.NoError(t, err)
+			err = view.Set(ctx, tx, utils.JSONMap{"name": name, "description": "view " + name})
+			assert.NoError(t, err)
+		}
+		nameList := func() (names []string) {
+			vlist, err := table.ListView(ctx, tx)
+			assert.NoError(t, err)
+			for idx, view := range vlist {
+				assert.Equal(t, idx, view.Position())
+				names = append(names, view.Name())
+			}
+			return
+		}
+		assert.Equal(t, []string{"A", "B", "C"}, nameList())
+
+		vlist, err := table.ListView(ctx, tx)
+		assert.NoError(t, err)
+		err = vlist[0].Set(ctx, tx, utils.JSONMap{"limit": float64(7)})
+		assert.NoError(t, err)
+		copyView, err := table.DuplicateView(ctx, tx, vlist[0].ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, "A copy", copyView.Name())
+		assert.Equal(t, "view A", copyView.Description())
+		assert.Equal(t, int64(7), gjson.Get(copyView.Marshal(), "limit").Int())
+		assert.Equal(t, []string{"A", "A copy", "B", "C"}, nameList())
+
+		// 调整顺序
+		err = vlist[2].Set(ctx, tx, utils.JSONMap{"position": float64(0)})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"C", "A", "A copy", "B"}, nameList())
+		err = vlist[2].Set(ctx, tx, utils.JSONMap{"position": float64(100)})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A", "A copy", "B", "C"}, nameList())
+
+		err = table.DeleteView(ctx, tx, copyView.ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"A", "B", "C"}, nameList())
+		err = table.DeleteView(ctx, tx, copyView.ViewId())
+		assert.Error(t, err)
+
+		// 其他表的视图不能通过该表打开
+		otherTable, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		_, err = otherTable.View(ctx, tx, vlist[0].ViewId())
+		assert.Error(t, err)
+		_, err = otherTable.GetViewData(ctx, tx, vlist[0].ViewId(), nil)
+		assert.Error(t, err)
+		_, err = otherTable.DuplicateView(ctx, tx, vlist[0].ViewId())
+		assert.Error(t, err)
+
+		err = vlist[1].Set(ctx, tx, utils.JSONMap{"name": ""})
+		assert.Error(t, err)
+		assert.Equal(t, "B", vlist[1].Name())
+
+		// 删除表时同时删除视图
+		err = sqlite.DeleteTable(ctx, tx, table.TableId())
+		assert.NoError(t, err)
+		var count int
+		err = tx.QueryRow(`SELECT COUNT(*) FROM table_views`).Scan(&count)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+	t.Run("Test Group By View", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		statusAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeSelect)
+		assert.NoError(t, err)
+		err = statusAc.Set(ctx, tx, utils.JSONMap{
+			"options": `[{"name":"todo"},{"name":"done"}]`,
+		})
+		assert.NoError(t, err)
+		amountAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		dueAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeDate)
+		assert.NoError(t, err)
+		err = dueAc.Set(ctx, tx, utils.JSONMap{"timezone": "Asia/Shanghai"})
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{statusAc, amountAc, dueAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+
+		dataList := []struct {
+			status string
+			amount float64
+			due    string
+		}{
+			{"todo", 1, "2024-03-01"},
+			{"done", 2, "2024-03-20"},
+			{"todo", 3, "2024-04-02"},
+			{"", 4, "2024-04-03"},
+			{"todo", 5, "2024-05-01"},
+		}
+		setValue := func(ac common.AttributeClass, oid common.ObjectId, value interface{}) {
+			attr, err := ac.Insert(ctx, tx, oid)
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = ac.Update(ctx, tx, oid, attr)
+			assert.NoError(t, err)
+		}
+		oidList := []common.ObjectId{}
+		for _, data := range dataList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+			setValue(statusAc, obj.ObjectId(), data.status)
+			setValue(amountAc, obj.ObjectId(), data.amount)
+			setValue(dueAc, obj.ObjectId(), data.due)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"order": fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, amountAc.ClassId()),
+			"group_by": map[string]interface{}{
+				"field": statusAc.ClassId().String(),
+				"limit": float64(2),
+				"aggregates": []interface{}{
+					map[string]interface{}{"field": amountAc.ClassId().String(), "func": "sum"},
+					map[string]interface{}{"field": amountAc.ClassId().String(), "func": "max"},
+				},
+			},
+		})
+		assert.NoError(t, err)
+
+		idList := func(result common.TableResult) (idList []common.ObjectId) {
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		groups := result.Groups()
+		assert.Equal(t, 3, len(groups))
+		// 分组键为选项id，空分组总是在最后
+		optionId := func(oid common.ObjectId) string {
+			attr, err := statusAc.FindId(ctx, tx, oid)
+			assert.NoError(t, err)
+			return gjson.Get(attr.GetJSON(), "value").String()
+		}
+		todoId, doneId := optionId(oidList[0]), optionId(oidList[1])
+		assert.Equal(t, []interface{}{todoId, doneId, nil}, []interface{}{groups[0].Key, groups[1].Key, groups[2].Key})
+		assert.Equal(t, []int{3, 1, 1}, []int{groups[0].Count, groups[1].Count, groups[2].Count})
+		assert.Equal(t, []interface{}{float64(9), float64(5)}, groups[0].Aggregates)
+		assert.Equal(t, []common.ObjectId{oidList[4], oidList[2]}, idList(groups[0].Rows))
+		assert.Equal(t, []common.ObjectId{oidList[3]}, idList(groups[2].Rows))
+		data, err := result.Marshal(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), gjson.Get(data, "0.count").Int())
+		assert.Equal(t, 2, len(gjson.Get(data, "0.rows").Array()))
+
+		// 分组之间独立分页
+		result, err = view.QueryGroup(ctx, tx, todoId, &common.QueryConfig{Limit: 2, Offset: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, []common.ObjectId{oidList[0]}, idList(result))
+		result, err = view.QueryGroup(ctx, tx, nil, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, []common.ObjectId{oidList[3]}, idList(result))
+		_, err = view.QueryGroup(ctx, tx, todoId, &common.QueryConfig{Filter: `{"$and":[]}`})
+		var errList query.ValidationErrors
+		assert.True(t, errors.As(err, &errList))
+
+		// 日期按月分组
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"group_by": fmt.Sprintf(`{"field":"%v","bucket":"month","mode":"desc"}`, dueAc.ClassId()),
+		})
+		assert.NoError(t, err)
+		result, err = view.Query(ctx, tx)
+		assert.NoError(t, err)
+		groups = result.Groups()
+		assert.Equal(t, 3, len(groups))
+		assert.Equal(t, []interface{}{"2024-05-01", "2024-04-01", "2024-03-01"}, []interface{}{groups[0].Key, groups[1].Key, groups[2].Key})
+		assert.Equal(t, []int{1, 2, 2}, []int{groups[0].Count, groups[1].Count, groups[2].Count})
+
+		// 分组设置保存在视图中
+		savedView, err := table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, "month", gjson.Get(savedView.Marshal(), "group_by.bucket").String())
+
+		err = view.Set(ctx, tx, utils.JSONMap{"group_by": `{"field":"not exist"}`})
+		assert.Error(t, err)
+		err = view.Set(ctx, tx, utils.JSONMap{
+			"group_by": fmt.Sprintf(`{"field":"%v","aggregates":[{"field":"%v","func":"sum"}]}`, statusAc.ClassId(), dueAc.ClassId()),
+		})
+		assert.Error(t, err)
+		assert.Equal(t, "month", gjson.Get(view.Marshal(), "group_by.bucket").String())
+
+		// 删除分组的属性时取消分组
+		err = table.DeleteAttributeClass(ctx, tx, dueAc)
+		assert.NoError(t, err)
+		savedView, err = table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, gjson.Null, gjson.Get(savedView.Marshal(), "group_by").Type)
+		assert.True(t, savedView.Pruned()[0].GroupBy)
+		result, err = savedView.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Nil(t, result.Groups())
+		assert.Equal(t, 5, len(result.Raw()))
+
+		err = view.Set(ctx, tx, utils.JSONMap{"group_by": nil})
+		assert.NoError(t, err)
+		_, err = view.QueryGroup(ctx, tx, nil, nil)
+		assert.Error(t, err)
+	})
+	t.Run("Test View Summary", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		amountAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		dueAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeDate)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{textAc, amountAc, dueAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+
+		dataList := []struct {
+			text   string
+			amount interface{}
+			due    string
+		}{
+			{"a", float64(1), "2024-03-05"},
+			{"b", float64(4), "2024-01-20"},
+			{"a", float64(2), ""},
+			{"", float64(10), "2024-06-01"},
+			{"c", nil, "2024-02-10"},
+		}
+		setValue := func(ac common.AttributeClass, oid common.ObjectId, value interface{}) {
+			attr, err := ac.Insert(ctx, tx, oid)
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = ac.Update(ctx, tx, oid, attr)
+			assert.NoError(t, err)
+		}
+		for _, data := range dataList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			setValue(textAc, obj.ObjectId(), data.text)
+			if data.amount != nil {
+				setValue(amountAc, obj.ObjectId(), data.amount)
+			}
+			setValue(dueAc, obj.ObjectId(), data.due)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		summary, err := view.Summary(ctx, tx, common.SummarySpec{
+			textAc.ClassId():   {"count", "count_empty", "count_unique"},
+			amountAc.ClassId(): {"count_empty", "sum", "avg", "min", "max", "median", "std"},
+			dueAc.ClassId():    {"count_empty", "earliest", "latest"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"count": int64(5), "count_empty": int64(1), "count_unique": int64(3)}, summary[textAc.ClassId()])
+		amount := summary[amountAc.ClassId()]
+		assert.Equal(t, int64(1), amount["count_empty"])
+		assert.EqualValues(t, 17, amount["sum"])
+		assert.EqualValues(t, 4.25, amount["avg"])
+		assert.EqualValues(t, 1, amount["min"])
+		assert.EqualValues(t, 10, amount["max"])
+		assert.EqualValues(t, 3, amount["median"])
+		assert.InDelta(t, 3.4911, amount["std"], 0.001)
+		due := summary[dueAc.ClassId()]
+		assert.Equal(t, int64(1), due["count_empty"])
+		assert.Contains(t, due["earliest"], "2024-01-20")
+		assert.Contains(t, due["latest"], "2024-06-01")
+
+		// 统计在视图的筛选下进行
+		err = view.Filter(tx, fmt.Sprintf(`{"%v":{"eq":"a"}}`, textAc.ClassId()))
+		assert.NoError(t, err)
+		summary, err = view.Summary(ctx, tx, common.SummarySpec{amountAc.ClassId(): {"count", "sum", "median"}})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 2, summary[amountAc.ClassId()]["count"])
+		assert.EqualValues(t, 3, summary[amountAc.ClassId()]["sum"])
+		assert.EqualValues(t, 1.5, summary[amountAc.ClassId()]["median"])
+
+		_, err = view.Summary(ctx, tx, common.SummarySpec{textAc.ClassId(): {"sum"}})
+		assert.Error(t, err)
+		_, err = view.Summary(ctx, tx, common.SummarySpec{amountAc.ClassId(): {"latest"}})
+		assert.Error(t, err)
+		otherAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		_, err = view.Summary(ctx, tx, common.SummarySpec{otherAc.ClassId(): {"count"}})
+		assert.Error(t, err)
+	})
+	t.Run("Test View Total And Cursor", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		amountAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{textAc, amountAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+		// 包含重复和空的排序键
+		amountList := []interface{}{float64(3), nil, float64(1), float64(3), float64(2), nil, float64(3)}
+		for idx, amount := range amountList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = attr.SetValue(map[string]interface{}{"value": fmt.Sprintf("%c", 'a'+idx%2)})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+			assert.NoError(t, err)
+			if amount != nil {
+				attr, err = amountAc.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": amount})
+				assert.NoError(t, err)
+				err = amountAc.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+			}
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		idList := func(result common.TableResult) (idList []common.ObjectId) {
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		for _, order := range []string{
+			fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, amountAc.ClassId()),
+			fmt.Sprintf(`[{"field":"%v","mode":"asc"},{"field":"%v","mode":"desc"}]`, amountAc.ClassId(), textAc.ClassId()),
+			fmt.Sprintf(`[{"field":"%v","mode":"desc","collation":"nocase","nulls":"first"},{"field":"%v","mode":"asc","nulls":"last"}]`,
+				textAc.ClassId(), amountAc.ClassId()),
+			`[]`,
+		} {
+			err = view.SortBy(tx, order)
+			assert.NoError(t, err)
+			view.Limit(100).Offset(0)
+			all, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			assert.Equal(t, len(amountList), all.Total())
+			assert.Equal(t, "", all.Cursor())
+
+			// 游标分页的结果与一次查询的结果一致
+			view.Limit(2)
+			page, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			assert.Equal(t, len(amountList), page.Total())
+			pageIdList := idList(page)
+			for page.Cursor() != "" {
+				page, err = view.QueryAfter(ctx, tx, page.Cursor())
+				assert.NoError(t, err)
+				assert.Equal(t, len(amountList), page.Total())
+				pageIdList = append(pageIdList, idList(page)...)
+			}
+			assert.Equal(t, idList(all), pageIdList)
+		}
+
+		// 游标与筛选同时生效
+		err = view.Filter(tx, fmt.Sprintf(`{"%v":{"eq":"a"}}`, textAc.ClassId()))
+		assert.NoError(t, err)
+		view.Limit(3)
+		page, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 4, page.Total())
+		assert.Equal(t, 3, len(page.Raw()))
+		page, err = view.QueryAfter(ctx, tx, page.Cursor())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, len(page.Raw()))
+		assert.Equal(t, "", page.Cursor())
+
+		_, err = view.QueryAfter(ctx, tx, "not a cursor")
+		assert.Error(t, err)
+	})
+	t.Run("Test Sort Nulls And Tie Breaker", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		amountAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, amountAc)
+		assert.NoError(t, err)
+		amountList := []interface{}{float64(2), nil, float64(1), float64(2), nil}
+		oidList := []common.ObjectId{}
+		for _, amount := range amountList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			oidList = append(oidList, obj.ObjectId())
+			if amount != nil {
+				attr, err := amountAc.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": amount})
+				assert.NoError(t, err)
+				err = amountAc.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+			}
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		// 对象id按创建顺序递增，相同的排序键按对象id排列
+		sort.Slice(oidList, func(i, j int) bool { return oidList[i].String() < oidList[j].String() })
+
+		idList := func(result common.TableResult) (idList []common.ObjectId) {
+			for _, obj := range result.Raw() {
+				idList = append(idList, obj.ObjectId())
+			}
+			return
+		}
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		for _, item := range []struct {
+			order    string
+			expected []int
+		}{
+			{`"mode":"asc"`, []int{1, 4, 2, 0, 3}},
+			{`"mode":"asc","nulls":"last"`, []int{2, 0, 3, 1, 4}},
+			{`"mode":"desc"`, []int{0, 3, 2, 1, 4}},
+			{`"mode":"desc","nulls":"first"`, []int{1, 4, 0, 3, 2}},
+		} {
+			err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v",%s}]`, amountAc.ClassId(), item.order))
+			assert.NoError(t, err)
+			expected := []common.ObjectId{}
+			for _, idx := range item.expected {
+				expected = append(expected, oidList[idx])
+			}
+			view.Limit(100)
+			result, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			assert.Equal(t, expected, idList(result), item.order)
+
+			// 游标分页不重复也不遗漏
+			view.Limit(1)
+			page, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			pageIdList := idList(page)
+			for page.Cursor() != "" {
+				page, err = view.QueryAfter(ctx, tx, page.Cursor())
+				assert.NoError(t, err)
+				pageIdList = append(pageIdList, idList(page)...)
+			}
+			assert.Equal(t, expected, pageIdList, item.order)
+		}
+
+		err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc","nulls":"middle"}]`, amountAc.ClassId()))
+		assert.Error(t, err)
+	})
+	t.Run("Test Text Sort Collation", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		sortedText := func(valueList []string, collation string) (textList []string) {
+			_, err := tx.Exac(fmt.Sprintf(`DELETE FROM %s`, table.TableId().DataTable()))
+			assert.NoError(t, err)
+			for _, value := range valueList {
+				obj, err := sqlite.CreateObject(ctx, tx)
+				assert.NoError(t, err)
+				attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": value})
+				assert.NoError(t, err)
+				err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+				err = table.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+			}
+			err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc","collation":"%s"}]`, textAc.ClassId(), collation))
+			assert.NoError(t, err)
+			// 逐个对象用游标翻页，检查游标条件使用同样的排序规则
+			view.Limit(1)
+			page, err := view.Query(ctx, tx)
+			assert.NoError(t, err)
+			for {
+				for _, obj := range page.Raw() {
+					attr, err := textAc.FromObject(obj)
+					assert.NoError(t, err)
+					textList = append(textList, attr.String())
+				}
+				if page.Cursor() == "" {
+					return
+				}
+				page, err = view.QueryAfter(ctx, tx, page.Cursor())
+				assert.NoError(t, err)
+			}
+		}
+
+		cardList := []string{"Card 10", "card 2", "Card 1", "Card 02b", "Card"}
+		assert.Equal(t, []string{"Card", "Card 02b", "Card 1", "Card 10", "card 2"}, sortedText(cardList, "binary"))
+		assert.Equal(t, []string{"Card", "Card 1", "card 2", "Card 02b", "Card 10"}, sortedText(cardList, "natural"))
+		// 只有大小写不同时相等，按对象id排列
+		assert.Equal(t, []string{"apple", "Banana", "cherry", "éclair", "Éclair"}, sortedText([]string{"éclair", "cherry", "Banana", "Éclair", "apple"}, "nocase"))
+		assert.Equal(t, []string{"Alice", "阿毛", "李四", "王五", "章鱼", "张三"}, sortedText([]string{"张三", "王五", "章鱼", "Alice", "李四", "阿毛"}, "pinyin"))
+
+		err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc","collation":"klingon"}]`, textAc.ClassId()))
+		assert.Error(t, err)
+	})
+	t.Run("Test Text Filter Operators", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		for _, value := range []string{"Apple pie", "apple_juice", "Banana", "", "Äpfel", "100% juice", "<nil>"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			// <nil>表示对象没有该属性
+			if value != "<nil>" {
+				attr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": value})
+				assert.NoError(t, err)
+				err = textAc.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+			}
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc"}]`, textAc.ClassId()))
+		assert.NoError(t, err)
+		filterText := func(op string, value interface{}) (textList []string) {
+			data, err := json.Marshal(map[string]interface{}{
+				textAc.ClassId().String(): map[string]interface{}{op: value},
+			})
+			assert.NoError(t, err)
+			err = view.Filter(tx, string(data))
+			assert.NoError(t, err)
+			textList = []string{}
+			result, err := view.Query(ctx, tx)
+			if !assert.NoError(t, err, op) {
+				return
+			}
+			for _, obj := range result.Raw() {
+				attr, err := textAc.FromObject(obj)
+				assert.NoError(t, err)
+				textList = append(textList, attr.String())
+			}
+			return
+		}
+
+		assert.Equal(t, []string{"Apple pie", "apple_juice"}, filterText("starts_with", "apple"))
+		assert.Equal(t, []string{"100% juice", "apple_juice"}, filterText("ends_with", "JUICE"))
+		// 通配符按字面匹配
+		assert.Equal(t, []string{"100% juice"}, filterText("like", "0%"))
+		assert.Equal(t, []string{"apple_juice"}, filterText("like", "_"))
+		assert.Equal(t, []string{"Äpfel"}, filterText("eq_nocase", "äPFEL"))
+		assert.Equal(t, []string{"Apple pie", "Banana"}, filterText("in", []interface{}{"Banana", "Apple pie", "Cherry"}))
+		assert.Equal(t, []string{"", "", "100% juice", "apple_juice", "Äpfel"}, filterText("not_in", []interface{}{"Banana", "Apple pie"}))
+		assert.Equal(t, []string{}, filterText("in", []interface{}{}))
+		assert.Equal(t, []string{"", ""}, filterText("is_empty", true))
+		assert.Equal(t, 5, len(filterText("is_not_empty", true)))
+		assert.Equal(t, 5, len(filterText("is_empty", false)))
+		assert.Equal(t, []string{"Äpfel"}, filterText("len_eq", float64(5)))
+		assert.Equal(t, []string{"100% juice", "Apple pie", "apple_juice"}, filterText("len_gt", float64(6)))
+		assert.Equal(t, []string{"Apple pie", "Banana"}, filterText("regexp", `^[A-Z][a-z]+( |$)`))
+
+		for _, item := range []struct {
+			op    string
+			value interface{}
+		}{
+			{"regexp", "("},
+			{"in", "Banana"},
+			{"in", []interface{}{float64(1)}},
+			{"len_gt", "6"},
+			{"starts_with", float64(1)},
+			{"unknown", "a"},
+		} {
+			data, err := json.Marshal(map[string]interface{}{
+				textAc.ClassId().String(): map[string]interface{}{item.op: item.value},
+			})
+			assert.NoError(t, err)
+			err = view.Filter(tx, string(data))
+			assert.Error(t, err, item.op)
+		}
+	})
+	t.Run("Test Number Filter Operators", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		numAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, numAc)
+		assert.NoError(t, err)
+		for _, value := range []interface{}{float64(1), float64(2.5), float64(3), float64(-3), float64(10), nil} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			if value != nil {
+				attr, err := numAc.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": value})
+				assert.NoError(t, err)
+				err = numAc.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+			}
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.SortBy(tx, fmt.Sprintf(`[{"field":"%v","mode":"asc","nulls":"last"}]`, numAc.ClassId()))
+		assert.NoError(t, err)
+		queryNumber := func(op string, value interface{}) (numList []interface{}, err error) {
+			data, err := json.Marshal(map[string]interface{}{
+				numAc.ClassId().String(): map[string]interface{}{op: value},
+			})
+			assert.NoError(t, err)
+			// 错误的筛选在保存时返回
+			if err = view.Filter(tx, string(data)); err != nil {
+				return
+			}
+			result, err := view.Query(ctx, tx)
+			if err != nil {
+				return
+			}
+			numList = []interface{}{}
+			for _, obj := range result.Raw() {
+				numList = append(numList, gjson.GetBytes(obj.Data(), numAc.ClassId().String()+".value").Value())
+			}
+			return
+		}
+		filterNumber := func(op string, value interface{}) []interface{} {
+			numList, err := queryNumber(op, value)
+			assert.NoError(t, err, op)
+			return numList
+		}
+
+		assert.Equal(t, []interface{}{float64(3), float64(10)}, filterNumber("gte", float64(3)))
+		assert.Equal(t, []interface{}{float64(-3), float64(1), float64(2.5), float64(3)}, filterNumber("neq", float64(10)))
+		assert.Equal(t, []interface{}{float64(1), float64(2.5), float64(3)}, filterNumber("between", []interface{}{float64(1), float64(3)}))
+		assert.Equal(t, []interface{}{float64(-3), float64(10)}, filterNumber("in", []interface{}{float64(10), float64(-3)}))
+		assert.Equal(t, []interface{}{float64(1), float64(2.5), float64(3), nil}, filterNumber("not_in", []interface{}{float64(10), float64(-3)}))
+		assert.Equal(t, []interface{}{nil}, filterNumber("is_empty", true))
+		assert.Equal(t, 5, len(filterNumber("is_not_empty", nil)))
+		// 取模结果与除数同号，-3对2取模为1
+		assert.Equal(t, []interface{}{float64(-3), float64(1), float64(3)}, filterNumber("mod", []interface{}{float64(2), float64(1)}))
+		assert.Equal(t, []interface{}{float64(2.5)}, filterNumber("mod", []interface{}{float64(1), float64(0.5)}))
+		assert.Equal(t, []interface{}{float64(2.5)}, filterNumber("int_eq", float64(2)))
+		assert.Equal(t, []interface{}{float64(2.5)}, filterNumber("is_integer", false))
+
+		for _, item := range []struct {
+			op    string
+			value interface{}
+		}{
+			{"gt", "3"},
+			{"eq", nil},
+			{"between", []interface{}{float64(3), float64(1)}},
+			{"between", []interface{}{float64(1)}},
+			{"in", []interface{}{"1"}},
+			{"mod", []interface{}{float64(0), float64(1)}},
+			{"int_eq", float64(1.5)},
+			{"is_empty", "yes"},
+			{"like", float64(1)},
+		} {
+			_, err = queryNumber(item.op, item.value)
+			if assert.Error(t, err, item.op) {
+				assert.Contains(t, err.Error(), numAc.ClassId().String())
+				assert.Contains(t, err.Error(), item.op)
+			}
+		}
+	})
+
+	t.Run("Test Table Ad Hoc Query", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		numberAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, numberAc)
+		assert.NoError(t, err)
+		for idx, value := range []string{"alpha", "beta", "gamma", "delta", "epsilon"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			textAttr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = textAttr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), textAttr)
+			assert.NoError(t, err)
+			numberAttr, err := numberAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = numberAttr.SetValue(map[string]interface{}{"value": float64(idx + 1)})
+			assert.NoError(t, err)
+			err = numberAc.Update(ctx, tx, obj.ObjectId(), numberAttr)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		textOf := func(objList []common.Object) (textList []string) {
+			textList = []string{}
+			for _, obj := range objList {
+				attr, err := textAc.FromObject(obj)
+				assert.NoError(t, err)
+				textList = append(textList, attr.String())
+			}
+			return
+		}
+		order := fmt.Sprintf(`[{"field":"%v","mode":"desc"}]`, numberAc.ClassId())
+
+		// 筛选、排序和分页
+		objList, err := table.Query().
+			Filter(ctx, tx, fmt.Sprintf(`{"%v":{"gte":2}}`, numberAc.ClassId())).
+			OrderBy(ctx, tx, order).
+			Limit(2).
+			Offset(1).
+			Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"delta", "gamma"}, textOf(objList))
+
+		// 多次Filter同时生效
+		objList, err = table.Query().
+			Filter(ctx, tx, fmt.Sprintf(`{"%v":{"gte":2}}`, numberAc.ClassId())).
+			Filter(ctx, tx, fmt.Sprintf(`{"%v":{"like":"ta"}}`, textAc.ClassId())).
+			OrderBy(ctx, tx, order).
+			Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"delta", "beta"}, textOf(objList))
+
+		// 没有筛选时返回全部对象，按对象id排序
+		objList, err = table.Query().Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, len(objList))
+
+		// 查询不会保存为视图
+		viewList, err := table.ListView(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(viewList))
+
+		// 解析错误在Find时返回
+		_, err = table.Query().Filter(ctx, tx, `{"$and":1}`).Limit(1).Find(ctx, tx)
+		assert.Error(t, err)
+		_, err = table.Query().Limit(-1).Find(ctx, tx)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Typed Query Builder", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		numberAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, textAc)
+		assert.NoError(t, err)
+		err = table.AddAttributeClass(ctx, tx, numberAc)
+		assert.NoError(t, err)
+		for idx, value := range []string{"apple", "avocado", "banana", "apricot", "cherry"} {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			textAttr, err := textAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = textAttr.SetValue(map[string]interface{}{"value": value})
+			assert.NoError(t, err)
+			err = textAc.Update(ctx, tx, obj.ObjectId(), textAttr)
+			assert.NoError(t, err)
+			numberAttr, err := numberAc.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+			err = numberAttr.SetValue(map[string]interface{}{"value": float64(idx + 1)})
+			assert.NoError(t, err)
+			err = numberAc.Update(ctx, tx, obj.ObjectId(), numberAttr)
+			assert.NoError(t, err)
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		textOf := func(objList []common.Object) (textList []string) {
+			textList = []string{}
+			for _, obj := range objList {
+				attr, err := textAc.FromObject(obj)
+				assert.NoError(t, err)
+				textList = append(textList, attr.String())
+			}
+			return
+		}
+
+		cond := query.And(
+			query.Text(textAc).StartsWith("a"),
+			query.Or(query.Number(numberAc).Gt(3), query.Number(numberAc).In(1)),
+		)
+		order := []common.Ordering{query.Desc(numberAc).NullsLast()}
+		objList, err := table.Query().Where(cond).Sort(order...).Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"apricot", "apple"}, textOf(objList))
+
+		// 序列化为json筛选和排序
+		filter, err := query.MarshalFilter(cond)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"$and":[{"%[1]v":{"starts_with":"a"}},{"$or":[{"%[2]v":{"gt":3}},{"%[2]v":{"in":[1]}}]}]}`,
+			textAc.ClassId(), numberAc.ClassId()), filter)
+		orderJSON, err := query.MarshalOrder(query.Desc(numberAc).NullsLast(), query.Asc(textAc).Collate("nocase"))
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`[{"field":"%v","mode":"desc","nulls":"last"},{"collation":"nocase","field":"%v","mode":"asc"}]`,
+			numberAc.ClassId(), textAc.ClassId()), orderJSON)
+
+		// json筛选与类型化的条件结果一致
+		objList, err = table.Query().Filter(ctx, tx, filter).OrderBy(ctx, tx, orderJSON).Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"apricot", "apple"}, textOf(objList))
+
+		// 保存到视图中
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		err = view.Filter(tx, filter)
+		assert.NoError(t, err)
+		err = view.SortBy(tx, orderJSON)
+		assert.NoError(t, err)
+		result, err := view.Query(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"apricot", "apple"}, textOf(result.Raw()))
+
+		// Where与Filter同时生效
+		objList, err = table.Query().
+			Where(query.Not(query.Text(textAc).Eq("apple"))).
+			Filter(ctx, tx, filter).
+			Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"apricot"}, textOf(objList))
+
+		// 零值表示没有筛选
+		objList, err = table.Query().Where(query.Cond{}).Find(ctx, tx)
+		assert.NoError(t, err)
+		assert.Equal(t, 5, len(objList))
+		// 没有子条件的连接不是有效的筛选
+		_, err = table.Query().Where(query.And()).Find(ctx, tx)
+		assert.Error(t, err)
+		_, err = table.Query().Where(query.Or(query.Text(textAc).Eq("apple"), query.Not())).Find(ctx, tx)
+		assert.Error(t, err)
+		_, err = query.MarshalFilter(query.And())
+		assert.Error(t, err)
+
+		// 与Filter一样校验属性和操作，错误路径与序列化后的json一致
+		otherAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		checkWhere := func(cond query.Cond, path string, code string) {
+			_, err := table.Query().Where(cond).Find(ctx, tx)
+			var errList query.ValidationErrors
+			if assert.True(t, errors.As(err, &errList)) && assert.Equal(t, 1, len(errList)) {
+				assert.Equal(t, path, errList[0].Path)
+				assert.Equal(t, code, errList[0].Code)
+			}
+		}
+		checkWhere(query.Text(otherAc).Eq("apple"), otherAc.ClassId().String(), query.ValidateUnknownField)
+		checkWhere(query.Or(query.Text(textAc).Eq("apple"), query.Op(textAc, "gt", 1)),
+			fmt.Sprintf("$or.1.%v.gt", textAc.ClassId()), query.ValidateUnsupportedOp)
+		checkWhere(query.Not(query.Op(numberAc, "gt", "x")),
+			fmt.Sprintf("$not.0.%v.gt", numberAc.ClassId()), query.ValidateInvalidValue)
+
+		// 属性类型不符
+		_, err = table.Query().Where(query.Number(textAc).Gt(1)).Find(ctx, tx)
+		assert.Error(t, err)
+		_, err = query.MarshalFilter(query.And(query.Number(textAc).Gt(1)))
+		assert.Error(t, err)
+		_, err = table.Query().Sort(query.Order{}).Find(ctx, tx)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Filter Expression", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		frontAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		err = frontAc.Set(ctx, tx, utils.JSONMap{"name": "Front", "key": "front"})
+		assert.NoError(t, err)
+		easeAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		err = easeAc.Set(ctx, tx, utils.JSONMap{"name": "Ease", "key": "ease"})
+		assert.NoError(t, err)
+		tagAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeMultiSelect)
+		assert.NoError(t, err)
+		err = tagAc.Set(ctx, tx, utils.JSONMap{
+			"name":    "Card Tags",
+			"key":     "tags",
+			"options": `[{"name":"hard"},{"name":"easy"}]`,
+		})
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{frontAc, easeAc, tagAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+		dataList := []struct {
+			front string
+			ease  float64
+			tags  []interface{}
+		}{
+			{"verb list", 2.0, []interface{}{"hard"}},
+			{"noun", 3.0, []interface{}{}},
+			{"verbose", 2.7, []interface{}{"hard", "easy"}},
+			{"adjective", 1.5, []interface{}{"easy"}},
+		}
+		for _, data := range dataList {
+			obj, err := sqlite.CreateObject(ctx, tx)
+			assert.NoError(t, err)
+			for ac, value := range map[common.AttributeClass]interface{}{frontAc: data.front, easeAc: data.ease, tagAc: data.tags} {
+				attr, err := ac.Insert(ctx, tx, obj.ObjectId())
+				assert.NoError(t, err)
+				err = attr.SetValue(map[string]interface{}{"value": value})
+				assert.NoError(t, err)
+				err = ac.Update(ctx, tx, obj.ObjectId(), attr)
+				assert.NoError(t, err)
+			}
+			err = table.Insert(ctx, tx, obj.ObjectId())
+			assert.NoError(t, err)
+		}
+		filterExpr := func(expr string) (frontList []string) {
+			frontList = []string{}
+			objList, err := table.Query().FilterExpr(ctx, tx, expr).Find(ctx, tx)
+			if !assert.NoError(t, err, expr) {
+				return
+			}
+			for _, obj := range objList {
+				attr, err := frontAc.FromObject(obj)
+				assert.NoError(t, err)
+				frontList = append(frontList, attr.String())
+			}
+			return
+		}
+
+		// not优先于and，and优先于or
+		assert.Equal(t, []string{"noun", "verbose", "adjective"},
+			filterExpr(`Front ~ "verb" and Ease > 2.5 or not tags has "hard"`))
+		assert.Equal(t, []string{"noun", "verbose"}, filterExpr(`(Front ~ "verb" OR front = "noun") AND Ease >= 2.7`))
+		assert.Equal(t, []string{"noun", "adjective"}, filterExpr(`front in ["noun", "adjective"]`))
+		assert.Equal(t, []string{"verb list", "verbose"}, filterExpr(`front not in ["noun", "adjective"]`))
+		assert.Equal(t, []string{"verbose"}, filterExpr(`tags has ["hard", "easy"]`))
+		assert.Equal(t, []string{"noun"}, filterExpr(`tags is empty`))
+		assert.Equal(t, []string{"verb list", "verbose", "adjective"}, filterExpr("`Card Tags` is not empty"))
+		assert.Equal(t, []string{"verb list", "noun", "verbose"}, filterExpr(`not ease < 2`))
+		assert.Equal(t, []string{"verb list", "adjective"}, filterExpr(`ease in [2, 1.5] and front !~ "\"x\""`))
+		assert.Equal(t, []string{"verb list", "noun", "verbose", "adjective"}, filterExpr(`  `))
+		// 单独的字符串为全文搜索
+		assert.Equal(t, []string{"adjective"}, filterExpr(`"adjective" or Ease > 5`))
+		// 属性也可以使用class id
+		assert.Equal(t, []string{"adjective"}, filterExpr(fmt.Sprintf(`%v < 2`, easeAc.ClassId())))
+
+		// 与json筛选的语法树一致
+		cond, err := query.ParseExpr(ctx, tx, sqlite, table, `Ease > 2.5 and not tags has "hard"`)
+		assert.NoError(t, err)
+		filter, err := query.MarshalFilter(cond)
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf(`{"$and":[{"%v":{"gt":2.5}},{"$not":[{"%v":{"contains_any":"hard"}}]}]}`,
+			easeAc.ClassId(), tagAc.ClassId()), filter)
+
+		// 错误指向表达式中的列
+		for expr, column := range map[string]int{
+			`Frnt ~ "x"`:                    1,
+			`Front ~ "verb" and Ease > "x"`: 27,
+			`tags has "missing"`:            10,
+			`Front > "a"`:                   7,
+			`Front ~ "verb" and`:            19,
+			`Front ~ "verb`:                 9,
+			`Front ~ "verb")`:               15,
+			`Ease > two`:                    8,
+			`tags is not`:                   12,
+			`Front ! "a"`:                   7,
+		} {
+			_, err := query.ParseExpr(ctx, tx, sqlite, table, expr)
+			exprErr, ok := err.(*query.ExprError)
+			if assert.True(t, ok, expr) {
+				assert.Equal(t, column, exprErr.Column, exprErr.Error())
+			}
+		}
+		_, err = table.Query().FilterExpr(ctx, tx, `Front ~`).Find(ctx, tx)
+		assert.Error(t, err)
+	})
+
+	t.Run("Test Filter Validation", func(t *testing.T) {
+		cleanupDatabase()
+		tx, err := sqlite.WriteTx(ctx)
+		assert.NoError(t, err)
+		defer tx.Commit()
+
+		textAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		numberAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeNumber)
+		assert.NoError(t, err)
+		statusAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeSelect)
+		assert.NoError(t, err)
+		err = statusAc.Set(ctx, tx, utils.JSONMap{"options": `[{"name":"todo"},{"name":"done"}]`})
+		assert.NoError(t, err)
+		otherAc, err := sqlite.CreateAttributeClass(ctx, tx, attribute.AttributeTypeText)
+		assert.NoError(t, err)
+		table, err := sqlite.CreateTable(ctx, tx)
+		assert.NoError(t, err)
+		for _, ac := range []common.AttributeClass{textAc, numberAc, statusAc} {
+			err = table.AddAttributeClass(ctx, tx, ac)
+			assert.NoError(t, err)
+		}
+		text, number, status, other := textAc.ClassId(), numberAc.ClassId(), statusAc.ClassId(), otherAc.ClassId()
+
+		// 一次返回全部错误，路径可以直接用于gjson
+		filter := fmt.Sprintf(`{"$and":[
+			{"%[1]v":{"gt":1}},
+			{"%[2]v":{"gt":"x"}},
+			{"%[4]v":{"eq":"x"}},
+			{"$or":[]},
+			{"%[2]v":{"between":[1]}},
+			{"%[3]v":{"is":"missing"}},
+			{"%[1]v":{"eq":"a","neq":"b"}},
+			{"%[2]v":{"gte":1}}
+		]}`, text, number, status, other)
+		errList, err := query.ValidateFilter(ctx, tx, sqlite, table, filter)
+		assert.NoError(t, err)
+		type pathCode struct{ Path, Code string }
+		got := []pathCode{}
+		for _, item := range errList {
+			got = append(got, pathCode{item.Path, item.Code})
+			assert.True(t, gjson.Get(filter, item.Path).Exists(), item.Path)
+		}
+		assert.Equal(t, []pathCode{
+			{fmt.Sprintf("$and.0.%v.gt", text), query.ValidateUnsupportedOp},
+			{fmt.Sprintf("$and.1.%v.gt", number), query.ValidateInvalidValue},
+			{fmt.Sprintf("$and.2.%v", other), query.ValidateUnknownField},
+			{"$and.3.$or", query.ValidateSyntax},
+			{fmt.Sprintf("$and.4.%v.between", number), query.ValidateInvalidValue},
+			{fmt.Sprintf("$and.5.%v.is", status), query.ValidateInvalidValue},
+			{fmt.Sprintf("$and.6.%v", text), query.ValidateSyntax},
+		}, got)
+
+		for _, valid := range []string{
+			`{}`,
+			fmt.Sprintf(`{"$or":[{"%v":{"is":"todo"}},{"$not":[{"%v":{"is_empty":null}}]}]}`, status, text),
+			`{"$fts":{"search":"cat OR dog"}}`,
+			fmt.Sprintf(`{"%v":{"contains_any":"todo"}}`, status),
+			fmt.Sprintf(`{"%v":{"in":[]}}`, text),
+		} {
+			errList, err = query.ValidateFilter(ctx, tx, sqlite, table, valid)
+			assert.NoError(t, err)
+			assert.Equal(t, 0, len(errList), valid)
+		}
+		for filter, code := range map[string]string{
+			`[]`:                     query.ValidateSyntax,
+			`{"a":`:                  query.ValidateSyntax,
+			`{"$and":{}}`:            query.ValidateSyntax,
+			`{"$fts":{"search":1}}`:  query.ValidateInvalidValue,
+			`{"$fts":{"find":"x"}}`:  query.ValidateUnsupportedOp,
+			`{"not an id":{"eq":1}}`: query.ValidateUnknownField,
+			fmt.Sprintf(`{"%v":{"contains_any":[]}}`, status): query.ValidateInvalidValue,
+			fmt.Sprintf(`{"%v":{"contains_all":[]}}`, status): query.ValidateInvalidValue,
+		} {
+			errList, err = query.ValidateFilter(ctx, tx, sqlite, table, filter)
+			assert.NoError(t, err)
+			if assert.Equal(t, 1, len(errList), filter) {
+				assert.Equal(t, code, errList[0].Code, filter)
+			}
+		}
+
+		order := fmt.Sprintf(`[
+			{"field":"%[2]v","mode":"up"},
+			{"mode":"asc"},
+			{"field":"%[1]v","mode":"asc","collation":"klingon"},
+			3,
+			{"field":"%[2]v","mode":"desc","nulls":"last"},
+			{"field":"$fts"}
+		]`, text, number)
+		errList, err = query.ValidateOrder(ctx, tx, sqlite, table, order)
+		assert.NoError(t, err)
+		got = []pathCode{}
+		for _, item := range errList {
+			got = append(got, pathCode{item.Path, item.Code})
+		}
+		assert.Equal(t, []pathCode{
+			{"0.mode", query.ValidateInvalidValue},
+			{"1.field", query.ValidateSyntax},
+			{"2", query.ValidateInvalidValue},
+			{"3", query.ValidateSyntax},
+		}, got)
+
+		// 视图在保存之前校验，校验失败时不修改
+		view, err := table.NewView(ctx, tx)
+		assert.NoError(t, err)
+		validFilter := fmt.Sprintf(`{"%v":{"gte":1}}`, number)
+		err = view.Filter(tx, validFilter)
+		assert.NoError(t, err)
+		err = view.Filter(tx, filter)
+		var validationErrors query.ValidationErrors
+		if assert.True(t, errors.As(err, &validationErrors)) {
+			assert.Equal(t, 7, len(validationErrors))
+		}
+		err = view.Set(ctx, tx, utils.JSONMap{"order": `[{"field":"$fts","mode":"sideways"}]`})
+		assert.True(t, errors.As(err, &validationErrors))
+		assert.Equal(t, validFilter, gjson.Get(view.Marshal(), "filter").Raw)
+		view, err = table.View(ctx, tx, view.ViewId())
+		assert.NoError(t, err)
+		assert.Equal(t, validFilter, gjson.Get(view.Marshal(), "filter").Raw)
+
+		// 临时查询在Find时返回校验错误
+		_, err = table.Query().Filter(ctx, tx, fmt.Sprintf(`{"%v":{"gt":"x"}}`, number)).Find(ctx, tx)
+		assert.True(t, errors.As(err, &validationErrors))
+	})
+}
+
+func SetAC(t *testing.T, ctx context.Context, tx tx.WriteTx, ac common.AttributeClass, i int) (err error) {
+
+	switch ac.Type() {
+	case attribute.AttributeTypeText:
+		newName := fmt.Sprintf("text_%d", i)
+		err = ac.Set(ctx, tx, map[string]interface{}{
+			"name": newName,
+		})
+		assert.NoError(t, err)
+	case attribute.AttributeTypeNumber:
+		newName := fmt.Sprintf("number_%d", i)
+		err = ac.Set(ctx, tx, map[string]interface{}{
+			"name": newName,
+		})
+		assert.NoError(t, err)
+	case attribute.AttributeTypeLink:
+		queryAcid := `SELECT class_id FROM attribute_classes WHERE attribute_type != 'link' limit 3`
+		rows, err := tx.Query(queryAcid)
+		assert.NoError(t, err)
+		buf := &bytes.Buffer{}
+		buf.WriteString("[")
+		idx := 0
+		for rows.Next() {
+			if idx != 0 {
+				buf.WriteString(",")
+			}
+			idx++
+			var acid common.AttributeClassId
+			rows.Scan(&acid)
+			buf.WriteString(fmt.Sprintf(`"%v"`, acid))
+		}
+		buf.WriteString("]")
+
+		nvalue := buf.String()
+		newName := fmt.Sprintf("link_%d", i)
+		err = ac.Set(ctx, tx, map[string]interface{}{
+			"dep_attribute": nvalue,
+			"name":          newName,
+		})
+		assert.NoError(t, err)
+	default:
+		err = fmt.Errorf("unsupport type")
+		return
+	}
+
+	assert.NoError(t, err)
+	return
+}
+
+func SetValue(t *testing.T, ctx context.Context, tx tx.WriteTx, ac common.AttributeClass, oid common.ObjectId, i, j int) (err error) {
+	attr, err := ac.Insert(ctx, tx, oid)
+	assert.NoError(t, err)
+	switch ac.Type() {
+	case attribute.AttributeTypeText:
+		nvalue := fmt.Sprintf("测试_%d_%d", j, i)
+		err = attr.SetValue(map[string]interface{}{
+			"value": nvalue,
+		})
+	case attribute.AttributeTypeNumber:
+		nvalue := 1000*j + i
+		err = attr.SetValue(map[string]interface{}{
+			"value": nvalue,
+		})
+	case attribute.AttributeTypeLink:
+		nvalue := fmt.Sprintf(`["%v"]`, oid)
+		err = attr.SetValue(map[string]interface{}{
+			"update": nvalue,
+			"ctx":    ctx,
+			"tx":     tx,
+		})
+	default:
+		err = fmt.Errorf("unsupport type")
+		return
+	}
+	assert.NoError(t, err)
+	err = ac.Update(ctx, tx, oid, attr)
+	assert.NoError(t, err)
+	return
+}
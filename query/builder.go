@@ -0,0 +1,384 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"paroket/attribute"
+	"paroket/common"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// 在代码中构建的筛选条件，与解析json筛选得到相同的语法树，也可以序列化为视图中保存的json
+//
+//	query.And(query.Text(name).Eq("x"), query.Number(price).Gt(3))
+//
+// 零值表示没有筛选，构建中的错误在使用或序列化时返回
+type Cond struct {
+	connect  string
+	children []Cond
+	key      string
+	field    common.FilterField
+	op       string
+	value    interface{}
+	err      error
+}
+
+func And(conds ...Cond) Cond {
+	return connectCond(opBytesAnd, conds)
+}
+
+func Or(conds ...Cond) Cond {
+	return connectCond(opBytesOr, conds)
+}
+
+// 子条件同时满足时取反
+func Not(conds ...Cond) Cond {
+	return connectCond(opBytesNot, conds)
+}
+
+func connectCond(connect string, conds []Cond) Cond {
+	return Cond{
+		connect:  connect,
+		children: append([]Cond{}, conds...),
+	}
+}
+
+// 属性上的任意操作，op和value与json筛选中的一致
+func Op(ac common.AttributeClass, op string, value interface{}) Cond {
+	return Cond{
+		key:   ac.ClassId().String(),
+		field: ac,
+		op:    op,
+		value: value,
+	}
+}
+
+// 全文搜索，支持的语法见fts包
+func Search(search string) Cond {
+	return Cond{
+		key:   ftsField,
+		op:    "search",
+		value: search,
+	}
+}
+
+func (c Cond) isZero() bool {
+	return c.connect == "" && c.key == "" && c.err == nil
+}
+
+// 生成与parseFilterHelper相同的语法树，值经过一次json转换，与解析得到的类型一致
+// 与ValidateFilter一样检查属性是否在表中以及操作和值的类型，错误的路径与序列化后的json一致
+func (c Cond) node(table common.Table) (node *filterNode, err error) {
+	return c.nodeAt(table, "")
+}
+
+func (c Cond) nodeAt(table common.Table, path string) (node *filterNode, err error) {
+	if c.err != nil {
+		err = c.err
+		return
+	}
+	if c.connect != "" {
+		node = &filterNode{
+			Type:       connection,
+			Connect:    c.connect,
+			ChildNodes: []filterNode{},
+		}
+		if len(c.children) == 0 {
+			err = fmt.Errorf("invaild empty condition in %s", c.connect)
+			return
+		}
+		for idx, child := range c.children {
+			if child.isZero() {
+				err = fmt.Errorf("invaild empty condition in %s", c.connect)
+				return
+			}
+			var childNode *filterNode
+			if childNode, err = child.nodeAt(table, joinPath(joinPath(path, c.connect), fmt.Sprint(idx))); err != nil {
+				return
+			}
+			node.ChildNodes = append(node.ChildNodes, *childNode)
+		}
+		return
+	}
+	if c.key == "" {
+		err = fmt.Errorf("invaild empty condition")
+		return
+	}
+	data, err := json.Marshal(c.value)
+	if err != nil {
+		return
+	}
+	field := c.field
+	keyPath := joinPath(path, c.key)
+	v := &validator{table: table}
+	if c.key == ftsField {
+		field = NewFtsFilter(table)
+	} else {
+		v.inTable(keyPath, c.field.(common.AttributeClass).ClassId())
+	}
+	value := gjson.ParseBytes(data)
+	if len(v.errList) == 0 {
+		v.operation(joinPath(keyPath, c.op), field, c.op, value)
+	}
+	if len(v.errList) != 0 {
+		err = v.errList
+		return
+	}
+	node = &filterNode{
+		Type:        operation,
+		filterField: field,
+		filterValue: map[string]interface{}{
+			"op":    c.op,
+			"value": value.Value(),
+		},
+	}
+	return
+}
+
+func (c Cond) MarshalJSON() (data []byte, err error) {
+	if c.err != nil {
+		err = c.err
+		return
+	}
+	switch {
+	case c.connect != "" && len(c.children) == 0:
+		err = fmt.Errorf("invaild empty condition in %s", c.connect)
+	case c.connect != "":
+		data, err = json.Marshal(map[string][]Cond{c.connect: c.children})
+	case c.key != "":
+		data, err = json.Marshal(map[string]map[string]interface{}{c.key: {c.op: c.value}})
+	default:
+		data = []byte("{}")
+	}
+	return
+}
+
+// 序列化为View.Filter和Query.Filter使用的json筛选
+func MarshalFilter(cond Cond) (filter string, err error) {
+	data, err := json.Marshal(cond)
+	if err != nil {
+		return
+	}
+	filter = string(data)
+	return
+}
+
+// 检查属性类型，类型不符时生成的条件都带有错误
+func typedField(ac common.AttributeClass, attrType common.AttributeType) fieldCond {
+	f := fieldCond{ac: ac}
+	if ac.Type() != attrType {
+		f.err = fmt.Errorf("attribute %v is not a %s attribute", ac.ClassId(), attrType)
+	}
+	return f
+}
+
+type fieldCond struct {
+	ac  common.AttributeClass
+	err error
+}
+
+func (f fieldCond) op(op string, value interface{}) Cond {
+	if f.err != nil {
+		return Cond{err: f.err}
+	}
+	return Op(f.ac, op, value)
+}
+
+type TextField struct{ fieldCond }
+
+func Text(ac common.AttributeClass) TextField {
+	return TextField{typedField(ac, attribute.AttributeTypeText)}
+}
+
+func (f TextField) Eq(value string) Cond         { return f.op("eq", value) }
+func (f TextField) Neq(value string) Cond        { return f.op("neq", value) }
+func (f TextField) EqNocase(value string) Cond   { return f.op("eq_nocase", value) }
+func (f TextField) Like(value string) Cond       { return f.op("like", value) }
+func (f TextField) Unlike(value string) Cond     { return f.op("unlike", value) }
+func (f TextField) StartsWith(value string) Cond { return f.op("starts_with", value) }
+func (f TextField) EndsWith(value string) Cond   { return f.op("ends_with", value) }
+func (f TextField) Regexp(pattern string) Cond   { return f.op("regexp", pattern) }
+func (f TextField) In(values ...string) Cond     { return f.op("in", append([]string{}, values...)) }
+func (f TextField) NotIn(values ...string) Cond  { return f.op("not_in", append([]string{}, values...)) }
+func (f TextField) IsEmpty() Cond                { return f.op("is_empty", true) }
+func (f TextField) IsNotEmpty() Cond             { return f.op("is_not_empty", true) }
+func (f TextField) LenEq(n int) Cond             { return f.op("len_eq", n) }
+func (f TextField) LenGt(n int) Cond             { return f.op("len_gt", n) }
+func (f TextField) LenLt(n int) Cond             { return f.op("len_lt", n) }
+
+type NumberField struct{ fieldCond }
+
+func Number(ac common.AttributeClass) NumberField {
+	return NumberField{typedField(ac, attribute.AttributeTypeNumber)}
+}
+
+func (f NumberField) Eq(value float64) Cond         { return f.op("eq", value) }
+func (f NumberField) Neq(value float64) Cond        { return f.op("neq", value) }
+func (f NumberField) Gt(value float64) Cond         { return f.op("gt", value) }
+func (f NumberField) Gte(value float64) Cond        { return f.op("gte", value) }
+func (f NumberField) Lt(value float64) Cond         { return f.op("lt", value) }
+func (f NumberField) Lte(value float64) Cond        { return f.op("lte", value) }
+func (f NumberField) Between(min, max float64) Cond { return f.op("between", []float64{min, max}) }
+func (f NumberField) In(values ...float64) Cond     { return f.op("in", append([]float64{}, values...)) }
+func (f NumberField) NotIn(values ...float64) Cond {
+	return f.op("not_in", append([]float64{}, values...))
+}
+func (f NumberField) Mod(divisor, rem float64) Cond { return f.op("mod", []float64{divisor, rem}) }
+func (f NumberField) IntEq(value int64) Cond        { return f.op("int_eq", value) }
+func (f NumberField) IsInteger() Cond               { return f.op("is_integer", true) }
+func (f NumberField) IsEmpty() Cond                 { return f.op("is_empty", true) }
+func (f NumberField) IsNotEmpty() Cond              { return f.op("is_not_empty", true) }
+
+type CheckboxField struct{ fieldCond }
+
+func Checkbox(ac common.AttributeClass) CheckboxField {
+	return CheckboxField{typedField(ac, attribute.AttributeTypeCheckbox)}
+}
+
+func (f CheckboxField) Is(checked bool) Cond    { return f.op("is", checked) }
+func (f CheckboxField) IsNot(checked bool) Cond { return f.op("is_not", checked) }
+
+// 选项可以使用选项id或名称
+type SelectField struct{ fieldCond }
+
+func Select(ac common.AttributeClass) SelectField {
+	return SelectField{typedField(ac, attribute.AttributeTypeSelect)}
+}
+
+func (f SelectField) Is(option string) Cond    { return f.op("is", option) }
+func (f SelectField) IsNot(option string) Cond { return f.op("is_not", option) }
+func (f SelectField) ContainsAny(options ...string) Cond {
+	return f.op("contains_any", append([]string{}, options...))
+}
+func (f SelectField) IsEmpty() Cond { return f.op("is_empty", true) }
+
+type MultiSelectField struct{ fieldCond }
+
+func MultiSelect(ac common.AttributeClass) MultiSelectField {
+	return MultiSelectField{typedField(ac, attribute.AttributeTypeMultiSelect)}
+}
+
+func (f MultiSelectField) Is(option string) Cond    { return f.op("is", option) }
+func (f MultiSelectField) IsNot(option string) Cond { return f.op("is_not", option) }
+func (f MultiSelectField) ContainsAny(options ...string) Cond {
+	return f.op("contains_any", append([]string{}, options...))
+}
+func (f MultiSelectField) ContainsAll(options ...string) Cond {
+	return f.op("contains_all", append([]string{}, options...))
+}
+func (f MultiSelectField) IsEmpty() Cond { return f.op("is_empty", true) }
+
+// 日期为2006-01-02格式时表示整天，也可以带有时间
+type DateField struct{ fieldCond }
+
+func Date(ac common.AttributeClass) DateField {
+	return DateField{typedField(ac, attribute.AttributeTypeDate)}
+}
+
+func (f DateField) Before(date string) Cond        { return f.op("before", date) }
+func (f DateField) After(date string) Cond         { return f.op("after", date) }
+func (f DateField) On(date string) Cond            { return f.op("on", date) }
+func (f DateField) Between(start, end string) Cond { return f.op("between", []string{start, end}) }
+func (f DateField) WithinPastNDays(n int) Cond     { return f.op("within_past_n_days", n) }
+func (f DateField) WithinNextNDays(n int) Cond     { return f.op("within_next_n_days", n) }
+func (f DateField) IsEmpty() Cond                  { return f.op("is_empty", true) }
+
+// 排序项，零值不是有效的排序
+type Order struct {
+	key   string
+	field common.SortField
+	value map[string]interface{}
+}
+
+func Asc(ac common.AttributeClass) Order {
+	return newOrder(ac, "asc")
+}
+
+func Desc(ac common.AttributeClass) Order {
+	return newOrder(ac, "desc")
+}
+
+// 按全文搜索的相关度从高到低排序，搜索词取自筛选中的全文搜索
+func Relevance() Order {
+	return Order{
+		key:   ftsField,
+		value: map[string]interface{}{"mode": "asc"},
+	}
+}
+
+func newOrder(ac common.AttributeClass, mode string) Order {
+	return Order{
+		key:   ac.ClassId().String(),
+		field: ac,
+		value: map[string]interface{}{"mode": mode},
+	}
+}
+
+func (o Order) with(key string, value interface{}) Order {
+	v := map[string]interface{}{}
+	for k, val := range o.value {
+		v[k] = val
+	}
+	v[key] = value
+	o.value = v
+	return o
+}
+
+func (o Order) NullsFirst() Order {
+	return o.with("nulls", "first")
+}
+
+func (o Order) NullsLast() Order {
+	return o.with("nulls", "last")
+}
+
+// 文本的排序规则，见collate包
+func (o Order) Collate(collation string) Order {
+	return o.with("collation", collation)
+}
+
+// 生成与ParseOrder相同的排序节点
+func (o Order) node(qb *queryImpl) (node sortNode, err error) {
+	if o.key == "" {
+		err = fmt.Errorf("invaild empty order")
+		return
+	}
+	value := map[string]interface{}{}
+	for k, v := range o.value {
+		value[k] = v
+	}
+	node = sortNode{SortField: o.field, SortValue: value}
+	if o.key == ftsField {
+		node.SortField = newFtsSort(qb)
+	}
+	return
+}
+
+func (o Order) MarshalJSON() (data []byte, err error) {
+	if o.key == "" {
+		err = fmt.Errorf("invaild empty order")
+		return
+	}
+	value := map[string]interface{}{"field": o.key}
+	for k, v := range o.value {
+		value[k] = v
+	}
+	data, err = json.Marshal(value)
+	return
+}
+
+// 序列化为View.SortBy和Query.OrderBy使用的json排序
+func MarshalOrder(orders ...Order) (order string, err error) {
+	itemList := []string{}
+	for _, o := range orders {
+		var data []byte
+		if data, err = json.Marshal(o); err != nil {
+			return
+		}
+		itemList = append(itemList, string(data))
+	}
+	order = fmt.Sprintf("[%s]", strings.Join(itemList, ","))
+	return
+}
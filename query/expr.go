@@ -0,0 +1,535 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"paroket/attribute"
+	"paroket/common"
+	"paroket/tx"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// 筛选表达式：
+//
+//	比较        Ease > 2.5 / Front = "verb" / Done = true
+//	包含        Front ~ "verb" / Front !~ "verb" / Tags has "hard" / Tags has ["a", "b"]
+//	列表        Status in ["todo", "doing"] / Status not in ["done"]
+//	空值        Due is empty / Due is not empty
+//	全文搜索    "black cat"
+//	连接        not > and > or，可以使用括号分组
+//
+// 属性使用key、名称或class id，名称中有空格等字符时用反引号包围：`Due Date` < "2024-01-01"
+// 关键字不区分大小写，字符串使用双引号，支持\转义
+
+// 表达式中的错误，Column为出错位置在输入中的列，从1开始
+type ExprError struct {
+	Column int
+	Msg    string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("filter expr: %s at column %d", e.Msg, e.Column)
+}
+
+type exprTokenKind int
+
+const (
+	exprEOF exprTokenKind = iota
+	exprWord
+	exprString
+	exprIdent // 反引号包围的属性
+	exprOp
+	exprLParen
+	exprRParen
+	exprLBracket
+	exprRBracket
+	exprComma
+)
+
+type exprToken struct {
+	kind exprTokenKind
+	text string
+	pos  int
+}
+
+func (t exprToken) column() int {
+	return t.pos + 1
+}
+
+func exprErrorf(pos int, format string, a ...interface{}) error {
+	return &ExprError{Column: pos + 1, Msg: fmt.Sprintf(format, a...)}
+}
+
+func lexExpr(src string) (tokens []exprToken, err error) {
+	runes := []rune(src)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, exprToken{exprLParen, "(", i})
+			i++
+		case r == ')':
+			tokens = append(tokens, exprToken{exprRParen, ")", i})
+			i++
+		case r == '[':
+			tokens = append(tokens, exprToken{exprLBracket, "[", i})
+			i++
+		case r == ']':
+			tokens = append(tokens, exprToken{exprRBracket, "]", i})
+			i++
+		case r == ',':
+			tokens = append(tokens, exprToken{exprComma, ",", i})
+			i++
+		case strings.ContainsRune("=!<>~", r):
+			start := i
+			i++
+			if i < len(runes) {
+				switch string(runes[start : i+1]) {
+				case "!=", "!~", "<=", ">=":
+					i++
+				}
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				err = exprErrorf(start, "unexpected %q", op)
+				return
+			}
+			tokens = append(tokens, exprToken{exprOp, op, start})
+		case r == '"':
+			start := i
+			i++
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\\' {
+					i += 2
+					continue
+				}
+				if runes[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				i++
+			}
+			if !closed {
+				err = exprErrorf(start, "unterminated string")
+				return
+			}
+			var text string
+			if text, err = strconv.Unquote(string(runes[start:i])); err != nil {
+				err = exprErrorf(start, "invaild string %s", string(runes[start:i]))
+				return
+			}
+			tokens = append(tokens, exprToken{exprString, text, start})
+		case r == '`':
+			start := i
+			i++
+			for i < len(runes) && runes[i] != '`' {
+				i++
+			}
+			if i == len(runes) {
+				err = exprErrorf(start, "unterminated attribute name")
+				return
+			}
+			i++
+			tokens = append(tokens, exprToken{exprIdent, string(runes[start+1 : i-1]), start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()[],=!<>~\"`", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, exprToken{exprWord, string(runes[start:i]), start})
+		}
+	}
+	tokens = append(tokens, exprToken{exprEOF, "", len(runes)})
+	return
+}
+
+type exprParser struct {
+	ctx    context.Context
+	tx     tx.ReadTx
+	db     common.Database
+	table  common.Table
+	tokens []exprToken
+	pos    int
+	fields map[string]common.AttributeClass
+}
+
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() exprToken {
+	tok := p.tokens[p.pos]
+	if tok.kind != exprEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) isKeyword(tok exprToken, keyword string) bool {
+	return tok.kind == exprWord && strings.EqualFold(tok.text, keyword)
+}
+
+func (p *exprParser) expectKeyword(keyword string) (err error) {
+	if tok := p.next(); !p.isKeyword(tok, keyword) {
+		err = exprErrorf(tok.pos, "expect %s, got %s", keyword, describeToken(tok))
+	}
+	return
+}
+
+func describeToken(tok exprToken) string {
+	switch tok.kind {
+	case exprEOF:
+		return "end of input"
+	case exprString:
+		return strconv.Quote(tok.text)
+	case exprIdent:
+		return fmt.Sprintf("`%s`", tok.text)
+	default:
+		return fmt.Sprintf("%q", tok.text)
+	}
+}
+
+// 解析筛选表达式，属性在表的列中查找，空表达式返回零值表示没有筛选
+// 每个比较在解析时构建一次，选项、日期等值的错误也指向表达式中的位置
+func ParseExpr(ctx context.Context, tx tx.ReadTx, db common.Database, table common.Table, src string) (cond Cond, err error) {
+	tokens, err := lexExpr(src)
+	if err != nil {
+		return
+	}
+	p := &exprParser{ctx: ctx, tx: tx, db: db, table: table, tokens: tokens}
+	if p.peek().kind == exprEOF {
+		return
+	}
+	if cond, err = p.parseOr(); err != nil {
+		return
+	}
+	if tok := p.peek(); tok.kind != exprEOF {
+		err = exprErrorf(tok.pos, "unexpected %s", describeToken(tok))
+	}
+	return
+}
+
+func (p *exprParser) parseOr() (cond Cond, err error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return
+	}
+	conds := []Cond{left}
+	for p.isKeyword(p.peek(), "or") {
+		p.next()
+		var right Cond
+		if right, err = p.parseAnd(); err != nil {
+			return
+		}
+		conds = append(conds, right)
+	}
+	cond = left
+	if len(conds) > 1 {
+		cond = Or(conds...)
+	}
+	return
+}
+
+func (p *exprParser) parseAnd() (cond Cond, err error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return
+	}
+	conds := []Cond{left}
+	for p.isKeyword(p.peek(), "and") {
+		p.next()
+		var right Cond
+		if right, err = p.parseUnary(); err != nil {
+			return
+		}
+		conds = append(conds, right)
+	}
+	cond = left
+	if len(conds) > 1 {
+		cond = And(conds...)
+	}
+	return
+}
+
+func (p *exprParser) parseUnary() (cond Cond, err error) {
+	if p.isKeyword(p.peek(), "not") {
+		p.next()
+		var child Cond
+		if child, err = p.parseUnary(); err != nil {
+			return
+		}
+		cond = Not(child)
+		return
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (cond Cond, err error) {
+	tok := p.next()
+	switch tok.kind {
+	case exprLParen:
+		if cond, err = p.parseOr(); err != nil {
+			return
+		}
+		if end := p.next(); end.kind != exprRParen {
+			err = exprErrorf(end.pos, "expect ), got %s", describeToken(end))
+		}
+		return
+	case exprString:
+		cond = Search(tok.text)
+		err = p.check(cond, tok)
+		return
+	case exprWord, exprIdent:
+		if tok.kind == exprWord && isExprKeyword(tok.text) {
+			err = exprErrorf(tok.pos, "expect attribute, got %s", describeToken(tok))
+			return
+		}
+		return p.parseCompare(tok)
+	default:
+		err = exprErrorf(tok.pos, "expect attribute, got %s", describeToken(tok))
+		return
+	}
+}
+
+func isExprKeyword(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "in", "has", "is", "empty", "true", "false":
+		return true
+	}
+	return false
+}
+
+// 比较符在各类型属性上对应的操作，!开头表示对操作取反
+var exprOpMap = map[common.AttributeType]map[string]string{
+	attribute.AttributeTypeText: {
+		"=": "eq", "!=": "neq", "~": "like", "!~": "unlike", "in": "in", "not in": "not_in",
+	},
+	attribute.AttributeTypeNumber: {
+		"=": "eq", "!=": "neq", ">": "gt", ">=": "gte", "<": "lt", "<=": "lte", "in": "in", "not in": "not_in",
+	},
+	attribute.AttributeTypeCheckbox: {
+		"=": "is", "!=": "is_not",
+	},
+	attribute.AttributeTypeSelect: {
+		"=": "is", "!=": "is_not", "has": "contains_any", "in": "contains_any", "not in": "!contains_any",
+	},
+	attribute.AttributeTypeMultiSelect: {
+		"=": "is", "!=": "is_not", "has": "contains_all", "in": "contains_any", "not in": "!contains_any",
+	},
+	attribute.AttributeTypeDate: {
+		"=": "on", "!=": "!on", "<": "before", ">": "after", "<=": "!after", ">=": "!before",
+	},
+	attribute.AttributeTypeLink: {
+		"~": "like", "has": "contains_all", "in": "contains_any", "not in": "!contains_any",
+	},
+	attribute.AttributeTypeFormula: {
+		"=": "eq", "!=": "neq", ">": "gt", ">=": "gte", "<": "lt", "<=": "lte", "~": "like",
+	},
+	attribute.AttributeTypeRollup: {
+		"=": "eq", "!=": "neq", ">": "gt", ">=": "gte", "<": "lt", "<=": "lte", "~": "like",
+	},
+}
+
+// 各类型属性的值类型，为空时接受任意的字符串、数值或布尔值
+var exprValueKind = map[common.AttributeType]string{
+	attribute.AttributeTypeText:        "string",
+	attribute.AttributeTypeNumber:      "number",
+	attribute.AttributeTypeCheckbox:    "bool",
+	attribute.AttributeTypeSelect:      "string",
+	attribute.AttributeTypeMultiSelect: "string",
+	attribute.AttributeTypeDate:        "string",
+	attribute.AttributeTypeLink:        "string",
+}
+
+func (p *exprParser) parseCompare(fieldTok exprToken) (cond Cond, err error) {
+	ac, err := p.resolveField(fieldTok)
+	if err != nil {
+		return
+	}
+	opTok := p.next()
+	op := ""
+	switch {
+	case opTok.kind == exprOp:
+		op = opTok.text
+	case p.isKeyword(opTok, "in"), p.isKeyword(opTok, "has"):
+		op = strings.ToLower(opTok.text)
+	case p.isKeyword(opTok, "not"):
+		if err = p.expectKeyword("in"); err != nil {
+			return
+		}
+		op = "not in"
+	case p.isKeyword(opTok, "is"):
+		empty := true
+		if p.isKeyword(p.peek(), "not") {
+			p.next()
+			empty = false
+		}
+		if err = p.expectKeyword("empty"); err != nil {
+			return
+		}
+		switch ac.Type() {
+		case attribute.AttributeTypeFormula, attribute.AttributeTypeRollup:
+			err = exprErrorf(opTok.pos, "%s attribute %s unsupport is empty", ac.Type(), fieldTok.text)
+			return
+		}
+		cond = Op(ac, "is_empty", empty)
+		err = p.check(cond, opTok)
+		return
+	default:
+		err = exprErrorf(opTok.pos, "expect operator after %s, got %s", fieldTok.text, describeToken(opTok))
+		return
+	}
+	target, ok := exprOpMap[ac.Type()][op]
+	if !ok {
+		err = exprErrorf(opTok.pos, "%s attribute %s unsupport operator %s", ac.Type(), fieldTok.text, op)
+		return
+	}
+
+	valueTok := p.peek()
+	kind := exprValueKind[ac.Type()]
+	var value interface{}
+	switch {
+	case op == "in" || op == "not in":
+		value, err = p.parseList(kind)
+	case op == "has" && valueTok.kind == exprLBracket:
+		value, err = p.parseList(kind)
+	case op == "has":
+		// 包含一个值时与包含任意一个相同
+		value, err = p.parseValue(kind)
+		target = strings.Replace(target, "contains_all", "contains_any", 1)
+	default:
+		value, err = p.parseValue(kind)
+	}
+	if err != nil {
+		return
+	}
+	negate := strings.HasPrefix(target, "!")
+	cond = Op(ac, strings.TrimPrefix(target, "!"), value)
+	if err = p.check(cond, valueTok); err != nil {
+		return
+	}
+	if negate {
+		cond = Not(cond)
+	}
+	return
+}
+
+func (p *exprParser) parseValue(kind string) (value interface{}, err error) {
+	tok := p.next()
+	switch {
+	case tok.kind == exprString:
+		value = tok.text
+	case p.isKeyword(tok, "true"), p.isKeyword(tok, "false"):
+		value = strings.EqualFold(tok.text, "true")
+	case tok.kind == exprWord:
+		var n float64
+		if n, err = strconv.ParseFloat(tok.text, 64); err != nil {
+			err = exprErrorf(tok.pos, "expect value, got %s, quote strings with \"", describeToken(tok))
+			return
+		}
+		value = n
+	default:
+		err = exprErrorf(tok.pos, "expect value, got %s", describeToken(tok))
+		return
+	}
+	if !matchValueKind(value, kind) {
+		err = exprErrorf(tok.pos, "expect %s value, got %s", kind, describeToken(tok))
+	}
+	return
+}
+
+func matchValueKind(value interface{}, kind string) bool {
+	switch value.(type) {
+	case string:
+		return kind == "" || kind == "string"
+	case float64:
+		return kind == "" || kind == "number"
+	case bool:
+		return kind == "" || kind == "bool"
+	}
+	return false
+}
+
+func (p *exprParser) parseList(kind string) (valueList []interface{}, err error) {
+	if tok := p.next(); tok.kind != exprLBracket {
+		err = exprErrorf(tok.pos, "expect [, got %s", describeToken(tok))
+		return
+	}
+	valueList = []interface{}{}
+	if p.peek().kind == exprRBracket {
+		p.next()
+		return
+	}
+	for {
+		var value interface{}
+		if value, err = p.parseValue(kind); err != nil {
+			return
+		}
+		valueList = append(valueList, value)
+		tok := p.next()
+		if tok.kind == exprRBracket {
+			return
+		}
+		if tok.kind != exprComma {
+			err = exprErrorf(tok.pos, "expect , or ], got %s", describeToken(tok))
+			return
+		}
+	}
+}
+
+// 在表的列中按key、名称、class id的顺序查找属性，key唯一，名称重复时需要使用key
+func (p *exprParser) resolveField(tok exprToken) (ac common.AttributeClass, err error) {
+	if p.fields == nil {
+		p.fields = map[string]common.AttributeClass{}
+		for _, acid := range p.table.Fields() {
+			var fieldAc common.AttributeClass
+			if fieldAc, err = p.db.OpenAttributeClass(p.ctx, p.tx, acid); err != nil {
+				return
+			}
+			p.fields[acid.String()] = fieldAc
+		}
+	}
+	for _, fieldAc := range p.fields {
+		if fieldAc.Key() == tok.text {
+			ac = fieldAc
+			return
+		}
+	}
+	for _, fieldAc := range p.fields {
+		if fieldAc.Name() != tok.text {
+			continue
+		}
+		if ac != nil {
+			err = exprErrorf(tok.pos, "attribute name %s is ambiguous, use the key instead", tok.text)
+			return
+		}
+		ac = fieldAc
+	}
+	if ac != nil {
+		return
+	}
+	if ac = p.fields[tok.text]; ac == nil {
+		err = exprErrorf(tok.pos, "unknown attribute %s", tok.text)
+	}
+	return
+}
+
+// 构建一次条件，将属性返回的错误定位到对应的位置
+func (p *exprParser) check(cond Cond, tok exprToken) (err error) {
+	node, err := cond.node(p.table)
+	if err != nil {
+		err = exprErrorf(tok.pos, "%s", err)
+		return
+	}
+	if _, _, err = node.BuildFilterHelper(p.ctx, p.tx); err != nil {
+		err = exprErrorf(tok.pos, "%s", err)
+	}
+	return
+}
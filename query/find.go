@@ -0,0 +1,173 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+)
+
+// 不保存的临时查询，Filter可以多次调用，多次的筛选同时生效；OrderBy多次调用时排序项依次追加
+// 解析中的错误在Find时返回
+type tableQuery struct {
+	qb  *queryImpl
+	err error
+}
+
+func NewQuery(table common.Table, db common.Database) common.Query {
+	return &tableQuery{
+		qb: NewQueryBuilder(table, db).(*queryImpl),
+	}
+}
+
+func (q *tableQuery) Filter(ctx context.Context, tx tx.ReadTx, filter string) common.Query {
+	if q.err != nil {
+		return q
+	}
+	if q.err = validate(ValidateFilter(ctx, tx, q.qb.db, q.qb.table, filter)); q.err != nil {
+		return q
+	}
+	prev := q.qb.filter
+	q.qb.filter = nil
+	if q.err = q.qb.ParseFilter(ctx, tx, filter); q.err != nil {
+		return q
+	}
+	node := q.qb.filter
+	q.qb.filter = prev
+	q.and(node)
+	return q
+}
+
+func (q *tableQuery) Where(cond common.Condition) common.Query {
+	if q.err != nil {
+		return q
+	}
+	c, ok := cond.(Cond)
+	if !ok {
+		q.err = fmt.Errorf("unsupport condition type:%T", cond)
+		return q
+	}
+	if c.isZero() {
+		return q
+	}
+	node, err := c.node(q.qb.table)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	q.and(node)
+	return q
+}
+
+func (q *tableQuery) FilterExpr(ctx context.Context, tx tx.ReadTx, expr string) common.Query {
+	if q.err != nil {
+		return q
+	}
+	cond, err := ParseExpr(ctx, tx, q.qb.db, q.qb.table, expr)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	return q.Where(cond)
+}
+
+// 与已有的筛选同时生效
+func (q *tableQuery) and(node *filterNode) {
+	switch {
+	case node == nil:
+	case q.qb.filter == nil:
+		q.qb.filter = node
+	default:
+		q.qb.filter = &filterNode{
+			Type:       connection,
+			Connect:    opBytesAnd,
+			ChildNodes: []filterNode{*q.qb.filter, *node},
+		}
+	}
+}
+
+func (q *tableQuery) OrderBy(ctx context.Context, tx tx.ReadTx, order string) common.Query {
+	if q.err != nil {
+		return q
+	}
+	if q.err = validate(ValidateOrder(ctx, tx, q.qb.db, q.qb.table, order)); q.err != nil {
+		return q
+	}
+	q.err = q.qb.ParseOrder(ctx, tx, order)
+	return q
+}
+
+// 将校验的错误列表作为一个错误返回
+func validate(errList ValidationErrors, err error) error {
+	if err != nil {
+		return err
+	}
+	if len(errList) != 0 {
+		return errList
+	}
+	return nil
+}
+
+func (q *tableQuery) Sort(orders ...common.Ordering) common.Query {
+	if q.err != nil {
+		return q
+	}
+	for _, order := range orders {
+		o, ok := order.(Order)
+		if !ok {
+			q.err = fmt.Errorf("unsupport ordering type:%T", order)
+			return q
+		}
+		var node sortNode
+		if node, q.err = o.node(q.qb); q.err != nil {
+			return q
+		}
+		q.qb.sort = append(q.qb.sort, node)
+	}
+	return q
+}
+
+func (q *tableQuery) Limit(limit int) common.Query {
+	if limit < 0 {
+		q.err = fmt.Errorf("invaild limit:%d", limit)
+		return q
+	}
+	q.qb.limit = limit
+	return q
+}
+
+func (q *tableQuery) Offset(offset int) common.Query {
+	if offset < 0 {
+		q.err = fmt.Errorf("invaild offset:%d", offset)
+		return q
+	}
+	q.qb.offset = offset
+	return q
+}
+
+func (q *tableQuery) Find(ctx context.Context, tx tx.ReadTx) (objList []common.Object, err error) {
+	if q.err != nil {
+		err = q.err
+		return
+	}
+	filterStmt, filterArgs, err := q.qb.BuildFilter(ctx, tx)
+	if err != nil {
+		return
+	}
+	orderStmt, orderArgs, err := q.qb.BuildSort(ctx, tx)
+	if err != nil {
+		return
+	}
+	queryStmt := fmt.Sprintf(`
+	SELECT object_id, json(data) FROM %s
+	%s
+	%s
+	LIMIT %d OFFSET %d`, q.qb.table.TableId().DataTable(), filterStmt, orderStmt, q.qb.limit, q.qb.offset)
+	rows, err := tx.Query(queryStmt, append(filterArgs, orderArgs...)...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	objList, err = common.QueryTableObjectList(ctx, q.qb.db, rows)
+	return
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"paroket/common"
+	"paroket/fts"
+	"paroket/tx"
+	"sync"
+)
+
+// 全文搜索在过滤和排序中使用的字段名
+const ftsField = "$fts"
+
+type ftsFilterField struct {
+	table common.Table
+}
+
+func NewFtsFilter(table common.Table) common.FilterField {
+	return &ftsFilterField{table: table}
+}
+
+// search的值为全文搜索语法，见fts包
+func (f *ftsFilterField) FilterOps() map[string]common.FilterValueType {
+	return map[string]common.FilterValueType{
+		"search": common.FilterValueString,
+	}
+}
+
+// 有fts5时使用MATCH查询索引表，否则在idx列上退化为LIKE匹配
+func (f *ftsFilterField) BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (stmt string, args []interface{}, err error) {
+	op, ok := v["op"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	val, ok := v["value"].(string)
+	if !ok {
+		err = fmt.Errorf("invaild query value:%s", v)
+		return
+	}
+	switch op {
+	case "search":
+		var node *fts.Node
+		if node, err = fts.Parse(val); err != nil {
+			return
+		}
+		ftsTable, ok := f.table.MetaInfo()["fts_table"].(string)
+		if !ok || !Fts5Enabled(tx) {
+			var likeStmt string
+			if likeStmt, args, err = node.Like("idx"); err != nil {
+				return
+			}
+			stmt = fmt.Sprintf(" %s ", likeStmt)
+			return
+		}
+		var tokenizer fts.Tokenizer
+		if tokenizer, err = TableTokenizer(f.table); err != nil {
+			return
+		}
+		var match string
+		if match, err = node.Match(tokenizer); err != nil {
+			return
+		}
+		stmt = fmt.Sprintf(` (rowid IN (SELECT rowid FROM %s WHERE %s MATCH ?)) `, ftsTable, ftsTable)
+		args = []interface{}{match}
+	default:
+		err = fmt.Errorf("fts unsupport op type")
+	}
+	return
+}
+
+// 按bm25相关度排序，分数越小越相关，默认升序即相关度从高到低
+type ftsSortField struct {
+	qb *queryImpl
+}
+
+func newFtsSort(qb *queryImpl) common.SortField {
+	return &ftsSortField{qb: qb}
+}
+
+func (f *ftsSortField) BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (key common.SortKey, err error) {
+	key = common.SortKey{Expr: "NULL", NullsFirst: true}
+	if m, ok := v["mode"].(string); ok && m == "desc" {
+		key.Desc = true
+		key.NullsFirst = false
+	}
+	// 未指定query时使用过滤条件中的全文搜索
+	query, ok := v["query"].(string)
+	if !ok {
+		query, ok = f.qb.filter.ftsQuery()
+	}
+	if !ok {
+		return
+	}
+	key.Expr, key.Args, err = FtsRank(tx, f.qb.table, query)
+	return
+}
+
+// 对象与搜索的bm25相关度，没有fts5时为NULL
+func FtsRank(tx tx.ReadTx, table common.Table, query string) (stmt string, args []interface{}, err error) {
+	ftsTable, ok := table.MetaInfo()["fts_table"].(string)
+	if !ok || !Fts5Enabled(tx) {
+		stmt = "NULL"
+		return
+	}
+	node, err := fts.Parse(query)
+	if err != nil {
+		return
+	}
+	tokenizer, err := TableTokenizer(table)
+	if err != nil {
+		return
+	}
+	match, err := node.Match(tokenizer)
+	if err != nil {
+		return
+	}
+	stmt = fmt.Sprintf(
+		`(SELECT bm25(%s) FROM %s WHERE %s MATCH ? AND rowid = %s.rowid)`,
+		ftsTable, ftsTable, ftsTable, table.TableId().DataTable(),
+	)
+	args = []interface{}{match}
+	return
+}
+
+// 查找过滤条件中第一个全文搜索的搜索字符串
+func (q *filterNode) ftsQuery() (query string, ok bool) {
+	if q == nil {
+		return
+	}
+	if q.Type == operation {
+		if _, isFts := q.filterField.(*ftsFilterField); isFts {
+			query, ok = q.filterValue["value"].(string)
+		}
+		return
+	}
+	// NOT下的搜索词不参与相关度计算
+	if q.Connect == opBytesNot {
+		return
+	}
+	for i := range q.ChildNodes {
+		if query, ok = q.ChildNodes[i].ftsQuery(); ok {
+			return
+		}
+	}
+	return
+}
+
+var (
+	fts5Once    sync.Once
+	fts5Support bool
+)
+
+// sqlite是否编译了fts5，没有时全文搜索退化为LIKE匹配
+// go-sqlite3需要使用-tags sqlite_fts5构建才会包含fts5
+func Fts5Enabled(tx tx.ReadTx) bool {
+	fts5Once.Do(func() {
+		var used bool
+		if err := tx.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5')`).Scan(&used); err == nil {
+			fts5Support = used
+		}
+	})
+	return fts5Support
+}
+
+// 表使用的分词器
+func TableTokenizer(table common.Table) (tokenizer fts.Tokenizer, err error) {
+	name, _ := table.MetaInfo()["tokenizer"].(string)
+	return fts.GetTokenizer(name)
+}
@@ -1,191 +1,415 @@
-package query
-
-import (
-	"bytes"
-	"context"
-	"fmt"
-	"paroket/common"
-	"paroket/tx"
-)
-
-type filterField interface {
-	BuildQuery(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (string, error)
-}
-
-type SortField interface {
-	BuildSort(ctx context.Context, tx tx.ReadTx, v map[string]interface{}) (string, error)
-}
-
-type queryImpl struct {
-	table  common.Table
-	filter *filterNode
-	sort   []SortNode
-	limit  int
-	offset int
-}
-
-type filterNodeType string
-
-const (
-	Connection filterNodeType = "connection"
-	Operation  filterNodeType = "operation"
-)
-
-type filterNode struct {
-	Type        filterNodeType
-	ChildNodes  []filterNode
-	Op          string
-	Connect     string
-	filterField filterField
-	filterValue map[string]interface{}
-}
-
-type SortNode struct {
-	SortField SortField
-	SortValue map[string]interface{}
-}
-
-func NewQueryBuilder(table common.Table) common.QueryBuilder {
-	return &queryImpl{
-		table:  table,
-		sort:   []SortNode{},
-		limit:  50,
-		offset: 0,
-	}
-}
-
-func (qb *queryImpl) ParseFilter(ctx context.Context, tx tx.ReadTx, filter string) (err error) {
-	return
-}
-func (qb *queryImpl) ParseOrder(ctx context.Context, tx tx.ReadTx, order string) (err error) {
-	return
-}
-
-func (qb *queryImpl) setQuery(q *filterNode) *queryImpl {
-	qb.filter = q
-	return qb
-}
-
-func (qb *queryImpl) addSort(slist []SortNode) *queryImpl {
-	qb.sort = append(qb.sort, slist...)
-	return qb
-}
-
-func (qb *queryImpl) BuildFilter(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	stmt = ""
-	if qb.filter == nil {
-		return
-	}
-	s, err := qb.filter.BuildFilterHelper(ctx, tx)
-	if err != nil {
-		return
-	}
-	if s != "" {
-		stmt = fmt.Sprintf("WHERE %s", s)
-	}
-	return
-}
-
-func (qb *queryImpl) BuildSort(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	if len(qb.sort) == 0 {
-		stmt = ""
-		return
-	}
-	var buffer bytes.Buffer
-	buffer.WriteString(" ORDER BY ")
-	sortLen := len(qb.sort)
-	for idx, sNode := range qb.sort {
-		var s string
-		s, err = sNode.SortField.BuildSort(ctx, tx, sNode.SortValue)
-		if err != nil {
-			return
-		}
-
-		buffer.WriteString(fmt.Sprintf(" %s ", s))
-		if idx != sortLen-1 {
-			buffer.WriteString(",")
-		}
-	}
-	stmt = buffer.String()
-	return
-}
-
-func (q *filterNode) BuildFilterHelper(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	switch q.Type {
-	case Connection:
-		stmt, err = q.BuildConnect(ctx, tx)
-	case Operation:
-		stmt, err = q.BuildOp(ctx, tx)
-	default:
-		err = fmt.Errorf("unsupport queryNode type: %s from", q.Type)
-	}
-	return
-}
-
-func (q *filterNode) BuildConnect(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-
-	switch q.Connect {
-	case "and":
-		stmt, err = q.andConnect(ctx, tx)
-	case "or":
-		stmt, err = q.orConnect(ctx, tx)
-	default:
-		err = fmt.Errorf("unsupport query connect type of %s", q.Connect)
-	}
-	return
-}
-
-func (q *filterNode) andConnect(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	queryStmtList := []string{}
-	for _, childQuery := range q.ChildNodes {
-		var childStmt string
-		childStmt, err = childQuery.BuildFilterHelper(ctx, tx)
-		if err != nil {
-			return
-		}
-		queryStmtList = append(queryStmtList, childStmt)
-	}
-	var buffer bytes.Buffer
-	end := len(queryStmtList) - 1
-	buffer.WriteString("(")
-	for idx, childStmt := range queryStmtList {
-		buffer.WriteString(childStmt)
-		if idx != end {
-			buffer.WriteString(" AND ")
-		}
-
-	}
-	buffer.WriteString(")")
-	stmt = buffer.String()
-	return
-}
-
-func (q *filterNode) orConnect(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	queryStmtList := []string{}
-	for _, childQuery := range q.ChildNodes {
-		var childStmt string
-		childStmt, err = childQuery.BuildFilterHelper(ctx, tx)
-		if err != nil {
-			return
-		}
-		queryStmtList = append(queryStmtList, childStmt)
-	}
-	var buffer bytes.Buffer
-	end := len(queryStmtList) - 1
-	buffer.WriteString("(")
-	for idx, childStmt := range queryStmtList {
-		buffer.WriteString(childStmt)
-		if idx != end {
-			buffer.WriteString(" OR ")
-		}
-
-	}
-	buffer.WriteString(")")
-	stmt = buffer.String()
-	return
-}
-
-func (q *filterNode) BuildOp(ctx context.Context, tx tx.ReadTx) (stmt string, err error) {
-	stmt, err = q.filterField.BuildQuery(ctx, tx, q.filterValue)
-	return
-}
+package query
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+
+	"github.com/tidwall/gjson"
+)
+
+type queryImpl struct {
+	table  common.Table
+	db     common.Database
+	filter *filterNode
+	sort   []sortNode
+	limit  int
+	offset int
+}
+
+var (
+	opBytesAnd = "$and"
+	opBytesOr  = "$or"
+	opBytesNot = "$not"
+)
+
+type filterNodeType string
+
+const (
+	connection filterNodeType = "connection"
+	operation  filterNodeType = "operation"
+)
+
+type filterNode struct {
+	Type        filterNodeType
+	ChildNodes  []filterNode
+	Connect     string
+	filterField common.FilterField
+	filterValue map[string]interface{}
+}
+
+type sortNode struct {
+	SortField common.SortField
+	SortValue map[string]interface{}
+}
+
+// 解析视图和临时查询中的筛选、排序，并构建sql片段
+func NewQueryBuilder(table common.Table, db common.Database) common.QueryBuilder {
+	return &queryImpl{
+		table:  table,
+		db:     db,
+		sort:   []sortNode{},
+		limit:  50,
+		offset: 0,
+	}
+}
+
+func (qb *queryImpl) ParseFilter(ctx context.Context, tx tx.ReadTx, filter string) (err error) {
+	result := gjson.Parse(filter)
+	switch (result.Value()).(type) {
+	case interface{}:
+
+		keys := result.Get("@keys").Array()
+		if len(keys) == 0 {
+			return
+		}
+		var filterNode *filterNode
+		filterNode, err = parseFilterHelper(ctx, qb.db, qb.table, tx, result)
+		qb.filter = filterNode
+	default:
+		err = fmt.Errorf("invaild filter")
+	}
+	return
+}
+
+func parseFilterHelper(ctx context.Context, db common.Database, table common.Table, tx tx.ReadTx, filter gjson.Result) (node *filterNode, err error) {
+	keys := filter.Get("@keys").Array()
+	if len(keys) != 1 {
+		err = fmt.Errorf("parse filter failed : key error %v", keys)
+		return
+	}
+	key := keys[0].Str
+	switch matchOpType(key) {
+	case connection:
+		node, err = parseConnectFilter(ctx, db, table, tx, filter)
+	case operation:
+		node, err = parseOperationFilter(ctx, db, table, tx, filter)
+	default:
+		err = fmt.Errorf("parse op Type error")
+	}
+	return
+}
+
+func parseConnectFilter(ctx context.Context, db common.Database, table common.Table, tx tx.ReadTx, filter gjson.Result) (node *filterNode, err error) {
+	key := filter.Get("@keys").Array()[0].Str
+	node = &filterNode{
+		Type:       connection,
+		Connect:    key,
+		ChildNodes: []filterNode{},
+	}
+	childNode := filter.Get(key)
+	_, ok := childNode.Value().([]interface{})
+	if !ok {
+		err = fmt.Errorf("connect child node is not a array")
+		return
+	}
+	childFilterList := childNode.Array()
+	if len(childFilterList) == 0 {
+		err = fmt.Errorf("invaild empty condition in %s", key)
+		return
+	}
+	for _, childFilter := range childFilterList {
+		var childFilterNode *filterNode
+		childFilterNode, err = parseFilterHelper(ctx, db, table, tx, childFilter)
+		if err != nil {
+			return
+		}
+		node.ChildNodes = append(node.ChildNodes, *childFilterNode)
+	}
+	return
+}
+
+func parseOperationFilter(ctx context.Context, db common.Database, table common.Table, tx tx.ReadTx, filter gjson.Result) (node *filterNode, err error) {
+	key, op, err := filterKeyOp(filter)
+	if err != nil {
+		return
+	}
+	val := filter.Get(key).Get(op).Str
+
+	switch key {
+	case ftsField:
+		node = &filterNode{
+			Type:        operation,
+			filterField: NewFtsFilter(table),
+			filterValue: map[string]interface{}{
+				"op":    op,
+				"value": val,
+			},
+		}
+	default:
+		node, err = parseAttributeOperationFilter(ctx, db, tx, filter)
+	}
+	return
+}
+
+func parseAttributeOperationFilter(ctx context.Context, db common.Database, tx tx.ReadTx, filter gjson.Result) (node *filterNode, err error) {
+	key, op, err := filterKeyOp(filter)
+	if err != nil {
+		return
+	}
+	val := filter.Get(key).Get(op).Value()
+	var acid common.AttributeClassId
+	if err = acid.Scan(key); err != nil {
+		return
+	}
+	ac, err := db.OpenAttributeClass(ctx, tx, acid)
+	if err != nil {
+		return
+	}
+	node = &filterNode{
+		Type:        operation,
+		filterField: ac,
+		filterValue: map[string]interface{}{
+			"op":    op,
+			"value": val,
+		},
+	}
+	return
+}
+
+// 操作节点只能有一个属性和一个操作
+func filterKeyOp(filter gjson.Result) (key string, op string, err error) {
+	keys := filter.Get("@keys").Array()
+	if len(keys) != 1 {
+		err = fmt.Errorf("invaild filter node:%s", filter.Raw)
+		return
+	}
+	key = keys[0].Str
+	opKeys := filter.Get(key).Get("@keys").Array()
+	if len(opKeys) != 1 {
+		err = fmt.Errorf("invaild filter operation:%s", filter.Raw)
+		return
+	}
+	op = opKeys[0].Str
+	return
+}
+
+// 筛选中的键是否为$and、$or、$not连接
+func IsConnect(key string) bool {
+	return matchOpType(key) == connection
+}
+
+func matchOpType(op string) filterNodeType {
+	switch op {
+	case opBytesAnd:
+		return connection
+	case opBytesOr:
+		return connection
+	case opBytesNot:
+		return connection
+	default:
+		return operation
+	}
+}
+
+func (qb *queryImpl) ParseOrder(ctx context.Context, tx tx.ReadTx, order string) (err error) {
+	// result := gjson.Get(order, "")
+	result := gjson.Parse(order)
+	_, ok := result.Value().([]interface{})
+	if !ok {
+		err = fmt.Errorf("order is not a array")
+		return
+	}
+	orderList := gjson.Parse(order).Array()
+	for _, orderData := range orderList {
+
+		parseOrderData, ok := orderData.Value().(map[string]interface{})
+		if !ok {
+			err = fmt.Errorf("invaild order item format")
+			return
+		}
+		acidStr, ok := parseOrderData["field"].(string)
+		if !ok {
+			err = fmt.Errorf(" order item no found order field")
+			return
+		}
+		if acidStr == ftsField {
+			delete(parseOrderData, "field")
+			qb.sort = append(qb.sort, sortNode{
+				SortField: newFtsSort(qb),
+				SortValue: parseOrderData,
+			})
+			continue
+		}
+		var acid common.AttributeClassId
+		if err = acid.Scan(acidStr); err != nil {
+			return
+		}
+		var ac common.AttributeClass
+		ac, err = qb.db.OpenAttributeClass(ctx, tx, acid)
+		if err != nil {
+			return
+		}
+		delete(parseOrderData, "field")
+		node := sortNode{
+			SortField: ac,
+			SortValue: parseOrderData,
+		}
+		qb.sort = append(qb.sort, node)
+	}
+	return
+}
+
+func (qb *queryImpl) BuildFilter(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	stmt = ""
+	args = []interface{}{}
+	if qb.filter == nil {
+		return
+	}
+	s, args, err := qb.filter.BuildFilterHelper(ctx, tx)
+	if err != nil {
+		return
+	}
+	if s != "" {
+		stmt = fmt.Sprintf("WHERE %s", s)
+	}
+	return
+}
+
+func (qb *queryImpl) BuildSort(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	args = []interface{}{}
+	var buffer bytes.Buffer
+	buffer.WriteString(" ORDER BY ")
+	keys, err := qb.SortKeys(ctx, tx)
+	if err != nil {
+		return
+	}
+	for _, key := range keys {
+		args = append(args, key.Args...)
+		buffer.WriteString(fmt.Sprintf(" %s ,", key.Stmt()))
+	}
+	// 对象id作为最后的排序项，排序键相同或为空时顺序仍然稳定，分页不会重复或遗漏
+	buffer.WriteString(" object_id ASC ")
+	stmt = buffer.String()
+	return
+}
+
+// 每个排序项的表达式、方向和空值的位置，用于生成ORDER BY、游标和游标条件
+func (qb *queryImpl) SortKeys(ctx context.Context, tx tx.ReadTx) (keys []common.SortKey, err error) {
+	keys = []common.SortKey{}
+	for _, sNode := range qb.sort {
+		var key common.SortKey
+		if key, err = sNode.SortField.BuildSort(ctx, tx, sNode.SortValue); err != nil {
+			return
+		}
+		keys = append(keys, key)
+	}
+	return
+}
+
+// 过滤条件中第一个全文搜索的搜索词，用于相关度排序和高亮
+func (qb *queryImpl) SearchQuery() (query string, ok bool) {
+	return qb.filter.ftsQuery()
+}
+
+func (q *filterNode) BuildFilterHelper(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	switch q.Type {
+	case connection:
+		stmt, args, err = q.BuildConnect(ctx, tx)
+	case operation:
+		stmt, args, err = q.BuildOp(ctx, tx)
+	default:
+		err = fmt.Errorf("unsupport queryNode type: %s from", q.Type)
+	}
+	return
+}
+
+func (q *filterNode) BuildConnect(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+
+	switch q.Connect {
+	case opBytesAnd:
+		stmt, args, err = q.andConnect(ctx, tx)
+	case opBytesOr:
+		stmt, args, err = q.orConnect(ctx, tx)
+	case opBytesNot:
+		stmt, args, err = q.notConnect(ctx, tx)
+	default:
+		err = fmt.Errorf("unsupport query connect type of %s", q.Connect)
+	}
+	return
+}
+
+// 依次构建子节点，参数按子节点的顺序拼接
+func (q *filterNode) buildChildNodes(ctx context.Context, tx tx.ReadTx) (queryStmtList []string, args []interface{}, err error) {
+	queryStmtList = []string{}
+	args = []interface{}{}
+	for _, childQuery := range q.ChildNodes {
+		var childStmt string
+		var childArgs []interface{}
+		childStmt, childArgs, err = childQuery.BuildFilterHelper(ctx, tx)
+		if err != nil {
+			return
+		}
+		queryStmtList = append(queryStmtList, childStmt)
+		args = append(args, childArgs...)
+	}
+	return
+}
+
+func (q *filterNode) andConnect(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	queryStmtList, args, err := q.buildChildNodes(ctx, tx)
+	if err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	end := len(queryStmtList) - 1
+
+	buffer.WriteString("(")
+	for idx, childStmt := range queryStmtList {
+		buffer.WriteString(childStmt)
+		if idx != end {
+			buffer.WriteString(" AND ")
+		}
+
+	}
+	buffer.WriteString(")")
+	stmt = buffer.String()
+	return
+}
+
+func (q *filterNode) orConnect(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	queryStmtList, args, err := q.buildChildNodes(ctx, tx)
+	if err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	end := len(queryStmtList) - 1
+	buffer.WriteString("(")
+	for idx, childStmt := range queryStmtList {
+		buffer.WriteString(childStmt)
+		if idx != end {
+			buffer.WriteString(" OR ")
+		}
+
+	}
+	buffer.WriteString(")")
+	stmt = buffer.String()
+	return
+}
+
+func (q *filterNode) notConnect(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	queryStmtList, args, err := q.buildChildNodes(ctx, tx)
+	if err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	end := len(queryStmtList) - 1
+	buffer.WriteString(" NOT (")
+	for idx, childStmt := range queryStmtList {
+		buffer.WriteString(childStmt)
+		if idx != end {
+			buffer.WriteString(" AND ")
+		}
+
+	}
+	buffer.WriteString(")")
+	stmt = buffer.String()
+	return
+}
+
+func (q *filterNode) BuildOp(ctx context.Context, tx tx.ReadTx) (stmt string, args []interface{}, err error) {
+	stmt, args, err = q.filterField.BuildQuery(ctx, tx, q.filterValue)
+	return
+}
@@ -0,0 +1,335 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"paroket/common"
+	"paroket/fts"
+	"paroket/tx"
+	"sort"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// 校验错误的类型
+const (
+	ValidateSyntax        = "syntax"         // json格式或节点结构错误
+	ValidateUnknownField  = "unknown_field"  // 属性不存在或不在表中
+	ValidateUnsupportedOp = "unsupported_op" // 属性不支持的筛选操作
+	ValidateInvalidValue  = "invalid_value"  // 值的类型错误或构建查询失败
+)
+
+// 筛选或排序中的一个错误，Path为出错节点的gjson路径，根节点为空字符串
+type ValidationError struct {
+	Path string
+	Code string
+	Msg  string
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+}
+
+// 一次校验发现的全部错误
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgList := []string{}
+	for _, item := range e {
+		msgList = append(msgList, item.Error())
+	}
+	return strings.Join(msgList, "; ")
+}
+
+type validator struct {
+	ctx     context.Context
+	tx      tx.ReadTx
+	db      common.Database
+	table   common.Table
+	errList ValidationErrors
+}
+
+func (v *validator) add(path string, code string, format string, a ...interface{}) {
+	v.errList = append(v.errList, ValidationError{
+		Path: path,
+		Code: code,
+		Msg:  fmt.Sprintf(format, a...),
+	})
+}
+
+// 只转义gjson路径中的特殊字符，$and等键保持原样便于阅读
+var pathEscaper = strings.NewReplacer(`\`, `\\`, `.`, `\.`, `*`, `\*`, `?`, `\?`, `|`, `\|`, `#`, `\#`, `@`, `\@`)
+
+func joinPath(path string, key string) string {
+	key = pathEscaper.Replace(key)
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// 检查筛选中的每个节点，属性需要在表中，操作和值的类型需要是属性声明支持的
+// 校验不通过时errList不为空，err只返回读取属性类时的错误
+func ValidateFilter(ctx context.Context, tx tx.ReadTx, db common.Database, table common.Table, filter string) (errList ValidationErrors, err error) {
+	v := &validator{ctx: ctx, tx: tx, db: db, table: table}
+	if !gjson.Valid(filter) {
+		v.add("", ValidateSyntax, "filter is not json")
+		return v.errList, nil
+	}
+	result := gjson.Parse(filter)
+	if !result.IsObject() {
+		v.add("", ValidateSyntax, "filter must be an object")
+		return v.errList, nil
+	}
+	if len(result.Map()) != 0 {
+		if err = v.filterNode("", result); err != nil {
+			return
+		}
+	}
+	errList = v.errList
+	return
+}
+
+func (v *validator) filterNode(path string, node gjson.Result) (err error) {
+	if !node.IsObject() {
+		v.add(path, ValidateSyntax, "filter node must be an object")
+		return
+	}
+	keys := node.Get("@keys").Array()
+	if len(keys) != 1 {
+		v.add(path, ValidateSyntax, "filter node must have exactly one key, got %d", len(keys))
+		return
+	}
+	key := keys[0].Str
+	childPath := joinPath(path, key)
+	value := node.Get(pathEscaper.Replace(key))
+	if matchOpType(key) == connection {
+		if !value.IsArray() {
+			v.add(childPath, ValidateSyntax, "%s must be an array", key)
+			return
+		}
+		childList := value.Array()
+		if len(childList) == 0 {
+			v.add(childPath, ValidateSyntax, "%s must have at least one child", key)
+			return
+		}
+		for idx, child := range childList {
+			if err = v.filterNode(joinPath(childPath, fmt.Sprint(idx)), child); err != nil {
+				return
+			}
+		}
+		return
+	}
+
+	var field common.FilterField
+	if key == ftsField {
+		field = NewFtsFilter(v.table)
+	} else {
+		var ac common.AttributeClass
+		if ac, err = v.field(childPath, key); err != nil || ac == nil {
+			return
+		}
+		field = ac
+	}
+	if !value.IsObject() {
+		v.add(childPath, ValidateSyntax, "operation must be an object")
+		return
+	}
+	opKeys := value.Get("@keys").Array()
+	if len(opKeys) != 1 {
+		v.add(childPath, ValidateSyntax, "operation must have exactly one op, got %d", len(opKeys))
+		return
+	}
+	op := opKeys[0].Str
+	opPath := joinPath(childPath, op)
+	opValue := value.Get(pathEscaper.Replace(op)).Value()
+	if !v.operation(opPath, field, op, value.Get(pathEscaper.Replace(op))) {
+		return
+	}
+	if key == ftsField {
+		if search, ok := opValue.(string); ok {
+			if _, nerr := fts.Parse(search); nerr != nil {
+				v.add(opPath, ValidateInvalidValue, "%s", nerr)
+			}
+			return
+		}
+	}
+	// 选项、日期、正则等只有属性自己能检查
+	if _, _, nerr := field.BuildQuery(v.ctx, v.tx, map[string]interface{}{"op": op, "value": opValue}); nerr != nil {
+		v.add(opPath, ValidateInvalidValue, "%s", nerr)
+	}
+	return
+}
+
+// 检查操作是否是属性声明支持的，以及值的类型，不通过时记录错误并返回false
+func (v *validator) operation(path string, field common.FilterField, op string, value gjson.Result) bool {
+	opsField, ok := field.(common.FilterOpsField)
+	if !ok {
+		return true
+	}
+	ops := opsField.FilterOps()
+	valueType, ok := ops[op]
+	if !ok {
+		opList := []string{}
+		for name := range ops {
+			opList = append(opList, name)
+		}
+		sort.Strings(opList)
+		v.add(path, ValidateUnsupportedOp, "unsupport op %s, expect one of %s", op, strings.Join(opList, ", "))
+		return false
+	}
+	if !matchFilterValue(value.Value(), valueType) {
+		v.add(path, ValidateInvalidValue, "op %s expect %s value, got %s", op, valueType, value.Raw)
+		return false
+	}
+	return true
+}
+
+// 打开表中的属性，属性不存在或不在表中时记录错误并返回nil
+func (v *validator) field(path string, key string) (ac common.AttributeClass, err error) {
+	var acid common.AttributeClassId
+	if nerr := acid.Scan(key); nerr != nil {
+		v.add(path, ValidateUnknownField, "invaild attribute class id %s", key)
+		return
+	}
+	if !v.inTable(path, acid) {
+		return
+	}
+	ac, err = v.db.OpenAttributeClass(v.ctx, v.tx, acid)
+	return
+}
+
+// 属性是否在表中，不在时记录错误
+func (v *validator) inTable(path string, acid common.AttributeClassId) bool {
+	for _, fieldId := range v.table.Fields() {
+		if fieldId == acid {
+			return true
+		}
+	}
+	v.add(path, ValidateUnknownField, "attribute %s not in table", acid)
+	return false
+}
+
+func matchFilterValue(value interface{}, valueType common.FilterValueType) bool {
+	switch valueType {
+	case common.FilterValueString:
+		return isFilterString(value)
+	case common.FilterValueNumber:
+		return isFilterNumber(value)
+	case common.FilterValueInteger:
+		n, ok := value.(float64)
+		return ok && n == float64(int64(n))
+	case common.FilterValueBool:
+		return isFilterBool(value)
+	case common.FilterValueOptionalBool:
+		return value == nil || isFilterBool(value)
+	case common.FilterValueScalar:
+		return isFilterString(value) || isFilterNumber(value) || isFilterBool(value)
+	case common.FilterValueStrings:
+		return isFilterString(value) || (isFilterList(value, isFilterString, -1) && len(value.([]interface{})) != 0)
+	case common.FilterValueStringList:
+		return isFilterList(value, isFilterString, -1)
+	case common.FilterValueNumberList:
+		return isFilterList(value, isFilterNumber, -1)
+	case common.FilterValueBoolList:
+		return isFilterList(value, isFilterBool, -1) && len(value.([]interface{})) != 0
+	case common.FilterValueStringPair:
+		return isFilterList(value, isFilterString, 2)
+	case common.FilterValueNumberPair:
+		return isFilterList(value, isFilterNumber, 2)
+	}
+	return false
+}
+
+func isFilterString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func isFilterNumber(value interface{}) bool {
+	_, ok := value.(float64)
+	return ok
+}
+
+func isFilterBool(value interface{}) bool {
+	_, ok := value.(bool)
+	return ok
+}
+
+// 数组中的每一项都满足check，size不为-1时检查数组的长度
+func isFilterList(value interface{}, check func(interface{}) bool, size int) bool {
+	list, ok := value.([]interface{})
+	if !ok || (size != -1 && len(list) != size) {
+		return false
+	}
+	for _, item := range list {
+		if !check(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// 检查排序中的每一项，属性需要在表中，mode为asc或desc，nulls为first或last
+func ValidateOrder(ctx context.Context, tx tx.ReadTx, db common.Database, table common.Table, order string) (errList ValidationErrors, err error) {
+	v := &validator{ctx: ctx, tx: tx, db: db, table: table}
+	if !gjson.Valid(order) {
+		v.add("", ValidateSyntax, "order is not json")
+		return v.errList, nil
+	}
+	result := gjson.Parse(order)
+	if !result.IsArray() {
+		v.add("", ValidateSyntax, "order must be an array")
+		return v.errList, nil
+	}
+	qb := NewQueryBuilder(table, db).(*queryImpl)
+	for idx, item := range result.Array() {
+		path := fmt.Sprint(idx)
+		if err = v.orderItem(qb, path, item); err != nil {
+			return
+		}
+	}
+	errList = v.errList
+	return
+}
+
+func (v *validator) orderItem(qb *queryImpl, path string, item gjson.Result) (err error) {
+	value, ok := item.Value().(map[string]interface{})
+	if !ok {
+		v.add(path, ValidateSyntax, "order item must be an object")
+		return
+	}
+	key, ok := value["field"].(string)
+	if !ok {
+		v.add(joinPath(path, "field"), ValidateSyntax, "order item must have a field")
+		return
+	}
+	var field common.SortField
+	if key == ftsField {
+		field = newFtsSort(qb)
+	} else {
+		var ac common.AttributeClass
+		if ac, err = v.field(joinPath(path, "field"), key); err != nil || ac == nil {
+			return
+		}
+		field = ac
+	}
+	mode, hasMode := value["mode"]
+	if (hasMode || key != ftsField) && mode != "asc" && mode != "desc" {
+		v.add(joinPath(path, "mode"), ValidateInvalidValue, "mode must be asc or desc, got %v", mode)
+		return
+	}
+	if nulls, ok := value["nulls"]; ok && nulls != "first" && nulls != "last" {
+		v.add(joinPath(path, "nulls"), ValidateInvalidValue, "nulls must be first or last, got %v", nulls)
+		return
+	}
+	delete(value, "field")
+	if _, nerr := field.BuildSort(v.ctx, v.tx, value); nerr != nil {
+		v.add(path, ValidateInvalidValue, "%s", nerr)
+	}
+	return
+}
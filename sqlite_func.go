@@ -1,60 +1,240 @@
-package paroket
-
-import "math"
-
-type customFuncImpl struct {
-	impl any
-	pure bool
-}
-
-func custmFunc() map[string]customFuncImpl {
-	v := map[string]customFuncImpl{
-		"xor": {xor, true},
-	}
-	return v
-}
-
-func custmAggrFunc() map[string]customFuncImpl {
-
-	v := map[string]customFuncImpl{
-		"std": {newStddev, true},
-	}
-	return v
-}
-
-func xor(xs ...int64) int64 {
-	var ret int64
-	for _, x := range xs {
-		ret ^= x
-	}
-	return ret
-}
-
-type stddev struct {
-	xs []int64
-	// Running average calculation
-	sum int64
-	n   int64
-}
-
-func newStddev() *stddev { return &stddev{} }
-
-func (s *stddev) Step(x int64) {
-	s.xs = append(s.xs, x)
-	s.sum += x
-	s.n++
-}
-
-func (s *stddev) Done() float64 {
-	mean := float64(s.sum) / float64(s.n)
-	var sqDiff []float64
-	for _, x := range s.xs {
-		sqDiff = append(sqDiff, math.Pow(float64(x)-mean, 2))
-	}
-	var dev float64
-	for _, x := range sqDiff {
-		dev += x
-	}
-	dev /= float64(len(sqDiff))
-	return math.Sqrt(dev)
-}
+package paroket
+
+import (
+	"fmt"
+	"math"
+	"paroket/fts"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+type customFuncImpl struct {
+	impl any
+	pure bool
+}
+
+func custmFunc() map[string]customFuncImpl {
+	v := map[string]customFuncImpl{
+		"xor":         {xor, true},
+		"bucket":      {bucket, true},
+		"date_bucket": {dateBucket, true},
+		"regexp":      {regexpMatch, true},
+		"num_mod":     {numMod, true},
+	}
+	return v
+}
+
+func custmAggrFunc() map[string]customFuncImpl {
+
+	v := map[string]customFuncImpl{
+		"std":    {newStddev, true},
+		"median": {newMedian, true},
+	}
+	return v
+}
+
+func xor(xs ...int64) int64 {
+	var ret int64
+	for _, x := range xs {
+		ret ^= x
+	}
+	return ret
+}
+
+// bucket(value, size) 数值所在区间的下界，用于按数值区间分组
+func bucket(value interface{}, size float64) interface{} {
+	var x float64
+	switch v := value.(type) {
+	case int64:
+		x = float64(v)
+	case float64:
+		x = v
+	default:
+		return nil
+	}
+	if size <= 0 {
+		return x
+	}
+	return math.Floor(x/size) * size
+}
+
+// num_mod(value, divisor) 取模，结果与除数同号，支持小数
+func numMod(value interface{}, divisor float64) interface{} {
+	var x float64
+	switch v := value.(type) {
+	case int64:
+		x = float64(v)
+	case float64:
+		x = v
+	default:
+		return nil
+	}
+	if divisor == 0 {
+		return nil
+	}
+	return x - divisor*math.Floor(x/divisor)
+}
+
+// date_bucket(unix, timezone, unit) 时间在时区中所在的日、周、月、年的第一天，用于按日期分组
+func dateBucket(unix interface{}, timezone string, unit string) (interface{}, error) {
+	var sec int64
+	switch v := unix.(type) {
+	case int64:
+		sec = v
+	case float64:
+		sec = int64(v)
+	default:
+		return nil, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(sec, 0).In(loc)
+	var start time.Time
+	switch unit {
+	case "day":
+		start = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case "week":
+		// 以周一为一周的第一天
+		offset := (int(t.Weekday()) + 6) % 7
+		start = time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, loc)
+	case "month":
+		start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, loc)
+	case "year":
+		start = time.Date(t.Year(), 1, 1, 0, 0, 0, 0, loc)
+	default:
+		return nil, fmt.Errorf("unsupport date bucket:%s", unit)
+	}
+	return start.Format(time.DateOnly), nil
+}
+
+// 编译后的正则表达式，同一个查询会对每一行调用regexp
+var (
+	regexpCacheLock sync.Mutex
+	regexpCache     = map[string]*regexp.Regexp{}
+)
+
+// 缓存的正则表达式数量上限，超过时清空
+const regexpCacheSize = 64
+
+// regexp(pattern, text) 实现sqlite的 text REGEXP pattern，text为NULL时不匹配
+func regexpMatch(pattern string, text interface{}) (bool, error) {
+	var value string
+	switch v := text.(type) {
+	case nil:
+		return false, nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		value = fmt.Sprintf("%v", v)
+	}
+	regexpCacheLock.Lock()
+	re, ok := regexpCache[pattern]
+	if !ok {
+		var err error
+		if re, err = regexp.Compile(pattern); err != nil {
+			regexpCacheLock.Unlock()
+			return false, err
+		}
+		if len(regexpCache) >= regexpCacheSize {
+			regexpCache = map[string]*regexp.Regexp{}
+		}
+		regexpCache[pattern] = re
+	}
+	regexpCacheLock.Unlock()
+	return re.MatchString(value), nil
+}
+
+// fts_tokenize(tokenizer, text) 返回以空格连接的分词结果
+func ftsTokenize(name string, text interface{}) (string, error) {
+	tokenizer, err := fts.GetTokenizer(name)
+	if err != nil {
+		return "", err
+	}
+	switch value := text.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return fts.TokenizeText(tokenizer, value), nil
+	case []byte:
+		return fts.TokenizeText(tokenizer, string(value)), nil
+	default:
+		return fts.TokenizeText(tokenizer, fmt.Sprintf("%v", value)), nil
+	}
+}
+
+type stddev struct {
+	xs []float64
+	// Running average calculation
+	sum float64
+	n   int64
+}
+
+func newStddev() *stddev { return &stddev{} }
+
+// 接受整数与浮点数，忽略NULL与无法转换的值
+func (s *stddev) Step(v interface{}) {
+	var x float64
+	switch value := v.(type) {
+	case int64:
+		x = float64(value)
+	case float64:
+		x = value
+	default:
+		return
+	}
+	s.xs = append(s.xs, x)
+	s.sum += x
+	s.n++
+}
+
+func (s *stddev) Done() interface{} {
+	if s.n == 0 {
+		return nil
+	}
+	mean := s.sum / float64(s.n)
+	var sqDiff []float64
+	for _, x := range s.xs {
+		sqDiff = append(sqDiff, math.Pow(x-mean, 2))
+	}
+	var dev float64
+	for _, x := range sqDiff {
+		dev += x
+	}
+	dev /= float64(len(sqDiff))
+	return math.Sqrt(dev)
+}
+
+type median struct {
+	xs []float64
+}
+
+func newMedian() *median { return &median{} }
+
+// 接受整数与浮点数，忽略NULL与无法转换的值
+func (m *median) Step(v interface{}) {
+	switch value := v.(type) {
+	case int64:
+		m.xs = append(m.xs, float64(value))
+	case float64:
+		m.xs = append(m.xs, value)
+	}
+}
+
+// 偶数个值时取中间两个值的平均
+func (m *median) Done() interface{} {
+	n := len(m.xs)
+	if n == 0 {
+		return nil
+	}
+	sort.Float64s(m.xs)
+	if n%2 == 1 {
+		return m.xs[n/2]
+	}
+	return (m.xs[n/2-1] + m.xs[n/2]) / 2
+}
@@ -0,0 +1,208 @@
+package paroket
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"paroket/common"
+	"paroket/fts"
+	"paroket/query"
+	"paroket/tx"
+	"paroket/utils"
+	"sort"
+
+	"github.com/tidwall/gjson"
+)
+
+// 跨表搜索未指定数量时返回的结果数
+const defaultSearchLimit = 50
+
+type searchMatch struct {
+	oid       common.ObjectId
+	score     float64
+	tableList []common.Table // 对象匹配搜索的表
+}
+
+// 在多个表中搜索，同一个对象在多个表中匹配时只保留相关度最高的一次
+func searchTables(ctx context.Context, db common.Database, tx tx.ReadTx, search string, opts *common.SearchOption) (hits []common.SearchHit, err error) {
+	if opts == nil {
+		opts = &common.SearchOption{}
+	}
+	if _, err = fts.Parse(search); err != nil {
+		return
+	}
+	tidList := opts.TableIdList
+	if len(tidList) == 0 {
+		if tidList, err = listTableId(tx); err != nil {
+			return
+		}
+	}
+
+	matchMap := map[common.ObjectId]*searchMatch{}
+	matchList := []*searchMatch{}
+	for _, tid := range tidList {
+		var table common.Table
+		if table, err = db.OpenTable(ctx, tx, tid); err != nil {
+			return
+		}
+		var filterStmt, rankStmt string
+		var filterArgs, rankArgs []interface{}
+		filterStmt, filterArgs, err = query.NewFtsFilter(table).BuildQuery(ctx, tx, map[string]interface{}{
+			"op":    "search",
+			"value": search,
+		})
+		if err != nil {
+			return
+		}
+		if rankStmt, rankArgs, err = query.FtsRank(tx, table, search); err != nil {
+			return
+		}
+		queryStmt := fmt.Sprintf(`
+		SELECT object_id, IFNULL(%s, 0) FROM %s WHERE %s`, rankStmt, tid.DataTable(), filterStmt)
+		var rows *sql.Rows
+		if rows, err = tx.Query(queryStmt, append(rankArgs, filterArgs...)...); err != nil {
+			return
+		}
+		for rows.Next() {
+			var oid common.ObjectId
+			var score float64
+			if err = rows.Scan(&oid, &score); err != nil {
+				rows.Close()
+				return
+			}
+			match, ok := matchMap[oid]
+			if !ok {
+				match = &searchMatch{oid: oid, score: score}
+				matchMap[oid] = match
+				matchList = append(matchList, match)
+			}
+			match.score = min(match.score, score)
+			match.tableList = append(match.tableList, table)
+		}
+		rows.Close()
+	}
+
+	sort.SliceStable(matchList, func(i, j int) bool {
+		return matchList[i].score < matchList[j].score
+	})
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	start := min(max(opts.Offset, 0), len(matchList))
+	end := min(start+limit, len(matchList))
+
+	hits = []common.SearchHit{}
+	for _, match := range matchList[start:end] {
+		hit := common.SearchHit{Score: match.score}
+		if hit.Object, err = db.OpenObject(ctx, tx, match.oid); err != nil {
+			return
+		}
+		if hit.TableIdList, err = listObjectTableId(tx, match.oid); err != nil {
+			return
+		}
+		if opts.Highlight != nil {
+			if hit.Highlights, err = searchHighlights(ctx, db, tx, hit.Object, match.tableList, search, opts.Highlight); err != nil {
+				return
+			}
+		}
+		hits = append(hits, hit)
+	}
+	return
+}
+
+func listTableId(tx tx.ReadTx) (tidList []common.TableId, err error) {
+	tidList = []common.TableId{}
+	rows, err := tx.Query(`SELECT table_id FROM tables`)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	return
+}
+
+func listObjectTableId(tx tx.ReadTx, oid common.ObjectId) (tidList []common.TableId, err error) {
+	tidList = []common.TableId{}
+	rows, err := tx.Query(`SELECT table_id FROM object_to_tables WHERE object_id = ?`, oid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var tid common.TableId
+		if err = rows.Scan(&tid); err != nil {
+			return
+		}
+		tidList = append(tidList, tid)
+	}
+	return
+}
+
+// 在对象匹配的表的全部属性中生成高亮片段
+func searchHighlights(ctx context.Context, db common.Database, tx tx.ReadTx, obj common.Object, tableList []common.Table, search string, config *common.HighlightConfig) (highlightList []common.Highlight, err error) {
+	fields := []common.AttributeClassId{}
+	for _, table := range tableList {
+		for _, acid := range table.Fields() {
+			if !isInFields(fields, acid) {
+				fields = append(fields, acid)
+			}
+		}
+	}
+	highlights, err := buildHighlights(ctx, tx, db, fields, []common.Object{obj}, search, config)
+	if err != nil {
+		return
+	}
+	highlightList = highlights[obj.ObjectId()]
+	return
+}
+
+// 在对象的各个属性的索引文本中查找搜索词，生成高亮片段
+func buildHighlights(ctx context.Context, tx tx.ReadTx, db common.Database, fields []common.AttributeClassId, objList []common.Object, search string, config *common.HighlightConfig) (highlights map[common.ObjectId][]common.Highlight, err error) {
+	node, err := fts.Parse(search)
+	if err != nil {
+		return
+	}
+	terms := node.Terms()
+	idxPathMap := map[common.AttributeClassId]string{}
+	for _, acid := range fields {
+		var ac common.AttributeClass
+		if ac, err = db.OpenAttributeClass(ctx, tx, acid); err != nil {
+			return
+		}
+		var metaInfo utils.JSONMap
+		if metaInfo, err = ac.GetMetaInfo(ctx, tx); err != nil {
+			return
+		}
+		if idxPath, ok := metaInfo["gjson_idx_path"].(string); ok {
+			idxPathMap[acid] = idxPath
+		}
+	}
+	highlights = map[common.ObjectId][]common.Highlight{}
+	for _, obj := range objList {
+		highlightList := []common.Highlight{}
+		for _, acid := range fields {
+			idxPath, ok := idxPathMap[acid]
+			if !ok {
+				continue
+			}
+			text := gjson.GetBytes(obj.Data(), acid.String()).Get(idxPath).String()
+			snippet, ok := fts.Snippet(text, terms, config.StartMark, config.EndMark, config.Ellipsis, config.Context)
+			if !ok {
+				continue
+			}
+			highlightList = append(highlightList, common.Highlight{
+				ClassId: acid,
+				Snippet: snippet,
+			})
+		}
+		highlights[obj.ObjectId()] = highlightList
+	}
+	return
+}
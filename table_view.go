@@ -1,248 +1,942 @@
-package paroket
-
-import (
-	"bytes"
-	"context"
-	"database/sql"
-	"fmt"
-	"paroket/common"
-	"paroket/tx"
-
-	"github.com/tidwall/gjson"
-)
-
-// TODO
-// 删除表格的attribute貌似会对view造成影响，但现在还是懒得做了。
-
-type viewImpl struct {
-	viewId    common.ViewId
-	db        common.Database
-	table     common.Table
-	fields    []common.AttributeClassId
-	depFields []common.AttributeClassId
-	filter    string
-	order     string
-	limit     int
-	offset    int
-}
-
-func newView(_ context.Context, tx tx.WriteTx, db common.Database, table common.Table) (view common.View, err error) {
-
-	fields := []common.AttributeClassId{}
-	queryFields := `
-	SELECT class_id 
-	FROM table_to_attribute_classes 
-	WHERE table_id = ?`
-	rows, err := tx.Query(queryFields, table.TableId())
-	if err != nil && err != sql.ErrNoRows {
-		return
-	}
-	if err == nil {
-		for rows.Next() {
-			var field common.AttributeClassId
-			if err = rows.Scan(&field); err != nil {
-				return
-			}
-			fields = append(fields, field)
-		}
-	}
-	id, err := common.NewViewId()
-	if err != nil {
-		return
-	}
-	view = &viewImpl{
-		viewId: id,
-		db:     db,
-		table:  table,
-		fields: fields,
-		filter: "{}",
-		order:  "[]",
-		limit:  100,
-		offset: 0,
-	}
-
-	insertView := `INSERT INTO 
-	table_views (table_id, view_id, query)
-	VALUES
-	(?,?,?)
-	`
-	if _, err = tx.Exac(insertView, table.TableId(), view.ViewId(), view.Marshal()); err != nil {
-		return
-	}
-	return
-}
-
-func queryView(_ context.Context, tx tx.ReadTx, db common.Database, table common.Table, vid common.ViewId) (view common.View, err error) {
-	v := &viewImpl{
-		viewId: vid,
-		db:     db,
-		table:  table,
-		limit:  100,
-		offset: 0,
-	}
-	queryStmt := ` 
-	SELECT query FROM table_views WHERE view_id = ?`
-	var data string
-	if err = tx.QueryRow(queryStmt, vid).Scan(&data); err != nil {
-		return
-	}
-	if err = v.Unmarshal(data); err != nil {
-		return
-	}
-	view = v
-	return
-}
-func (v *viewImpl) ViewId() common.ViewId {
-	return v.viewId
-}
-
-func (v *viewImpl) Filter(tx tx.WriteTx, filter string) (err error) {
-	if !gjson.Valid(filter) {
-		return fmt.Errorf("invaild filter")
-	}
-	v.filter = filter
-	if err = v.save(tx); err != nil {
-		return
-	}
-	return
-}
-func (v *viewImpl) SortBy(tx tx.WriteTx, order string) (err error) {
-	if !gjson.Valid(order) {
-		return fmt.Errorf("invaild order")
-	}
-	v.order = order
-	if err = v.save(tx); err != nil {
-		return
-	}
-	return
-}
-func (v *viewImpl) Limit(limit int) common.View {
-	v.limit = limit
-	return v
-}
-func (v *viewImpl) Offset(offset int) common.View {
-	v.offset = offset
-	return v
-}
-func (v *viewImpl) Set(value map[string]interface{}) (err error) {
-	// TODO
-	panic("un impl")
-}
-
-func (v *viewImpl) save(tx tx.WriteTx) (err error) {
-	update := `UPDATE table_views SET query = ? WHERE view_id = ?`
-	if _, err = tx.Exac(update, v.Marshal(), v.viewId); err != nil {
-		return
-	}
-
-	return
-}
-
-func (v *viewImpl) Query(ctx context.Context, tx tx.ReadTx) (queryData common.TableResult, err error) {
-	query := newQueryBuilder(v.table, v.db)
-	if err = query.ParseFilter(ctx, tx, v.filter); err != nil {
-		return
-	}
-	if err = query.ParseOrder(ctx, tx, v.order); err != nil {
-		return
-	}
-
-	queryStmtBuffer := &bytes.Buffer{}
-	queryStmtBuffer.WriteString(fmt.Sprintf(`
-	SELECT object_id, json(data) FROM %s `, v.table.TableId().DataTable()))
-
-	filterStmt, err := query.BuildFilter(ctx, tx)
-	if err != nil {
-		return
-	}
-	orderStmt, err := query.BuildSort(ctx, tx)
-	if err != nil {
-		return
-	}
-	queryStmtBuffer.WriteString(fmt.Sprintf(`
-	%s
-	%s
-	LIMIT %d OFFSET %d`, filterStmt, orderStmt, v.limit, v.offset))
-
-	queryStmt := queryStmtBuffer.String()
-	rows, err := tx.Query(queryStmt)
-	if err != nil {
-		return
-	}
-	objList, err := common.QueryTableObjectList(ctx, v.db, rows)
-	if err != nil {
-		return
-	}
-	queryData = common.NewTableResult(v.db, v.fields, objList)
-	return
-}
-
-func marshalAcIdList(acidList []common.AttributeClassId) string {
-	listBuffer := &bytes.Buffer{}
-	listBuffer.WriteString("[")
-	for idx, field := range acidList {
-		if idx != 0 {
-			listBuffer.WriteString(",")
-		}
-		listBuffer.WriteString(fmt.Sprintf(`"%v"`, field))
-	}
-	listBuffer.WriteString("]")
-	ret := listBuffer.String()
-	return ret
-}
-
-func (v *viewImpl) Marshal() string {
-
-	fieldMashal := marshalAcIdList(v.fields)
-	depFieldMashal := marshalAcIdList(v.depFields)
-
-	return fmt.Sprintf(`{"fields":%s,"dep_fields":%s,"filter":%s,"order":%s}`,
-		fieldMashal, depFieldMashal, v.filter, v.order,
-	)
-}
-
-func (v *viewImpl) Unmarshal(data string) (err error) {
-	fieldsData := gjson.Get(data, "field")
-	if fieldsData.Type != gjson.JSON {
-		return fmt.Errorf("field unfound")
-	}
-	depFieldData := gjson.Get(data, "dep_fields")
-	if depFieldData.Type != gjson.JSON {
-		return fmt.Errorf("depField unfound")
-	}
-	filterData := gjson.Get(data, "filter")
-	if filterData.Type != gjson.JSON {
-		return fmt.Errorf("field unfound")
-	}
-
-	orderData := gjson.Get(data, "order")
-	if orderData.Type != gjson.JSON {
-		return fmt.Errorf("order unfound")
-	}
-	field := []common.AttributeClassId{}
-	fieldsData.ForEach(func(key, value gjson.Result) bool {
-		acid := common.AttributeClassId{}
-		err = acid.Scan(value.String())
-		if err != nil {
-			return false
-		}
-		field = append(field, acid)
-		return true // keep iterating
-	})
-
-	depFields := []common.AttributeClassId{}
-	depFieldData.ForEach(func(key, value gjson.Result) bool {
-		acid := common.AttributeClassId{}
-		err = acid.Scan(value.String())
-		if err != nil {
-			return false
-		}
-		depFields = append(depFields, acid)
-		return true // keep iterating
-	})
-	v.fields = field
-	v.depFields = depFields
-	v.filter = filterData.Raw
-	v.order = orderData.Raw
-	return
-}
+package paroket
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/query"
+	"paroket/tx"
+	"paroket/utils"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+type viewImpl struct {
+	viewId      common.ViewId
+	db          common.Database
+	table       common.Table
+	name        string
+	description string
+	position    int
+	fields      []common.AttributeClassId
+	depFields   []common.AttributeClassId
+	filter      string
+	order       string
+	limit       int
+	offset      int
+	widths      map[common.AttributeClassId]int
+	groupBy     string
+	pruned      []common.ViewPrune
+	highlight   *common.HighlightConfig
+}
+
+func newView(_ context.Context, tx tx.WriteTx, db common.Database, table common.Table) (view common.View, err error) {
+
+	fields := []common.AttributeClassId{}
+	queryFields := `
+	SELECT class_id 
+	FROM table_to_attribute_classes 
+	WHERE table_id = ?`
+	rows, err := tx.Query(queryFields, table.TableId())
+	if err != nil && err != sql.ErrNoRows {
+		return
+	}
+	if err == nil {
+		for rows.Next() {
+			var field common.AttributeClassId
+			if err = rows.Scan(&field); err != nil {
+				return
+			}
+			fields = append(fields, field)
+		}
+	}
+	id, err := common.NewViewId()
+	if err != nil {
+		return
+	}
+	// 新视图排在最后
+	var position int
+	queryPosition := `SELECT COALESCE(MAX(position) + 1, 0) FROM table_views WHERE table_id = ?`
+	if err = tx.QueryRow(queryPosition, table.TableId()).Scan(&position); err != nil {
+		return
+	}
+	v := &viewImpl{
+		viewId:   id,
+		db:       db,
+		table:    table,
+		name:     "untitled",
+		position: position,
+		fields:   fields,
+		filter:   "{}",
+		order:    "[]",
+		limit:    100,
+		offset:   0,
+		widths:   map[common.AttributeClassId]int{},
+	}
+	if err = v.insert(tx); err != nil {
+		return
+	}
+	view = v
+	return
+}
+
+func (v *viewImpl) insert(tx tx.WriteTx) (err error) {
+	insertView := `INSERT INTO 
+	table_views (table_id, view_id, query, view_name, description, position)
+	VALUES
+	(?,?,?,?,?,?)
+	`
+	if _, err = tx.Exac(insertView, v.table.TableId(), v.viewId, v.Marshal(), v.name, v.description, v.position); err != nil {
+		return
+	}
+	return
+}
+
+func queryView(_ context.Context, tx tx.ReadTx, db common.Database, table common.Table, vid common.ViewId) (view common.View, err error) {
+	v := &viewImpl{
+		viewId: vid,
+		db:     db,
+		table:  table,
+		limit:  100,
+		offset: 0,
+		widths: map[common.AttributeClassId]int{},
+	}
+	// 只能打开属于该表的视图
+	queryStmt := ` 
+	SELECT query, view_name, description, position FROM table_views WHERE table_id = ? AND view_id = ?`
+	var data string
+	if err = tx.QueryRow(queryStmt, table.TableId(), vid).Scan(&data, &v.name, &v.description, &v.position); err != nil {
+		return
+	}
+	if err = v.Unmarshal(data); err != nil {
+		return
+	}
+	view = v
+	return
+}
+func (v *viewImpl) ViewId() common.ViewId {
+	return v.viewId
+}
+
+func (v *viewImpl) Name() string {
+	return v.name
+}
+
+func (v *viewImpl) Description() string {
+	return v.description
+}
+
+func (v *viewImpl) Position() int {
+	return v.position
+}
+
+// 校验不通过时返回query.ValidationErrors，不保存
+func (v *viewImpl) Filter(tx tx.WriteTx, filter string) (err error) {
+	if err = v.validate(context.Background(), tx, "filter", filter); err != nil {
+		return
+	}
+	v.filter = filter
+	if err = v.save(tx); err != nil {
+		return
+	}
+	return
+}
+func (v *viewImpl) SortBy(tx tx.WriteTx, order string) (err error) {
+	if err = v.validate(context.Background(), tx, "order", order); err != nil {
+		return
+	}
+	v.order = order
+	if err = v.save(tx); err != nil {
+		return
+	}
+	return
+}
+func (v *viewImpl) Limit(limit int) common.View {
+	v.limit = limit
+	return v
+}
+func (v *viewImpl) Offset(offset int) common.View {
+	v.offset = offset
+	return v
+}
+func (v *viewImpl) Highlight(config *common.HighlightConfig) common.View {
+	v.highlight = config
+	return v
+}
+func (v *viewImpl) Set(ctx context.Context, tx tx.WriteTx, value utils.JSONMap) (err error) {
+	oldFields, oldWidths := v.fields, v.widths
+	oldFilter, oldOrder := v.filter, v.order
+	oldLimit, oldOffset := v.limit, v.offset
+	oldName, oldDescription := v.name, v.description
+	oldGroupBy := v.groupBy
+	defer func() {
+		if err != nil {
+			v.groupBy = oldGroupBy
+			v.fields, v.widths = oldFields, oldWidths
+			v.filter, v.order = oldFilter, oldOrder
+			v.limit, v.offset = oldLimit, oldOffset
+			v.name, v.description = oldName, oldDescription
+		}
+	}()
+
+	position := -1
+
+	for key, val := range value {
+		switch key {
+		case "fields":
+			// 显示的列，列表的顺序即列的顺序
+			var fields []common.AttributeClassId
+			if fields, err = parseAcIdList(val); err != nil {
+				return
+			}
+			for idx, acid := range fields {
+				if !isInFields(v.table.Fields(), acid) {
+					err = fmt.Errorf("field %v not in table", acid)
+					return
+				}
+				if isInFields(fields[:idx], acid) {
+					err = fmt.Errorf("duplicate field %v", acid)
+					return
+				}
+			}
+			v.fields = fields
+		case "widths":
+			var widths map[common.AttributeClassId]int
+			if widths, err = parseWidths(val); err != nil {
+				return
+			}
+			for acid := range widths {
+				if !isInFields(v.table.Fields(), acid) {
+					err = fmt.Errorf("field %v not in table", acid)
+					return
+				}
+			}
+			v.widths = widths
+		case "limit", "offset":
+			n, ok := toInt(val)
+			if !ok || n < 0 || (key == "limit" && n == 0) {
+				err = fmt.Errorf("invaild %s:%v", key, val)
+				return
+			}
+			if key == "limit" {
+				v.limit = n
+			} else {
+				v.offset = n
+			}
+		case "filter", "order":
+			s, ok := val.(string)
+			if !ok {
+				err = fmt.Errorf("invaild %s", key)
+				return
+			}
+			if err = v.validate(ctx, tx, key, s); err != nil {
+				return
+			}
+			if key == "filter" {
+				v.filter = s
+			} else {
+				v.order = s
+			}
+		case "group_by":
+			if v.groupBy, err = marshalGroupBy(val); err != nil {
+				return
+			}
+			if v.groupBy != "" {
+				if _, err = parseGroupBy(ctx, tx, v.db, v.table, v.groupBy); err != nil {
+					return
+				}
+			}
+		case "name":
+			name, ok := val.(string)
+			if !ok || name == "" {
+				err = fmt.Errorf("invaild view name:%v", val)
+				return
+			}
+			v.name = name
+		case "description":
+			description, ok := val.(string)
+			if !ok {
+				err = fmt.Errorf("invaild view description:%v", val)
+				return
+			}
+			v.description = description
+		case "position":
+			n, ok := toInt(val)
+			if !ok || n < 0 {
+				err = fmt.Errorf("invaild position:%v", val)
+				return
+			}
+			position = n
+		default:
+			err = fmt.Errorf("unsupport view setting:%s", key)
+			return
+		}
+	}
+	// 列宽只保留显示的列
+	widths := map[common.AttributeClassId]int{}
+	for acid, width := range v.widths {
+		if isInFields(v.fields, acid) {
+			widths[acid] = width
+		}
+	}
+	v.widths = widths
+	if position >= 0 {
+		if err = moveView(tx, v, position); err != nil {
+			return
+		}
+	}
+	if err = v.save(tx); err != nil {
+		return
+	}
+	return
+}
+
+// 接受整数和整数值的浮点数
+func toInt(value interface{}) (n int, ok bool) {
+	switch val := value.(type) {
+	case int:
+		return val, true
+	case int64:
+		return int(val), true
+	case float64:
+		if val == float64(int(val)) {
+			return int(val), true
+		}
+	}
+	return
+}
+
+// 接受json字符串或字符串列表
+func parseAcIdList(value interface{}) (acidList []common.AttributeClassId, err error) {
+	var strList []string
+	switch val := value.(type) {
+	case string:
+		result := gjson.Parse(val)
+		if !result.IsArray() {
+			err = fmt.Errorf("invaild field list:%s", val)
+			return
+		}
+		for _, item := range result.Array() {
+			strList = append(strList, item.String())
+		}
+	case []string:
+		strList = val
+	case []interface{}:
+		for _, item := range val {
+			s, ok := item.(string)
+			if !ok {
+				err = fmt.Errorf("invaild field:%v", item)
+				return
+			}
+			strList = append(strList, s)
+		}
+	case []common.AttributeClassId:
+		acidList = append([]common.AttributeClassId{}, val...)
+		return
+	default:
+		err = fmt.Errorf("invaild field list type:%T", value)
+		return
+	}
+	acidList = []common.AttributeClassId{}
+	for _, s := range strList {
+		var acid common.AttributeClassId
+		if err = acid.Scan(s); err != nil {
+			return
+		}
+		acidList = append(acidList, acid)
+	}
+	return
+}
+
+// 接受json字符串或map，键为属性id，值为正整数
+func parseWidths(value interface{}) (widths map[common.AttributeClassId]int, err error) {
+	widthMap := map[string]interface{}{}
+	switch val := value.(type) {
+	case string:
+		result := gjson.Parse(val)
+		if !result.IsObject() {
+			err = fmt.Errorf("invaild widths:%s", val)
+			return
+		}
+		result.ForEach(func(key, value gjson.Result) bool {
+			widthMap[key.String()] = value.Value()
+			return true
+		})
+	case map[string]interface{}:
+		widthMap = val
+	case utils.JSONMap:
+		widthMap = val
+	default:
+		err = fmt.Errorf("invaild widths type:%T", value)
+		return
+	}
+	widths = map[common.AttributeClassId]int{}
+	for key, w := range widthMap {
+		var acid common.AttributeClassId
+		if err = acid.Scan(key); err != nil {
+			return
+		}
+		n, ok := toInt(w)
+		if !ok || n <= 0 {
+			err = fmt.Errorf("invaild width:%v", w)
+			return
+		}
+		widths[acid] = n
+	}
+	return
+}
+
+// 保存之前检查筛选或排序，kind为filter或order
+func (v *viewImpl) validate(ctx context.Context, tx tx.ReadTx, kind string, value string) (err error) {
+	var errList query.ValidationErrors
+	if kind == "filter" {
+		errList, err = query.ValidateFilter(ctx, tx, v.db, v.table, value)
+	} else {
+		errList, err = query.ValidateOrder(ctx, tx, v.db, v.table, value)
+	}
+	if err != nil {
+		return
+	}
+	if len(errList) != 0 {
+		err = errList
+	}
+	return
+}
+
+func (v *viewImpl) save(tx tx.WriteTx) (err error) {
+	update := `UPDATE table_views SET (query, view_name, description) = (?,?,?) WHERE view_id = ?`
+	if _, err = tx.Exac(update, v.Marshal(), v.name, v.description, v.viewId); err != nil {
+		return
+	}
+
+	return
+}
+
+func (v *viewImpl) Query(ctx context.Context, tx tx.ReadTx) (queryData common.TableResult, err error) {
+	queryData, err = v.query(ctx, tx, v.filter, v.limit, v.offset, nil)
+	return
+}
+
+// 使用config覆盖分页并追加筛选，config不为nil时offset总是使用config中的值
+func (v *viewImpl) queryWithConfig(ctx context.Context, tx tx.ReadTx, config *common.QueryConfig) (queryData common.TableResult, err error) {
+	if config == nil {
+		return v.Query(ctx, tx)
+	}
+	if config.Limit < 0 || config.Offset < 0 {
+		err = fmt.Errorf("invaild query config limit:%d offset:%d", config.Limit, config.Offset)
+		return
+	}
+	limit := v.limit
+	if config.Limit != 0 {
+		limit = config.Limit
+	}
+	filter, err := v.mergeFilter(ctx, tx, v.filter, config.Filter)
+	if err != nil {
+		return
+	}
+	queryData, err = v.query(ctx, tx, filter, limit, config.Offset, nil)
+	return
+}
+
+// 合并视图的筛选和额外的筛选，两者同时生效，额外的筛选与视图的筛选一样先经过校验
+func (v *viewImpl) mergeFilter(ctx context.Context, tx tx.ReadTx, filter string, extra string) (merged string, err error) {
+	merged = filter
+	if extra == "" {
+		return
+	}
+	if err = v.validate(ctx, tx, "filter", extra); err != nil {
+		return
+	}
+	if len(gjson.Get(filter, "@keys").Array()) == 0 {
+		merged = extra
+	} else if len(gjson.Get(extra, "@keys").Array()) != 0 {
+		merged = fmt.Sprintf(`{"$and":[%s,%s]}`, filter, extra)
+	}
+	return
+}
+
+// 分组视图返回各个分组，limit和offset对分组视图不生效
+// after不为空时从游标之后查询，结果带有匹配筛选的总数和最后一个对象的游标
+func (v *viewImpl) query(ctx context.Context, tx tx.ReadTx, filter string, limit int, offset int, after *viewCursor) (queryData common.TableResult, err error) {
+	if v.groupBy != "" {
+		var gb *groupBy
+		if gb, err = parseGroupBy(ctx, tx, v.db, v.table, v.groupBy); err != nil {
+			return
+		}
+		var groups []common.Group
+		if groups, err = v.queryGroups(ctx, tx, gb, filter); err != nil {
+			return
+		}
+		queryData = common.NewGroupTableResult(v.db, v.fields, groups)
+		return
+	}
+	qb := query.NewQueryBuilder(v.table, v.db)
+	if err = qb.ParseFilter(ctx, tx, filter); err != nil {
+		return
+	}
+	if err = qb.ParseOrder(ctx, tx, v.order); err != nil {
+		return
+	}
+
+	filterStmt, filterArgs, err := qb.BuildFilter(ctx, tx)
+	if err != nil {
+		return
+	}
+	var total int
+	countStmt := fmt.Sprintf(`SELECT COUNT(*) FROM %s %s`, v.table.TableId().DataTable(), filterStmt)
+	if err = tx.QueryRow(countStmt, filterArgs...).Scan(&total); err != nil {
+		return
+	}
+
+	keys, err := qb.SortKeys(ctx, tx)
+	if err != nil {
+		return
+	}
+	args := append([]interface{}{}, filterArgs...)
+	if after != nil {
+		var cursorStmt string
+		var cursorArgs []interface{}
+		if cursorStmt, cursorArgs, err = buildCursorFilter(keys, after); err != nil {
+			return
+		}
+		if filterStmt == "" {
+			filterStmt = fmt.Sprintf("WHERE %s", cursorStmt)
+		} else {
+			filterStmt = fmt.Sprintf("WHERE (%s) AND (%s)", strings.TrimPrefix(filterStmt, "WHERE "), cursorStmt)
+		}
+		args = append(args, cursorArgs...)
+	}
+	orderStmt, orderArgs, err := qb.BuildSort(ctx, tx)
+	if err != nil {
+		return
+	}
+	args = append(args, orderArgs...)
+
+	// 多查询一个对象用于判断是否还有下一页
+	queryStmt := fmt.Sprintf(`
+	SELECT object_id, json(data) FROM %s
+	%s
+	%s
+	LIMIT %d OFFSET %d`, v.table.TableId().DataTable(), filterStmt, orderStmt, limit+1, offset)
+	rows, err := tx.Query(queryStmt, args...)
+	if err != nil {
+		return
+	}
+	objList, err := common.QueryTableObjectList(ctx, v.db, rows)
+	if err != nil {
+		return
+	}
+	cursor := ""
+	if len(objList) > limit {
+		objList = objList[:limit]
+		if limit > 0 {
+			if cursor, err = queryCursor(tx, v.table, keys, objList[limit-1].ObjectId()); err != nil {
+				return
+			}
+		}
+	}
+	if search, ok := qb.SearchQuery(); ok && v.highlight != nil {
+		var highlights map[common.ObjectId][]common.Highlight
+		highlights, err = buildHighlights(ctx, tx, v.db, v.fields, objList, search, v.highlight)
+		if err != nil {
+			return
+		}
+		queryData = common.NewPageTableResult(common.NewHighlightTableResult(v.db, v.fields, objList, highlights), total, cursor)
+		return
+	}
+	queryData = common.NewPageTableResult(common.NewTableResult(v.db, v.fields, objList), total, cursor)
+	return
+}
+
+func marshalAcIdList(acidList []common.AttributeClassId) string {
+	listBuffer := &bytes.Buffer{}
+	listBuffer.WriteString("[")
+	for idx, field := range acidList {
+		if idx != 0 {
+			listBuffer.WriteString(",")
+		}
+		listBuffer.WriteString(fmt.Sprintf(`"%v"`, field))
+	}
+	listBuffer.WriteString("]")
+	ret := listBuffer.String()
+	return ret
+}
+
+func (v *viewImpl) Marshal() string {
+
+	fieldMashal := marshalAcIdList(v.fields)
+	depFieldMashal := marshalAcIdList(v.depFields)
+
+	widthMap := map[string]int{}
+	for acid, width := range v.widths {
+		widthMap[acid.String()] = width
+	}
+	widthMashal, _ := json.Marshal(widthMap)
+
+	prunedBuffer := &bytes.Buffer{}
+	prunedBuffer.WriteString("[")
+	for idx, prune := range v.pruned {
+		if idx != 0 {
+			prunedBuffer.WriteString(",")
+		}
+		prunedBuffer.WriteString(fmt.Sprintf(`{"class_id":"%v","field":%t,"filter":[%s],"order":[%s],"group_by":%t}`,
+			prune.ClassId, prune.Field, strings.Join(prune.Filter, ","), strings.Join(prune.Order, ","), prune.GroupBy,
+		))
+	}
+	prunedBuffer.WriteString("]")
+
+	groupByMashal := "null"
+	if v.groupBy != "" {
+		groupByMashal = v.groupBy
+	}
+
+	return fmt.Sprintf(`{"fields":%s,"dep_fields":%s,"filter":%s,"order":%s,"widths":%s,"limit":%d,"offset":%d,"pruned":%s,"group_by":%s}`,
+		fieldMashal, depFieldMashal, v.filter, v.order, widthMashal, v.limit, v.offset, prunedBuffer.String(), groupByMashal,
+	)
+}
+
+func (v *viewImpl) Unmarshal(data string) (err error) {
+	fieldsData := gjson.Get(data, "fields")
+	if fieldsData.Type != gjson.JSON {
+		return fmt.Errorf("field unfound")
+	}
+	depFieldData := gjson.Get(data, "dep_fields")
+	if depFieldData.Type != gjson.JSON {
+		return fmt.Errorf("depField unfound")
+	}
+	filterData := gjson.Get(data, "filter")
+	if filterData.Type != gjson.JSON {
+		return fmt.Errorf("field unfound")
+	}
+
+	orderData := gjson.Get(data, "order")
+	if orderData.Type != gjson.JSON {
+		return fmt.Errorf("order unfound")
+	}
+	field := []common.AttributeClassId{}
+	fieldsData.ForEach(func(key, value gjson.Result) bool {
+		acid := common.AttributeClassId{}
+		err = acid.Scan(value.String())
+		if err != nil {
+			return false
+		}
+		field = append(field, acid)
+		return true // keep iterating
+	})
+
+	depFields := []common.AttributeClassId{}
+	depFieldData.ForEach(func(key, value gjson.Result) bool {
+		acid := common.AttributeClassId{}
+		err = acid.Scan(value.String())
+		if err != nil {
+			return false
+		}
+		depFields = append(depFields, acid)
+		return true // keep iterating
+	})
+	if err != nil {
+		return
+	}
+
+	// 旧版本保存的视图没有列宽和分页
+	widths := map[common.AttributeClassId]int{}
+	gjson.Get(data, "widths").ForEach(func(key, value gjson.Result) bool {
+		acid := common.AttributeClassId{}
+		err = acid.Scan(key.String())
+		if err != nil {
+			return false
+		}
+		widths[acid] = int(value.Int())
+		return true
+	})
+	if err != nil {
+		return
+	}
+	pruned := []common.ViewPrune{}
+	gjson.Get(data, "pruned").ForEach(func(key, value gjson.Result) bool {
+		prune := common.ViewPrune{
+			Field:   value.Get("field").Bool(),
+			GroupBy: value.Get("group_by").Bool(),
+			Filter:  []string{},
+			Order:   []string{},
+		}
+		err = prune.ClassId.Scan(value.Get("class_id").String())
+		if err != nil {
+			return false
+		}
+		for _, item := range value.Get("filter").Array() {
+			prune.Filter = append(prune.Filter, item.Raw)
+		}
+		for _, item := range value.Get("order").Array() {
+			prune.Order = append(prune.Order, item.Raw)
+		}
+		pruned = append(pruned, prune)
+		return true
+	})
+	if err != nil {
+		return
+	}
+	v.pruned = pruned
+	v.groupBy = ""
+	if groupByData := gjson.Get(data, "group_by"); groupByData.IsObject() {
+		v.groupBy = groupByData.Raw
+	}
+	if limitData := gjson.Get(data, "limit"); limitData.Exists() {
+		v.limit = int(limitData.Int())
+	}
+	if offsetData := gjson.Get(data, "offset"); offsetData.Exists() {
+		v.offset = int(offsetData.Int())
+	}
+	v.fields = field
+	v.depFields = depFields
+	v.widths = widths
+	v.filter = filterData.Raw
+	v.order = orderData.Raw
+	return
+}
+
+func (v *viewImpl) Pruned() []common.ViewPrune {
+	return append([]common.ViewPrune{}, v.pruned...)
+}
+
+func (v *viewImpl) ClearPruned(tx tx.WriteTx) (err error) {
+	v.pruned = []common.ViewPrune{}
+	if err = v.save(tx); err != nil {
+		return
+	}
+	return
+}
+
+// 从视图中移除属性：显示的列、列宽、引用属性的筛选条件和排序项
+// 有内容被移除时记录到pruned中并保存视图
+func (v *viewImpl) pruneAttributeClass(tx tx.WriteTx, acid common.AttributeClassId) (err error) {
+	prune := common.ViewPrune{
+		ClassId: acid,
+		Filter:  []string{},
+		Order:   []string{},
+	}
+	fields := []common.AttributeClassId{}
+	for _, field := range v.fields {
+		if field == acid {
+			prune.Field = true
+			continue
+		}
+		fields = append(fields, field)
+	}
+	depFields := []common.AttributeClassId{}
+	for _, field := range v.depFields {
+		if field != acid {
+			depFields = append(depFields, field)
+		}
+	}
+
+	filter, ok := pruneFilter(gjson.Parse(v.filter), acid.String(), &prune.Filter)
+	if !ok {
+		filter = "{}"
+	}
+
+	orderList := []string{}
+	for _, item := range gjson.Parse(v.order).Array() {
+		if item.Get("field").String() == acid.String() {
+			prune.Order = append(prune.Order, item.Raw)
+			continue
+		}
+		orderList = append(orderList, item.Raw)
+	}
+
+	groupBy := v.groupBy
+	if groupBy != "" {
+		groupBy, prune.GroupBy = pruneGroupBy(groupBy, acid.String())
+	}
+
+	if !prune.Field && len(prune.Filter) == 0 && len(prune.Order) == 0 && !prune.GroupBy {
+		return
+	}
+	v.groupBy = groupBy
+	v.fields = fields
+	v.depFields = depFields
+	delete(v.widths, acid)
+	v.filter = filter
+	v.order = fmt.Sprintf("[%s]", strings.Join(orderList, ","))
+	v.pruned = append(v.pruned, prune)
+	if err = v.save(tx); err != nil {
+		return
+	}
+	return
+}
+
+// 分组的属性被删除时取消分组，统计的属性被删除时移除对应的统计
+func pruneGroupBy(raw string, acid string) (groupBy string, pruned bool) {
+	result := gjson.Parse(raw)
+	if result.Get("field").String() == acid {
+		return "", true
+	}
+	value, ok := result.Value().(map[string]interface{})
+	if !ok {
+		return raw, false
+	}
+	aggregates := []interface{}{}
+	for _, item := range result.Get("aggregates").Array() {
+		if item.Get("field").String() == acid {
+			pruned = true
+			continue
+		}
+		aggregates = append(aggregates, item.Value())
+	}
+	if !pruned {
+		return raw, false
+	}
+	value["aggregates"] = aggregates
+	data, _ := json.Marshal(value)
+	return string(data), true
+}
+
+// 移除筛选树中引用属性的条件，连接节点的子节点全部被移除时连接节点也被移除
+// ok为false表示整个节点被移除
+func pruneFilter(filter gjson.Result, acid string, pruned *[]string) (stmt string, ok bool) {
+	keys := filter.Get("@keys").Array()
+	if len(keys) != 1 {
+		return filter.Raw, len(keys) != 0
+	}
+	key := keys[0].Str
+	if !query.IsConnect(key) {
+		if key == acid {
+			*pruned = append(*pruned, filter.Raw)
+			return
+		}
+		return filter.Raw, true
+	}
+	childList := []string{}
+	for _, child := range filter.Get(key).Array() {
+		if childStmt, childOk := pruneFilter(child, acid, pruned); childOk {
+			childList = append(childList, childStmt)
+		}
+	}
+	if len(childList) == 0 {
+		return
+	}
+	keyData, _ := json.Marshal(key)
+	return fmt.Sprintf(`{%s:[%s]}`, keyData, strings.Join(childList, ",")), true
+}
+
+// 表中按顺序排列的视图id
+func listViewId(tx tx.ReadTx, tid common.TableId) (vidList []common.ViewId, err error) {
+	vidList = []common.ViewId{}
+	queryVid := `
+	SELECT view_id FROM
+	table_views
+	WHERE table_id = ?
+	ORDER BY position, rowid`
+	rows, err := tx.Query(queryVid, tid)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var vid common.ViewId
+		if err = rows.Scan(&vid); err != nil {
+			return
+		}
+		vidList = append(vidList, vid)
+	}
+	return
+}
+
+// 按列表顺序重新编号视图，保持顺序连续
+func renumberViews(tx tx.WriteTx, vidList []common.ViewId) (err error) {
+	update := `UPDATE table_views SET position = ? WHERE view_id = ?`
+	for idx, vid := range vidList {
+		if _, err = tx.Exac(update, idx, vid); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// 把视图移动到position处，超出范围时移动到最后
+func moveView(tx tx.WriteTx, v *viewImpl, position int) (err error) {
+	vidList, err := listViewId(tx, v.table.TableId())
+	if err != nil {
+		return
+	}
+	newList := []common.ViewId{}
+	for _, vid := range vidList {
+		if vid != v.viewId {
+			newList = append(newList, vid)
+		}
+	}
+	position = min(position, len(newList))
+	newList = append(newList[:position], append([]common.ViewId{v.viewId}, newList[position:]...)...)
+	if err = renumberViews(tx, newList); err != nil {
+		return
+	}
+	v.position = position
+	return
+}
+
+func deleteView(tx tx.WriteTx, table common.Table, vid common.ViewId) (err error) {
+	deleteStmt := `DELETE FROM table_views WHERE table_id = ? AND view_id = ?`
+	result, err := tx.Exac(deleteStmt, table.TableId(), vid)
+	if err != nil {
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		err = fmt.Errorf("view %v not found in table", vid)
+		return
+	}
+	vidList, err := listViewId(tx, table.TableId())
+	if err != nil {
+		return
+	}
+	err = renumberViews(tx, vidList)
+	return
+}
+
+// 复制视图的全部设置，副本排在原视图之后；删除属性的记录不会被复制
+func duplicateView(ctx context.Context, tx tx.WriteTx, db common.Database, table common.Table, vid common.ViewId) (view common.View, err error) {
+	source, err := queryView(ctx, tx, db, table, vid)
+	if err != nil {
+		return
+	}
+	src, ok := source.(*viewImpl)
+	if !ok {
+		err = fmt.Errorf("invaild view type:%T", source)
+		return
+	}
+	id, err := common.NewViewId()
+	if err != nil {
+		return
+	}
+	widths := map[common.AttributeClassId]int{}
+	for acid, width := range src.widths {
+		widths[acid] = width
+	}
+	v := &viewImpl{
+		viewId:      id,
+		db:          db,
+		table:       table,
+		name:        fmt.Sprintf("%s copy", src.name),
+		description: src.description,
+		position:    src.position + 1,
+		fields:      append([]common.AttributeClassId{}, src.fields...),
+		depFields:   append([]common.AttributeClassId{}, src.depFields...),
+		filter:      src.filter,
+		order:       src.order,
+		limit:       src.limit,
+		offset:      src.offset,
+		widths:      widths,
+		groupBy:     src.groupBy,
+	}
+	if err = v.insert(tx); err != nil {
+		return
+	}
+	if err = moveView(tx, v, src.position+1); err != nil {
+		return
+	}
+	view = v
+	return
+}
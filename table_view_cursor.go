@@ -0,0 +1,131 @@
+package paroket
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"paroket/common"
+	"paroket/tx"
+	"strings"
+)
+
+// 游标分页的位置：最后一个对象的排序键和对象id，排序键的顺序与视图的排序一致
+type viewCursor struct {
+	Keys     []interface{} `json:"k"`
+	ObjectId string        `json:"o"`
+}
+
+// 游标对外不透明，使用url安全的base64编码
+func encodeCursor(cursor *viewCursor) (raw string, err error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+	raw = base64.RawURLEncoding.EncodeToString(data)
+	return
+}
+
+func decodeCursor(raw string) (cursor *viewCursor, err error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		err = fmt.Errorf("invaild cursor:%s", raw)
+		return
+	}
+	cursor = &viewCursor{}
+	if err = json.Unmarshal(data, cursor); err != nil || cursor.ObjectId == "" {
+		err = fmt.Errorf("invaild cursor:%s", raw)
+		return
+	}
+	return
+}
+
+// 生成排在游标之后的条件，排序键全部相等时按对象id升序
+func buildCursorFilter(keys []common.SortKey, cursor *viewCursor) (stmt string, args []interface{}, err error) {
+	if len(cursor.Keys) != len(keys) {
+		err = fmt.Errorf("cursor dont match view order")
+		return
+	}
+	args = []interface{}{}
+	orList := []string{}
+	prefix := []string{}
+	prefixArgs := []interface{}{}
+	for idx, key := range keys {
+		value := cursor.Keys[idx]
+		after := ""
+		afterArgs := []interface{}{}
+		cmp := ">"
+		if key.Desc {
+			cmp = "<"
+		}
+		switch {
+		case value == nil && key.NullsFirst:
+			after = fmt.Sprintf(`%s IS NOT NULL`, key.Expr)
+			afterArgs = append(afterArgs, key.Args...)
+		case value == nil:
+			// 空值在最后时，没有排在空值之后的值
+		case key.NullsFirst:
+			after = fmt.Sprintf(`%s %s ?`, key.Expr, cmp)
+			afterArgs = append(append(afterArgs, key.Args...), value)
+		default:
+			after = fmt.Sprintf(`(%s %s ? OR %s IS NULL)`, key.Expr, cmp, key.Expr)
+			afterArgs = append(append(append(afterArgs, key.Args...), value), key.Args...)
+		}
+		if after != "" {
+			orList = append(orList, fmt.Sprintf(`(%s)`, strings.Join(append(append([]string{}, prefix...), after), " AND ")))
+			args = append(append(args, prefixArgs...), afterArgs...)
+		}
+		prefix = append(prefix, fmt.Sprintf(`%s IS ?`, key.Expr))
+		prefixArgs = append(append(prefixArgs, key.Args...), value)
+	}
+	orList = append(orList, fmt.Sprintf(`(%s)`, strings.Join(append(prefix, `object_id > ?`), " AND ")))
+	args = append(append(args, prefixArgs...), cursor.ObjectId)
+	stmt = strings.Join(orList, " OR ")
+	return
+}
+
+// 读取对象的排序键生成游标
+func queryCursor(tx tx.ReadTx, table common.Table, keys []common.SortKey, oid common.ObjectId) (raw string, err error) {
+	cursor := &viewCursor{Keys: []interface{}{}, ObjectId: oid.String()}
+	if len(keys) != 0 {
+		selectList := []string{}
+		args := []interface{}{}
+		for _, key := range keys {
+			selectList = append(selectList, key.Expr)
+			args = append(args, key.Args...)
+		}
+		args = append(args, oid)
+		values := make([]interface{}, len(keys))
+		dest := make([]interface{}, len(keys))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		queryStmt := fmt.Sprintf(`SELECT %s FROM %s WHERE object_id = ?`,
+			strings.Join(selectList, ", "), table.TableId().DataTable())
+		if err = tx.QueryRow(queryStmt, args...).Scan(dest...); err != nil {
+			return
+		}
+		for i, value := range values {
+			if data, ok := value.([]byte); ok {
+				values[i] = string(data)
+			}
+		}
+		cursor.Keys = values
+	}
+	raw, err = encodeCursor(cursor)
+	return
+}
+
+// 从游标之后查询下一页，每页的数量使用视图的设置
+func (v *viewImpl) QueryAfter(ctx context.Context, tx tx.ReadTx, cursor string) (queryData common.TableResult, err error) {
+	if v.groupBy != "" {
+		err = fmt.Errorf("grouped view unsupport cursor")
+		return
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return
+	}
+	queryData, err = v.query(ctx, tx, v.filter, v.limit, 0, after)
+	return
+}
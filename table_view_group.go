@@ -0,0 +1,361 @@
+package paroket
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"paroket/attribute"
+	"paroket/common"
+	"paroket/query"
+	"paroket/tx"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// 分组中默认返回的对象数
+const defaultGroupLimit = 20
+
+// 视图的分组设置，保存在视图的group_by中：
+//
+//	{
+//		"field": "<acid>",          分组的属性
+//		"bucket": "month",          日期按day、week、month、year分组
+//		"size": 10,                 数值按区间分组
+//		"mode": "asc",              分组的顺序，空分组总是在最后
+//		"limit": 20,                每个分组返回的对象数
+//		"aggregates": [{"field": "<acid>", "func": "sum"}]
+//	}
+type groupBy struct {
+	field      common.AttributeClassId
+	groupField common.GroupField
+	value      map[string]interface{}
+	mode       string
+	limit      int
+	aggregates []groupAggregate
+}
+
+type groupAggregate struct {
+	field    common.AttributeClassId
+	fn       string
+	jsonPath string
+}
+
+// 分组支持的统计函数
+var groupAggregateFunc = map[string]string{
+	"sum":    "SUM",
+	"avg":    "AVG",
+	"min":    "MIN",
+	"max":    "MAX",
+	"std":    "std",
+	"median": "median",
+}
+
+func parseGroupBy(ctx context.Context, tx tx.ReadTx, db common.Database, table common.Table, raw string) (gb *groupBy, err error) {
+	result := gjson.Parse(raw)
+	if !result.IsObject() {
+		err = fmt.Errorf("invaild group_by:%s", raw)
+		return
+	}
+	value, _ := result.Value().(map[string]interface{})
+	gb = &groupBy{
+		value: map[string]interface{}{},
+		mode:  "asc",
+		limit: defaultGroupLimit,
+	}
+	for key, val := range value {
+		switch key {
+		case "field":
+			s, _ := val.(string)
+			if err = gb.field.Scan(s); err != nil {
+				return
+			}
+		case "mode":
+			if val != "asc" && val != "desc" {
+				err = fmt.Errorf("invaild group mode:%v", val)
+				return
+			}
+			gb.mode = val.(string)
+		case "limit":
+			n, ok := toInt(val)
+			if !ok || n <= 0 {
+				err = fmt.Errorf("invaild group limit:%v", val)
+				return
+			}
+			gb.limit = n
+		case "aggregates":
+			continue
+		default:
+			gb.value[key] = val
+		}
+	}
+	if !isInFields(table.Fields(), gb.field) {
+		err = fmt.Errorf("group field %v not in table", gb.field)
+		return
+	}
+	ac, err := db.OpenAttributeClass(ctx, tx, gb.field)
+	if err != nil {
+		return
+	}
+	groupField, ok := ac.(common.GroupField)
+	if !ok {
+		err = fmt.Errorf("attribute type %s unsupport group", ac.Type())
+		return
+	}
+	gb.groupField = groupField
+
+	for _, item := range result.Get("aggregates").Array() {
+		aggregate := groupAggregate{fn: item.Get("func").String()}
+		if _, ok := groupAggregateFunc[aggregate.fn]; !ok {
+			err = fmt.Errorf("unsupport aggregate func:%s", aggregate.fn)
+			return
+		}
+		if err = aggregate.field.Scan(item.Get("field").String()); err != nil {
+			return
+		}
+		if !isInFields(table.Fields(), aggregate.field) {
+			err = fmt.Errorf("aggregate field %v not in table", aggregate.field)
+			return
+		}
+		var aggAc common.AttributeClass
+		if aggAc, err = db.OpenAttributeClass(ctx, tx, aggregate.field); err != nil {
+			return
+		}
+		if aggAc.Type() != attribute.AttributeTypeNumber {
+			err = fmt.Errorf("aggregate field %v is not a number", aggregate.field)
+			return
+		}
+		var metaInfo map[string]interface{}
+		if metaInfo, err = aggAc.GetMetaInfo(ctx, tx); err != nil {
+			return
+		}
+		if aggregate.jsonPath, ok = metaInfo["json_value_path"].(string); !ok {
+			err = fmt.Errorf("NumberAttribute metainfo dont have json_value_path")
+			return
+		}
+		gb.aggregates = append(gb.aggregates, aggregate)
+	}
+	return
+}
+
+// 接受json字符串或map，nil或空字符串表示取消分组
+func marshalGroupBy(value interface{}) (raw string, err error) {
+	switch val := value.(type) {
+	case nil:
+		return
+	case string:
+		raw = val
+	case map[string]interface{}:
+		var data []byte
+		if data, err = json.Marshal(val); err != nil {
+			return
+		}
+		raw = string(data)
+	default:
+		err = fmt.Errorf("invaild group_by type:%T", value)
+	}
+	return
+}
+
+// 查询各个分组的键、数量、统计值和第一页的对象
+func (v *viewImpl) queryGroups(ctx context.Context, tx tx.ReadTx, gb *groupBy, filter string) (groups []common.Group, err error) {
+	qb := query.NewQueryBuilder(v.table, v.db)
+	if err = qb.ParseFilter(ctx, tx, filter); err != nil {
+		return
+	}
+	filterStmt, filterArgs, err := qb.BuildFilter(ctx, tx)
+	if err != nil {
+		return
+	}
+	keysStmt, keysArgs, err := gb.groupField.BuildGroup(ctx, tx, gb.value)
+	if err != nil {
+		return
+	}
+	selectStmt := ""
+	for _, aggregate := range gb.aggregates {
+		selectStmt += fmt.Sprintf(`, %s(f.data ->> '%s')`, groupAggregateFunc[aggregate.fn], aggregate.jsonPath)
+	}
+	order := "ASC"
+	if gb.mode == "desc" {
+		order = "DESC"
+	}
+	queryStmt := fmt.Sprintf(`
+	WITH filtered AS (
+		SELECT object_id, data FROM %s %s
+	)
+	SELECT g.value, COUNT(DISTINCT f.object_id) %s
+	FROM filtered AS f LEFT JOIN json_each(%s) AS g
+	GROUP BY g.value
+	ORDER BY g.value IS NULL, g.value %s`,
+		v.table.TableId().DataTable(), filterStmt, selectStmt, keysStmt, order)
+	rows, err := tx.Query(queryStmt, append(filterArgs, keysArgs...)...)
+	if err != nil {
+		return
+	}
+	groups = []common.Group{}
+	for rows.Next() {
+		group := common.Group{Aggregates: make([]interface{}, len(gb.aggregates))}
+		dest := []interface{}{&group.Key, &group.Count}
+		for i := range group.Aggregates {
+			dest = append(dest, &group.Aggregates[i])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			rows.Close()
+			return
+		}
+		groups = append(groups, group)
+	}
+	rows.Close()
+
+	rowsMap, err := v.queryGroupsFirstRows(ctx, tx, gb, qb, filterStmt, filterArgs, keysStmt, keysArgs)
+	if err != nil {
+		return
+	}
+	for i := range groups {
+		groups[i].Rows = common.NewTableResult(v.db, v.fields, rowsMap[groupMapKey(groups[i].Key)])
+	}
+	return
+}
+
+// 一次查询出每个分组的前limit个对象，分组内按视图的排序
+func (v *viewImpl) queryGroupsFirstRows(ctx context.Context, tx tx.ReadTx, gb *groupBy, qb common.QueryBuilder, filterStmt string, filterArgs []interface{}, keysStmt string, keysArgs []interface{}) (rowsMap map[interface{}][]common.Object, err error) {
+	if err = qb.ParseOrder(ctx, tx, v.order); err != nil {
+		return
+	}
+	sortKeys, err := qb.SortKeys(ctx, tx)
+	if err != nil {
+		return
+	}
+	orderList := []string{}
+	orderArgs := []interface{}{}
+	for _, key := range sortKeys {
+		orderList = append(orderList, key.Stmt())
+		orderArgs = append(orderArgs, key.Args...)
+	}
+	orderList = append(orderList, "object_id ASC")
+
+	args := append([]interface{}{}, filterArgs...)
+	args = append(args, keysArgs...)
+	args = append(args, keysArgs...)
+	args = append(args, orderArgs...)
+	dataTable := v.table.TableId().DataTable()
+	// 对象属于每个非空的分组键，没有非空的键时属于空分组，与queryGroupRows一致
+	queryStmt := fmt.Sprintf(`
+	WITH filtered AS (
+		SELECT object_id FROM %[1]s %[2]s
+	), pairs AS (
+		SELECT DISTINCT g.value AS group_key, d.object_id AS oid
+		FROM %[1]s AS d LEFT JOIN json_each(%[3]s) AS g
+		WHERE d.object_id IN (SELECT object_id FROM filtered)
+		AND (g.value IS NOT NULL OR NOT EXISTS (SELECT 1 FROM json_each(%[3]s) WHERE value IS NOT NULL))
+	), ranked AS (
+		SELECT object_id, json(data) AS data, p.group_key AS group_key,
+		ROW_NUMBER() OVER (PARTITION BY p.group_key ORDER BY %[4]s) AS row_idx
+		FROM pairs AS p JOIN %[1]s ON object_id = p.oid
+	)
+	SELECT object_id, data, group_key FROM ranked
+	WHERE row_idx <= %[5]d
+	ORDER BY group_key, row_idx`, dataTable, filterStmt, keysStmt, strings.Join(orderList, ", "), gb.limit)
+	rows, err := tx.Query(queryStmt, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	rowsMap = map[interface{}][]common.Object{}
+	for rows.Next() {
+		var key interface{}
+		var obj common.Object
+		if obj, err = common.QueryTableObjectWith(ctx, v.db, rows, &key); err != nil {
+			return
+		}
+		mapKey := groupMapKey(key)
+		rowsMap[mapKey] = append(rowsMap[mapKey], obj)
+	}
+	return
+}
+
+// 分组键作为map的键，[]byte不能直接作为键
+func groupMapKey(key interface{}) interface{} {
+	if b, ok := key.([]byte); ok {
+		return string(b)
+	}
+	return key
+}
+
+// 查询一个分组中的对象，分组之间独立分页
+func (v *viewImpl) queryGroupRows(ctx context.Context, tx tx.ReadTx, gb *groupBy, filter string, key interface{}, limit int, offset int) (queryData common.TableResult, err error) {
+	qb := query.NewQueryBuilder(v.table, v.db)
+	if err = qb.ParseFilter(ctx, tx, filter); err != nil {
+		return
+	}
+	if err = qb.ParseOrder(ctx, tx, v.order); err != nil {
+		return
+	}
+	filterStmt, filterArgs, err := qb.BuildFilter(ctx, tx)
+	if err != nil {
+		return
+	}
+	orderStmt, orderArgs, err := qb.BuildSort(ctx, tx)
+	if err != nil {
+		return
+	}
+	keysStmt, keysArgs, err := gb.groupField.BuildGroup(ctx, tx, gb.value)
+	if err != nil {
+		return
+	}
+	args := append([]interface{}{}, filterArgs...)
+	args = append(args, keysArgs...)
+	keyStmt := fmt.Sprintf(`NOT EXISTS (SELECT 1 FROM json_each(%s) WHERE value IS NOT NULL)`, keysStmt)
+	if key != nil {
+		keyStmt = fmt.Sprintf(`EXISTS (SELECT 1 FROM json_each(%s) WHERE value IS ?)`, keysStmt)
+		args = append(args, key)
+	}
+	args = append(args, orderArgs...)
+	dataTable := v.table.TableId().DataTable()
+	queryStmt := fmt.Sprintf(`
+	WITH filtered AS (
+		SELECT object_id FROM %s %s
+	)
+	SELECT object_id, json(data) FROM %s
+	WHERE object_id IN (SELECT object_id FROM filtered) AND %s
+	%s
+	LIMIT %d OFFSET %d`, dataTable, filterStmt, dataTable, keyStmt, orderStmt, limit, offset)
+	var rows *sql.Rows
+	if rows, err = tx.Query(queryStmt, args...); err != nil {
+		return
+	}
+	objList, err := common.QueryTableObjectList(ctx, v.db, rows)
+	if err != nil {
+		return
+	}
+	queryData = common.NewTableResult(v.db, v.fields, objList)
+	return
+}
+
+func (v *viewImpl) QueryGroup(ctx context.Context, tx tx.ReadTx, key interface{}, config *common.QueryConfig) (queryData common.TableResult, err error) {
+	if v.groupBy == "" {
+		err = fmt.Errorf("view is not grouped")
+		return
+	}
+	gb, err := parseGroupBy(ctx, tx, v.db, v.table, v.groupBy)
+	if err != nil {
+		return
+	}
+	limit, offset, filter := gb.limit, 0, v.filter
+	if config != nil {
+		if config.Limit < 0 || config.Offset < 0 {
+			err = fmt.Errorf("invaild query config limit:%d offset:%d", config.Limit, config.Offset)
+			return
+		}
+		if config.Limit != 0 {
+			limit = config.Limit
+		}
+		offset = config.Offset
+		if filter, err = v.mergeFilter(ctx, tx, filter, config.Filter); err != nil {
+			return
+		}
+	}
+	queryData, err = v.queryGroupRows(ctx, tx, gb, filter, key, limit, offset)
+	return
+}
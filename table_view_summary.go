@@ -0,0 +1,137 @@
+package paroket
+
+import (
+	"context"
+	"fmt"
+	"paroket/attribute"
+	"paroket/common"
+	"paroket/query"
+	"paroket/tx"
+	"strings"
+)
+
+// 列统计方式
+const (
+	SummaryCount       = "count"
+	SummaryCountEmpty  = "count_empty"
+	SummaryCountUnique = "count_unique"
+	SummaryEarliest    = "earliest"
+	SummaryLatest      = "latest"
+)
+
+type summaryItem struct {
+	acid common.AttributeClassId
+	fn   string
+}
+
+// 在视图的筛选下统计各列，全部统计在一条sql中完成
+func (v *viewImpl) Summary(ctx context.Context, tx tx.ReadTx, spec common.SummarySpec) (summary common.SummaryResult, err error) {
+	summary = common.SummaryResult{}
+	itemList := []summaryItem{}
+	selectList := []string{}
+	for acid, fnList := range spec {
+		if !isInFields(v.table.Fields(), acid) {
+			err = fmt.Errorf("summary field %v not in table", acid)
+			return
+		}
+		var ac common.AttributeClass
+		if ac, err = v.db.OpenAttributeClass(ctx, tx, acid); err != nil {
+			return
+		}
+		var metaInfo map[string]interface{}
+		if metaInfo, err = ac.GetMetaInfo(ctx, tx); err != nil {
+			return
+		}
+		for _, fn := range fnList {
+			var stmt string
+			if stmt, err = summaryExpr(ac, metaInfo, fn); err != nil {
+				return
+			}
+			selectList = append(selectList, stmt)
+			itemList = append(itemList, summaryItem{acid: acid, fn: fn})
+		}
+	}
+	if len(itemList) == 0 {
+		return
+	}
+
+	qb := query.NewQueryBuilder(v.table, v.db)
+	if err = qb.ParseFilter(ctx, tx, v.filter); err != nil {
+		return
+	}
+	filterStmt, filterArgs, err := qb.BuildFilter(ctx, tx)
+	if err != nil {
+		return
+	}
+	queryStmt := fmt.Sprintf(`
+	WITH filtered AS (
+		SELECT object_id, data FROM %s %s
+	)
+	SELECT %s FROM filtered AS f`,
+		v.table.TableId().DataTable(), filterStmt, strings.Join(selectList, ", "))
+	values := make([]interface{}, len(itemList))
+	dest := make([]interface{}, len(itemList))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err = tx.QueryRow(queryStmt, filterArgs...).Scan(dest...); err != nil {
+		return
+	}
+	for i, item := range itemList {
+		if summary[item.acid] == nil {
+			summary[item.acid] = map[string]interface{}{}
+		}
+		summary[item.acid][item.fn] = values[i]
+	}
+	return
+}
+
+// 生成一个统计的sql表达式，空值包括NULL、空字符串和空数组
+func summaryExpr(ac common.AttributeClass, metaInfo map[string]interface{}, fn string) (stmt string, err error) {
+	jsonPath, ok := metaInfo["json_value_path"].(string)
+	if !ok {
+		err = fmt.Errorf("attribute %v metainfo dont have json_value_path", ac.ClassId())
+		return
+	}
+	empty := fmt.Sprintf(`(IFNULL(json_type(f.data, '%[1]s'), 'null') = 'null' OR f.data ->> '%[1]s' = '' OR (json_type(f.data, '%[1]s') = 'array' AND json_array_length(f.data, '%[1]s') = 0))`, jsonPath)
+	switch fn {
+	case SummaryCount:
+		stmt = `COUNT(*)`
+		return
+	case SummaryCountEmpty:
+		stmt = fmt.Sprintf(`IFNULL(SUM(%s), 0)`, empty)
+		return
+	case SummaryCountUnique:
+		stmt = fmt.Sprintf(`COUNT(DISTINCT CASE WHEN NOT %s THEN f.data -> '%s' END)`, empty, jsonPath)
+		return
+	}
+
+	switch ac.Type() {
+	case attribute.AttributeTypeNumber:
+		if aggrFunc, ok := groupAggregateFunc[fn]; ok {
+			stmt = fmt.Sprintf(`%s(f.data ->> '%s')`, aggrFunc, jsonPath)
+			return
+		}
+	case attribute.AttributeTypeDate:
+		unixPath, ok := metaInfo["json_unix_path"].(string)
+		if !ok {
+			err = fmt.Errorf("DateAttribute metainfo dont have json_unix_path")
+			return
+		}
+		mode := ""
+		switch fn {
+		case SummaryEarliest:
+			mode = "ASC"
+		case SummaryLatest:
+			mode = "DESC"
+		}
+		if mode != "" {
+			// 返回保存的日期值而不是时间戳
+			stmt = fmt.Sprintf(`(SELECT data ->> '%s' FROM filtered WHERE data ->> '%s' IS NOT NULL ORDER BY data ->> '%s' %s LIMIT 1)`,
+				jsonPath, unixPath, unixPath, mode)
+			return
+		}
+	}
+	err = fmt.Errorf("attribute type %s unsupport summary func:%s", ac.Type(), fn)
+	return
+}